@@ -1,7 +1,67 @@
+// Package mock 测试使用的模拟HTTP接口
 package mock
 
-import "net/http"
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// BaseURL 企业微信接口的地址
+const BaseURL = "https://qyapi.weixin.qq.com"
 
 func NewMockedHTTP() *http.Client {
 	return http.DefaultClient
 }
+
+// Transport 模拟企业微信接口的http.RoundTripper，已注册获取access token的接口
+//
+// 内嵌的httpmock.MockTransport可以注册其他响应及统计调用次数
+type Transport struct {
+	*httpmock.MockTransport
+
+	mutex    *sync.Mutex
+	requests []map[string]any
+}
+
+func NewTransport() *Transport {
+	transport := &Transport{MockTransport: httpmock.NewMockTransport(), mutex: &sync.Mutex{}}
+	transport.RegisterResponder(http.MethodGet, BaseURL+"/cgi-bin/gettoken",
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok","access_token":"accesstoken000001","expires_in":7200}`))
+	return transport
+}
+
+// HTTPClient 使用该Transport发送请求的http.Client
+func (t *Transport) HTTPClient() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RegisterPost 注册POST接口，记录JSON格式的请求体，依次返回bodies中的响应，最后一个响应会重复返回
+//
+// path为接口路径，可以包含查询参数；请求体不是JSON对象时返回错误
+func (t *Transport) RegisterPost(path string, bodies ...string) {
+	t.RegisterResponder(http.MethodPost, BaseURL+path, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]any{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		t.requests = append(t.requests, payload)
+		body := bodies[0]
+		if len(bodies) > 1 {
+			bodies = bodies[1:]
+		}
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+}
+
+// Requests 按接收顺序返回RegisterPost注册的接口收到的请求体
+func (t *Transport) Requests() []map[string]any {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]map[string]any(nil), t.requests...)
+}
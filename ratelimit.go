@@ -0,0 +1,64 @@
+package wechatgo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter 限流器
+type RateLimiter interface {
+	// Wait 阻塞直到允许下一次调用，ctx被取消时返回ctx.Err()
+	Wait(ctx context.Context) error
+}
+
+type windowLimiter struct {
+	mutex  *sync.Mutex
+	limit  int
+	period time.Duration
+	calls  []time.Time // 窗口内的调用时间
+}
+
+// NewRateLimiter 滑动窗口限流器，任意period时间内最多允许limit次调用
+func NewRateLimiter(limit int, period time.Duration) RateLimiter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &windowLimiter{mutex: &sync.Mutex{}, limit: limit, period: period}
+}
+
+func (l *windowLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve(time.Now())
+		if wait <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// 尝试占用一次调用额度，成功时返回0，否则返回需要等待的时间
+func (l *windowLimiter) reserve(now time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	start := now.Add(-l.period)
+	expired := 0
+	for expired < len(l.calls) && !l.calls[expired].After(start) {
+		expired++
+	}
+	l.calls = l.calls[expired:]
+
+	if len(l.calls) < l.limit {
+		l.calls = append(l.calls, now)
+		return 0
+	}
+	return l.calls[0].Sub(start)
+}
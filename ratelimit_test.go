@@ -0,0 +1,38 @@
+package wechatgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := NewRateLimiter(2, time.Millisecond*50)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("RateLimiter.Wait() error = '%s'", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+		t.Errorf("RateLimiter.Wait() error = 'third call returned after %s, want >= 50ms'", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitCanceled(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Hour)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("RateLimiter.Wait() error = '%s'", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	if err := limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RateLimiter.Wait() error = '%v', want context.DeadlineExceeded", err)
+	}
+}
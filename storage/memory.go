@@ -21,10 +21,11 @@ func NewMemoryStorage() *MemoryStorage {
 }
 
 func (s MemoryStorage) Get(ctx context.Context, key string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	data, ok := s.data[key]
-	if ok && s.HasExpired(ctx, key) {
-		s.mutex.Lock()
-		defer s.mutex.Unlock()
+	if ok && s.hasExpired(key) {
 		delete(s.data, key)
 		return ""
 	}
@@ -39,6 +40,13 @@ func (s MemoryStorage) Set(ctx context.Context, key string, val string, ttl time
 }
 
 func (s MemoryStorage) HasExpired(ctx context.Context, key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.hasExpired(key)
+}
+
+func (s MemoryStorage) hasExpired(key string) bool {
 	val, ok := s.data[key]
 	if !ok {
 		return true
@@ -0,0 +1,101 @@
+// Package alert 将Prometheus Alertmanager及通用JSON的webhook转换为企业微信消息
+//
+// Handler接收Alertmanager的webhook请求，按照标签路由到应用消息的成员、部门、标签或群机器人，
+// 按路由及分组标签合并为markdown或模板卡片消息发送，并对重复的告警及恢复通知去重。
+package alert
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 告警状态
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Payload Alertmanager webhook的请求数据
+//
+// 参考文档：https://prometheus.io/docs/alerting/latest/configuration/#webhook_config
+type Payload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	TruncatedAlerts   int               `json:"truncatedAlerts"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert 单个告警
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// Name 告警名称，即alertname标签
+func (a Alert) Name() string {
+	return a.Labels["alertname"]
+}
+
+// Firing 告警是否正在触发
+func (a Alert) Firing() bool {
+	return a.Status != StatusResolved
+}
+
+// ID 告警的唯一标识，优先使用Alertmanager提供的fingerprint，否则根据标签计算
+func (a Alert) ID() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return LabelsFingerprint(a.Labels)
+}
+
+// LabelsFingerprint 根据标签计算摘要，标签相同时摘要相同
+func LabelsFingerprint(labels map[string]string) string {
+	h := sha1.New()
+	for _, name := range sortedKeys(labels) {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[name]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// FormatLabels 将标签格式化为name=value形式，按标签名排序，exclude中的标签不输出
+func FormatLabels(labels map[string]string, exclude ...string) string {
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+
+	var items []string
+	for _, name := range sortedKeys(labels) {
+		if !excluded[name] {
+			items = append(items, name+"="+labels[name])
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,66 @@
+package alert
+
+import (
+	"context"
+	"time"
+
+	"github.com/huimingz/wechatgo/storage"
+)
+
+// DefaultDedupWindow 默认的去重时间窗口
+const DefaultDedupWindow = time.Hour * 24
+
+// Deduplicator 告警去重
+//
+// 记录每个告警在每个路由上最后一次发送的状态：状态未变化的告警不再重复发送。
+// 持续告警的重复通知会刷新记录的有效期，记录只在window内没有再收到该告警时过期，
+// 过期后相同状态的告警会再次发送；没有记录时恢复通知照常发送，避免记录过期后丢失恢复通知。
+type Deduplicator struct {
+	storage storage.Storage
+	window  time.Duration
+}
+
+// NewDeduplicator 创建去重器，window不大于0时使用DefaultDedupWindow
+func NewDeduplicator(s storage.Storage, window time.Duration) *Deduplicator {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	return &Deduplicator{storage: s, window: window}
+}
+
+func (d *Deduplicator) key(route string, alert Alert) string {
+	return "alert_" + route + "_" + alert.ID()
+}
+
+// Filter 返回需要发送的告警
+func (d *Deduplicator) Filter(ctx context.Context, route string, alerts []Alert) []Alert {
+	var filtered []Alert
+	for _, alert := range alerts {
+		key := d.key(route, alert)
+		last := d.storage.Get(ctx, key)
+		if alert.Firing() && last == StatusFiring {
+			// 告警仍在持续，刷新有效期以保证之后的恢复通知能够匹配
+			_ = d.storage.Set(ctx, key, StatusFiring, d.window)
+			continue
+		}
+		if !alert.Firing() && last == StatusResolved {
+			continue
+		}
+		filtered = append(filtered, alert)
+	}
+	return filtered
+}
+
+// Mark 记录告警已发送
+func (d *Deduplicator) Mark(ctx context.Context, route string, alerts []Alert) error {
+	for _, alert := range alerts {
+		status := StatusFiring
+		if !alert.Firing() {
+			status = StatusResolved
+		}
+		if err := d.storage.Set(ctx, d.key(route, alert), status, d.window); err != nil {
+			return err
+		}
+	}
+	return nil
+}
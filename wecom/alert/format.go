@@ -0,0 +1,221 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+// Group 合并为一条消息发送的一组告警
+type Group struct {
+	Route             string            // 路由名称
+	Status            string            // 组内存在触发中的告警时为firing，否则为resolved
+	Receiver          string            // Alertmanager的receiver
+	GroupLabels       map[string]string // 分组标签
+	CommonLabels      map[string]string // 组内告警共同的标签
+	CommonAnnotations map[string]string // 组内告警共同的注解
+	ExternalURL       string            // Alertmanager的地址
+	Alerts            []Alert           // 告警列表
+}
+
+func newGroup(route string, payload *Payload, groupLabels map[string]string, alerts []Alert) Group {
+	group := Group{
+		Route:             route,
+		Status:            StatusResolved,
+		Receiver:          payload.Receiver,
+		GroupLabels:       groupLabels,
+		CommonLabels:      commonValues(alerts, func(a Alert) map[string]string { return a.Labels }),
+		CommonAnnotations: commonValues(alerts, func(a Alert) map[string]string { return a.Annotations }),
+		ExternalURL:       payload.ExternalURL,
+		Alerts:            alerts,
+	}
+	if len(group.Firing()) > 0 {
+		group.Status = StatusFiring
+	}
+	return group
+}
+
+// 所有告警中值都相同的项
+func commonValues(alerts []Alert, values func(Alert) map[string]string) map[string]string {
+	common := map[string]string{}
+	if len(alerts) == 0 {
+		return common
+	}
+	for k, v := range values(alerts[0]) {
+		common[k] = v
+	}
+	for _, alert := range alerts[1:] {
+		m := values(alert)
+		for k, v := range common {
+			if value, ok := m[k]; !ok || value != v {
+				delete(common, k)
+			}
+		}
+	}
+	return common
+}
+
+// Firing 触发中的告警
+func (g Group) Firing() []Alert {
+	return g.filter(true)
+}
+
+// Resolved 已恢复的告警
+func (g Group) Resolved() []Alert {
+	return g.filter(false)
+}
+
+func (g Group) filter(firing bool) []Alert {
+	var alerts []Alert
+	for _, alert := range g.Alerts {
+		if alert.Firing() == firing {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// Title 分组的标题，优先使用共同的alertname标签
+func (g Group) Title() string {
+	if name := g.CommonLabels["alertname"]; name != "" {
+		return name
+	}
+	if name := g.GroupLabels["alertname"]; name != "" {
+		return name
+	}
+	if labels := FormatLabels(g.GroupLabels); labels != "" {
+		return labels
+	}
+	return g.Receiver
+}
+
+// Formatter 将一组告警转换为消息
+type Formatter func(group Group) (msg.Message, error)
+
+// 告警的描述，依次使用summary、description及message注解
+func alertSummary(alert Alert) string {
+	for _, name := range []string{"summary", "description", "message"} {
+		if v := alert.Annotations[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// MarkdownFormatter 将告警转换为markdown消息，触发中的告警以橙红色显示，已恢复的告警以绿色显示
+func MarkdownFormatter(group Group) (msg.Message, error) {
+	b := msg.NewMarkdownBuilder()
+	firing, resolved := group.Firing(), group.Resolved()
+
+	b.Heading(2, group.Title())
+	if len(firing) > 0 {
+		b.Color(msg.FontColorWarning, fmt.Sprintf("FIRING:%d", len(firing)))
+	}
+	if len(resolved) > 0 {
+		if len(firing) > 0 {
+			b.Text(" ")
+		}
+		b.Color(msg.FontColorInfo, fmt.Sprintf("RESOLVED:%d", len(resolved)))
+	}
+	b.Newline()
+
+	for _, alert := range group.Alerts {
+		b.Newline()
+		if alert.Firing() {
+			b.Color(msg.FontColorWarning, "● ")
+		} else {
+			b.Color(msg.FontColorInfo, "● ")
+		}
+		b.Bold(alert.Name())
+		if summary := alertSummary(alert); summary != "" {
+			b.Text(" " + summary)
+		}
+		b.Newline()
+
+		if labels := FormatLabels(alert.Labels, "alertname"); labels != "" {
+			b.Color(msg.FontColorComment, labels).Newline()
+		}
+		b.Color(msg.FontColorComment, formatTimeRange(alert)).Newline()
+		if alert.GeneratorURL != "" {
+			b.Link("查看详情", alert.GeneratorURL).Newline()
+		}
+	}
+
+	if group.ExternalURL != "" {
+		b.Newline().Link("Alertmanager", group.ExternalURL)
+	}
+	return b.Message(), nil
+}
+
+func formatTimeRange(alert Alert) string {
+	const layout = "2006-01-02 15:04:05"
+	text := "开始于 " + alert.StartsAt.Local().Format(layout)
+	if !alert.Firing() && !alert.EndsAt.IsZero() {
+		text += "，恢复于 " + alert.EndsAt.Local().Format(layout) +
+			"，持续 " + alert.EndsAt.Sub(alert.StartsAt).Round(time.Second).String()
+	}
+	return text
+}
+
+// TemplateCardFormatter 将告警转换为文本通知型模板卡片，url为点击卡片跳转的地址，为空时使用Alertmanager的地址
+//
+// 卡片最多显示6个告警，其余的告警仅计入数量
+func TemplateCardFormatter(url string) Formatter {
+	return func(group Group) (msg.Message, error) {
+		firing, resolved := group.Firing(), group.Resolved()
+		actionUrl := url
+		if actionUrl == "" {
+			actionUrl = group.ExternalURL
+		}
+
+		card := msg.TemplateCardMsg{
+			CardType: msg.CardTypeTextNotice,
+			Source:   &msg.CardSource{Desc: "Alertmanager", DescColor: 3},
+			MainTitle: &msg.CardMainTitle{
+				Title: msg.TruncateRunes(group.Title(), 36),
+				Desc:  msg.TruncateRunes(FormatLabels(group.GroupLabels), 44),
+			},
+			EmphasisContent: &msg.CardEmphasisContent{
+				Title: strconv.Itoa(len(firing)),
+				Desc:  fmt.Sprintf("触发中，已恢复%d", len(resolved)),
+			},
+			CardAction: &msg.CardAction{Type: 1, Url: actionUrl},
+		}
+		if len(firing) > 0 {
+			card.Source.DescColor = 2
+		}
+		if summary := group.CommonAnnotations["summary"]; summary != "" {
+			card.SubTitleText = msg.TruncateRunes(summary, 160)
+		}
+
+		for i, alert := range group.Alerts {
+			if i == 6 {
+				break
+			}
+			status := "恢复"
+			if alert.Firing() {
+				status = "告警"
+			}
+			value := alertSummary(alert)
+			if value == "" {
+				value = FormatLabels(alert.Labels, "alertname")
+			}
+			item := msg.CardHorizontalContent{KeyName: status, Value: msg.TruncateRunes(value, 30)}
+			if alert.GeneratorURL != "" {
+				item.Type = 1
+				item.Url = alert.GeneratorURL
+			}
+			card.HorizontalContentList = append(card.HorizontalContentList, item)
+		}
+		return card, nil
+	}
+}
+
+// RendererFormatter 使用消息模板渲染告警，模板的数据为Group
+func RendererFormatter(renderer *msg.Renderer, name, locale string) Formatter {
+	return func(group Group) (msg.Message, error) {
+		return renderer.Render(name, locale, group)
+	}
+}
@@ -0,0 +1,326 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+// DefaultMaxBodyBytes 默认的请求体最大字节数
+const DefaultMaxBodyBytes = 4 * 1024 * 1024
+
+type HandlerOptionFn func(handler *Handler)
+
+// HandlerWithFormatter 指定消息格式，默认为MarkdownFormatter
+func HandlerWithFormatter(formatter Formatter) HandlerOptionFn {
+	return func(handler *Handler) {
+		handler.formatter = formatter
+	}
+}
+
+// HandlerWithGroupBy 按指定的标签将告警分组，每组发送一条消息，默认使用Alertmanager的分组
+func HandlerWithGroupBy(labels ...string) HandlerOptionFn {
+	return func(handler *Handler) {
+		handler.groupBy = labels
+	}
+}
+
+// HandlerWithDeduplicator 对告警去重，默认不去重
+func HandlerWithDeduplicator(dedup *Deduplicator) HandlerOptionFn {
+	return func(handler *Handler) {
+		handler.dedup = dedup
+	}
+}
+
+// HandlerWithMaxBodyBytes 请求体的最大字节数，默认为DefaultMaxBodyBytes
+func HandlerWithMaxBodyBytes(n int64) HandlerOptionFn {
+	return func(handler *Handler) {
+		handler.maxBodyBytes = n
+	}
+}
+
+func HandlerWithLogger(logger wechatgo.Logger) HandlerOptionFn {
+	return func(handler *Handler) {
+		handler.log = logger
+	}
+}
+
+// Handler 接收Alertmanager webhook请求的http.Handler
+//
+// 告警按路由及分组标签分组，每组格式化为一条消息发送到路由的目标。
+// 发送失败时返回500，Alertmanager会稍后重试；已发送成功的分组会被去重，不会重复发送。
+type Handler struct {
+	router       *Router
+	formatter    Formatter
+	groupBy      []string
+	dedup        *Deduplicator
+	maxBodyBytes int64
+	log          wechatgo.Logger
+}
+
+func NewHandler(router *Router, options ...HandlerOptionFn) *Handler {
+	handler := &Handler{router: router}
+	for _, opt := range options {
+		opt(handler)
+	}
+
+	if handler.formatter == nil {
+		handler.formatter = MarkdownFormatter
+	}
+	if handler.maxBodyBytes <= 0 {
+		handler.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if handler.log == nil {
+		handler.log = wechatgo.DefaultLogger()
+	}
+	return handler
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload := &Payload{}
+	if err := decodeBody(w, r, h.maxBodyBytes, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Handle(r.Context(), payload); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, maxBytes int64, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err := decoder.Decode(v); err != nil {
+		if err == io.EOF {
+			return fmt.Errorf("alert: empty request body")
+		}
+		return fmt.Errorf("alert: invalid request body: %w", err)
+	}
+	return nil
+}
+
+// Handle 处理Alertmanager的告警，所有分组发送完成后返回第一个发送失败的错误
+func (h *Handler) Handle(ctx context.Context, payload *Payload) error {
+	var firstErr error
+	for _, group := range h.groups(payload) {
+		route := group.route
+		if err := dispatch(ctx, route, h.dedup, group.alerts, func(alerts []Alert) (msg.Message, error) {
+			return h.formatter(newGroup(route.Name, payload, group.labels, alerts))
+		}); err != nil {
+			h.log.Error(ctx, fmt.Sprintf("Alert: failed to send alerts to route %s, Error: %s", route.Name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+type alertGroup struct {
+	route  Route
+	labels map[string]string
+	alerts []Alert
+}
+
+// 按路由及分组标签分组，保持告警在请求中的顺序
+func (h *Handler) groups(payload *Payload) []*alertGroup {
+	var groups []*alertGroup
+	index := map[string]*alertGroup{}
+
+	for _, alert := range payload.Alerts {
+		labels := payload.GroupLabels
+		if len(h.groupBy) > 0 {
+			labels = map[string]string{}
+			for _, name := range h.groupBy {
+				if v, ok := alert.Labels[name]; ok {
+					labels[name] = v
+				}
+			}
+		}
+		groupKey := LabelsFingerprint(labels)
+
+		for _, route := range h.router.Match(alert.Labels) {
+			key := route.Name + "\x00" + groupKey
+			group, ok := index[key]
+			if !ok {
+				group = &alertGroup{route: route, labels: labels}
+				index[key] = group
+				groups = append(groups, group)
+			}
+			group.alerts = append(group.alerts, alert)
+		}
+	}
+	return groups
+}
+
+// 去重后格式化并发送，发送成功后记录已发送的告警
+func dispatch(ctx context.Context, route Route, dedup *Deduplicator, alerts []Alert, format func([]Alert) (msg.Message, error)) error {
+	if dedup != nil {
+		alerts = dedup.Filter(ctx, route.Name, alerts)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	message, err := format(alerts)
+	if err != nil {
+		return err
+	}
+	if err = route.Target.Send(ctx, message); err != nil {
+		return err
+	}
+
+	if dedup != nil {
+		return dedup.Mark(ctx, route.Name, alerts)
+	}
+	return nil
+}
+
+type GenericHandlerOptionFn func(handler *GenericHandler)
+
+// GenericWithExtractor 从请求数据中提取用于路由及去重的告警信息
+//
+// 默认使用顶层的labels、status及fingerprint字段，fingerprint不存在时根据labels计算
+func GenericWithExtractor(extractor func(data interface{}) Alert) GenericHandlerOptionFn {
+	return func(handler *GenericHandler) {
+		handler.extract = extractor
+	}
+}
+
+// GenericWithDeduplicator 对告警去重，默认不去重
+func GenericWithDeduplicator(dedup *Deduplicator) GenericHandlerOptionFn {
+	return func(handler *GenericHandler) {
+		handler.dedup = dedup
+	}
+}
+
+// GenericWithLocale 渲染模板时使用的语言
+func GenericWithLocale(locale string) GenericHandlerOptionFn {
+	return func(handler *GenericHandler) {
+		handler.locale = locale
+	}
+}
+
+// GenericWithMaxBodyBytes 请求体的最大字节数，默认为DefaultMaxBodyBytes
+func GenericWithMaxBodyBytes(n int64) GenericHandlerOptionFn {
+	return func(handler *GenericHandler) {
+		handler.maxBodyBytes = n
+	}
+}
+
+func GenericWithLogger(logger wechatgo.Logger) GenericHandlerOptionFn {
+	return func(handler *GenericHandler) {
+		handler.log = logger
+	}
+}
+
+// GenericHandler 接收任意JSON webhook请求的http.Handler
+//
+// 请求体解析后作为模板数据，使用renderer中名称为name的模板渲染为消息，
+// 再按照提取的标签路由发送，每个请求作为一个告警处理。
+type GenericHandler struct {
+	router       *Router
+	renderer     *msg.Renderer
+	name         string
+	locale       string
+	extract      func(data interface{}) Alert
+	dedup        *Deduplicator
+	maxBodyBytes int64
+	log          wechatgo.Logger
+}
+
+func NewGenericHandler(router *Router, renderer *msg.Renderer, name string, options ...GenericHandlerOptionFn) *GenericHandler {
+	handler := &GenericHandler{router: router, renderer: renderer, name: name}
+	for _, opt := range options {
+		opt(handler)
+	}
+
+	if handler.extract == nil {
+		handler.extract = extractAlert
+	}
+	if handler.maxBodyBytes <= 0 {
+		handler.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if handler.log == nil {
+		handler.log = wechatgo.DefaultLogger()
+	}
+	return handler
+}
+
+// 默认的告警信息提取，非字符串的标签值使用JSON表示
+func extractAlert(data interface{}) Alert {
+	alert := Alert{Labels: map[string]string{}}
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return alert
+	}
+
+	if labels, ok := m["labels"].(map[string]interface{}); ok {
+		for name, value := range labels {
+			if s, ok := value.(string); ok {
+				alert.Labels[name] = s
+			} else {
+				b, _ := json.Marshal(value)
+				alert.Labels[name] = string(b)
+			}
+		}
+	}
+	if status, ok := m["status"].(string); ok && strings.EqualFold(status, StatusResolved) {
+		alert.Status = StatusResolved
+	} else {
+		alert.Status = StatusFiring
+	}
+	alert.Fingerprint, _ = m["fingerprint"].(string)
+	return alert
+}
+
+func (h *GenericHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var data interface{}
+	if err := decodeBody(w, r, h.maxBodyBytes, &data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Handle(r.Context(), data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handle 渲染并发送一个请求的数据，返回第一个发送失败的错误
+func (h *GenericHandler) Handle(ctx context.Context, data interface{}) error {
+	alert := h.extract(data)
+	var firstErr error
+	for _, route := range h.router.Match(alert.Labels) {
+		if err := dispatch(ctx, route, h.dedup, []Alert{alert}, func([]Alert) (msg.Message, error) {
+			return h.renderer.Render(h.name, h.locale, data)
+		}); err != nil {
+			h.log.Error(ctx, fmt.Sprintf("Alert: failed to send alert to route %s, Error: %s", route.Name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
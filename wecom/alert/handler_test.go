@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/storage"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+type recordingTarget struct {
+	messages []msg.Message
+	err      error
+}
+
+func (t *recordingTarget) Send(ctx context.Context, message msg.Message) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.messages = append(t.messages, message)
+	return nil
+}
+
+const alertmanagerPayload = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighLatency\"}",
+  "status": "firing",
+  "receiver": "wecom",
+  "groupLabels": {"alertname": "HighLatency"},
+  "commonLabels": {"alertname": "HighLatency"},
+  "externalURL": "http://alertmanager:9093",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLatency", "team": "db", "instance": "db-1"},
+      "annotations": {"summary": "db-1 latency > 1s"},
+      "startsAt": "2024-01-01T00:00:00Z",
+      "generatorURL": "http://prometheus/graph?g0.expr=latency",
+      "fingerprint": "aaa"
+    },
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLatency", "team": "web", "instance": "web-1"},
+      "annotations": {"summary": "web-1 latency > 1s"},
+      "startsAt": "2024-01-01T00:00:00Z",
+      "fingerprint": "bbb"
+    }
+  ]
+}`
+
+type handlerTestSuite struct {
+	suite.Suite
+	db       *recordingTarget
+	fallback *recordingTarget
+	router   *Router
+}
+
+func (s *handlerTestSuite) SetupTest() {
+	s.db = &recordingTarget{}
+	s.fallback = &recordingTarget{}
+	s.router = NewRouter(s.fallback, Route{Name: "db", Match: map[string]string{"team": "db"}, Target: s.db})
+}
+
+func (s *handlerTestSuite) post(handler http.Handler, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/alert", strings.NewReader(body)))
+	return recorder
+}
+
+func (s *handlerTestSuite) TestShouldRouteAlertsByLabels() {
+	recorder := s.post(NewHandler(s.router), alertmanagerPayload)
+	s.Equal(http.StatusOK, recorder.Code)
+
+	s.Require().Len(s.db.messages, 1)
+	content := s.db.messages[0].(msg.MarkdownMsg).Content
+	s.Contains(content, "## HighLatency")
+	s.Contains(content, `<font color="warning">FIRING:1</font>`)
+	s.Contains(content, "db-1 latency > 1s")
+	s.NotContains(content, "web-1")
+	s.Contains(content, "[查看详情](http://prometheus/graph?g0.expr=latency)")
+
+	s.Require().Len(s.fallback.messages, 1)
+	s.Contains(s.fallback.messages[0].(msg.MarkdownMsg).Content, "web-1 latency > 1s")
+}
+
+func (s *handlerTestSuite) TestShouldContinueMatchingRoutes() {
+	all := &recordingTarget{}
+	router := NewRouter(nil,
+		Route{MatchRE: map[string]*regexp.Regexp{"instance": regexp.MustCompile(`^db-\d+$`)}, Target: s.db, Continue: true},
+		Route{Target: all},
+	)
+	s.Equal(http.StatusOK, s.post(NewHandler(router), alertmanagerPayload).Code)
+	s.Len(s.db.messages, 1)
+	s.Len(all.messages, 1)
+	s.Contains(all.messages[0].(msg.MarkdownMsg).Content, "db-1")
+	s.Contains(all.messages[0].(msg.MarkdownMsg).Content, "web-1")
+}
+
+func (s *handlerTestSuite) TestShouldGroupByLabels() {
+	router := NewRouter(s.fallback)
+	s.post(NewHandler(router, HandlerWithGroupBy("team")), alertmanagerPayload)
+	s.Len(s.fallback.messages, 2)
+}
+
+func (s *handlerTestSuite) TestShouldDeduplicateFiringAndResolved() {
+	handler := NewHandler(NewRouter(s.fallback), HandlerWithDeduplicator(NewDeduplicator(storage.NewMemoryStorage(), time.Hour)))
+
+	s.post(handler, alertmanagerPayload)
+	s.post(handler, alertmanagerPayload)
+	s.Len(s.fallback.messages, 1, "repeated firing alerts are not sent again")
+
+	resolved := strings.ReplaceAll(alertmanagerPayload, `"status": "firing"`, `"status": "resolved"`)
+	s.post(handler, resolved)
+	s.post(handler, resolved)
+	s.Require().Len(s.fallback.messages, 2, "resolved notification is sent once")
+	s.Contains(s.fallback.messages[1].(msg.MarkdownMsg).Content, "RESOLVED:2")
+
+	s.post(handler, alertmanagerPayload)
+	s.Len(s.fallback.messages, 3, "alert firing again after resolving is sent")
+}
+
+func (s *handlerTestSuite) TestShouldSendResolvedWithoutRecord() {
+	handler := NewHandler(NewRouter(s.fallback), HandlerWithDeduplicator(NewDeduplicator(storage.NewMemoryStorage(), 0)))
+	s.post(handler, strings.ReplaceAll(alertmanagerPayload, `"status": "firing"`, `"status": "resolved"`))
+	s.Len(s.fallback.messages, 1)
+}
+
+func (s *handlerTestSuite) TestShouldRefreshFiringRecordWhenSuppressed() {
+	handler := NewHandler(NewRouter(s.fallback), HandlerWithDeduplicator(NewDeduplicator(storage.NewMemoryStorage(), time.Millisecond*200)))
+
+	s.post(handler, alertmanagerPayload)
+	time.Sleep(time.Millisecond * 120)
+	s.post(handler, alertmanagerPayload)
+	time.Sleep(time.Millisecond * 120)
+	s.post(handler, alertmanagerPayload)
+	s.Len(s.fallback.messages, 1, "record is refreshed while the alert keeps firing")
+}
+
+func (s *handlerTestSuite) TestShouldReturnErrorWhenSendFailed() {
+	s.fallback.err = errors.New("network error")
+	dedup := NewDeduplicator(storage.NewMemoryStorage(), time.Hour)
+	handler := NewHandler(s.router, HandlerWithDeduplicator(dedup))
+
+	s.Equal(http.StatusInternalServerError, s.post(handler, alertmanagerPayload).Code)
+	s.Len(s.db.messages, 1)
+
+	s.fallback.err = nil
+	s.Equal(http.StatusOK, s.post(handler, alertmanagerPayload).Code)
+	s.Len(s.db.messages, 1, "delivered group is not sent again on retry")
+	s.Len(s.fallback.messages, 1)
+}
+
+func (s *handlerTestSuite) TestShouldRejectInvalidRequest() {
+	handler := NewHandler(s.router)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/alert", nil))
+	s.Equal(http.StatusMethodNotAllowed, recorder.Code)
+
+	s.Equal(http.StatusBadRequest, s.post(handler, "{").Code)
+	s.Equal(http.StatusBadRequest, s.post(handler, "").Code)
+}
+
+func (s *handlerTestSuite) TestShouldFormatTemplateCard() {
+	s.post(NewHandler(NewRouter(s.fallback), HandlerWithFormatter(TemplateCardFormatter(""))), alertmanagerPayload)
+
+	s.Require().Len(s.fallback.messages, 1)
+	card := s.fallback.messages[0].(msg.TemplateCardMsg)
+	s.Equal(msg.CardTypeTextNotice, card.CardType)
+	s.Equal("HighLatency", card.MainTitle.Title)
+	s.Equal("2", card.EmphasisContent.Title)
+	s.Equal("http://alertmanager:9093", card.CardAction.Url)
+	s.Len(card.HorizontalContentList, 2)
+	s.Equal("http://prometheus/graph?g0.expr=latency", card.HorizontalContentList[0].Url)
+}
+
+func (s *handlerTestSuite) TestShouldRenderGenericPayload() {
+	renderer := msg.NewRenderer(msg.RendererWithFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	s.Require().NoError(renderer.Register("build", msg.MarkdownTemplate(
+		"**{{.project | upper}}** build {{.status}}: {{.message}}")))
+
+	dedup := NewDeduplicator(storage.NewMemoryStorage(), time.Hour)
+	handler := NewGenericHandler(s.router, renderer, "build", GenericWithDeduplicator(dedup))
+
+	body := `{"project": "api", "status": "failed", "message": "test_*_failed", "labels": {"team": "db"}}`
+	s.Equal(http.StatusOK, s.post(handler, body).Code)
+	s.Equal(http.StatusOK, s.post(handler, body).Code)
+
+	s.Require().Len(s.db.messages, 1)
+	s.Equal(`**API** build failed: test\_\*\_failed`, s.db.messages[0].(msg.MarkdownMsg).Content)
+	s.Empty(s.fallback.messages)
+}
+
+func (s *handlerTestSuite) TestShouldExtractGenericAlert() {
+	alert := extractAlert(map[string]interface{}{
+		"status": "RESOLVED",
+		"labels": map[string]interface{}{"team": "db", "priority": float64(1)},
+	})
+	s.Equal(StatusResolved, alert.Status)
+	s.Equal(map[string]string{"team": "db", "priority": "1"}, alert.Labels)
+	s.Equal(LabelsFingerprint(alert.Labels), alert.ID())
+}
+
+func TestHandler(t *testing.T) {
+	suite.Run(t, new(handlerTestSuite))
+}
@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Route 告警路由，标签全部匹配时告警发送到Target
+type Route struct {
+	Name     string                    // 路由名称，用于区分不同的路由，同时作为去重的命名空间
+	Match    map[string]string         // 标签值需要完全相等
+	MatchRE  map[string]*regexp.Regexp // 标签值需要匹配正则表达式，表达式需自行锚定
+	Target   Target                    // 发送目标
+	Continue bool                      // 匹配后是否继续匹配后续的路由
+}
+
+// Matches 告警的标签是否匹配路由
+func (r Route) Matches(labels map[string]string) bool {
+	for name, value := range r.Match {
+		if labels[name] != value {
+			return false
+		}
+	}
+	for name, re := range r.MatchRE {
+		if !re.MatchString(labels[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Router 按顺序匹配路由，未匹配任何路由的告警发送到默认路由
+type Router struct {
+	routes   []Route
+	fallback *Route
+}
+
+// NewRouter 创建路由，fallback为nil时丢弃未匹配的告警
+//
+// 未指定名称的路由按照顺序命名为route0、route1等
+func NewRouter(fallback Target, routes ...Route) *Router {
+	router := &Router{routes: make([]Route, len(routes))}
+	for i, route := range routes {
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route%d", i)
+		}
+		router.routes[i] = route
+	}
+	if fallback != nil {
+		router.fallback = &Route{Name: "default", Target: fallback}
+	}
+	return router
+}
+
+// Match 返回告警匹配的路由
+func (r *Router) Match(labels map[string]string) []Route {
+	var matched []Route
+	for _, route := range r.routes {
+		if route.Matches(labels) {
+			matched = append(matched, route)
+			if !route.Continue {
+				break
+			}
+		}
+	}
+	if len(matched) == 0 && r.fallback != nil {
+		matched = append(matched, *r.fallback)
+	}
+	return matched
+}
@@ -0,0 +1,23 @@
+package alert
+
+import (
+	"github.com/huimingz/wechatgo/wecom/msg"
+	"github.com/huimingz/wechatgo/wecom/notify"
+	"github.com/huimingz/wechatgo/wecom/robot"
+)
+
+// Target 告警消息的发送目标，参见notify.Target
+type Target = notify.Target
+
+// TargetFunc 将函数转换为Target
+type TargetFunc = notify.TargetFunc
+
+// AppTarget 通过应用消息发送给指定的成员、部门或标签，参见notify.AppTarget
+func AppTarget(w *msg.WechatMsg, to msg.Recipients, options ...msg.SendOption) Target {
+	return notify.AppTarget(w, to, options...)
+}
+
+// RobotTarget 通过群机器人发送，参见notify.RobotTarget
+func RobotTarget(r *robot.Robot) Target {
+	return notify.RobotTarget(r)
+}
@@ -0,0 +1,242 @@
+// Package appchat 应用群聊管理及消息推送
+package appchat
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/huimingz/wechatgo/wecom"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+const (
+	urlCreate = "/cgi-bin/appchat/create"
+	urlUpdate = "/cgi-bin/appchat/update"
+	urlGet    = "/cgi-bin/appchat/get"
+	urlSend   = "/cgi-bin/appchat/send"
+)
+
+// 群聊限制
+const (
+	MinMembers      = 2    // 群成员的最少人数
+	MaxMembers      = 2000 // 群成员的最多人数
+	MaxChatIdLength = 32   // 群聊id的最大长度
+)
+
+var chatIdPattern = regexp.MustCompile(`^[0-9A-Za-z]{1,32}$`)
+
+// ErrInvalidChatId 群聊id不合法，群聊id只能是字符串0-9及a-zA-Z，最长32个字符
+var ErrInvalidChatId = errors.New("appchat: chatid must be 1-32 characters of 0-9a-zA-Z")
+
+// ValidateChatId 校验群聊id
+func ValidateChatId(chatId string) error {
+	if !chatIdPattern.MatchString(chatId) {
+		return ErrInvalidChatId
+	}
+	return nil
+}
+
+// ChatIdFromKey 根据业务key生成固定的群聊id，相同的key总是生成相同的群聊id
+//
+// 可用于将业务对象（如故障单号）与群聊对应，无需额外保存群聊id
+func ChatIdFromKey(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])[:MaxChatIdLength]
+}
+
+// ChatInfo 群聊信息
+type ChatInfo struct {
+	ChatId   string   `json:"chatid"`   // 群聊唯一标志
+	Name     string   `json:"name"`     // 群聊名
+	Owner    string   `json:"owner"`    // 群主id
+	UserList []string `json:"userlist"` // 群成员id列表
+}
+
+// CreateOption 创建群聊的参数
+type CreateOption struct {
+	// 群聊名，最多50个utf8字符，超过将截断
+	Name string `json:"name,omitempty"`
+
+	// 指定群主的id。如果不指定，系统会随机从userlist中选一人作为群主
+	Owner string `json:"owner,omitempty"`
+
+	// 群成员id列表。至少2人，至多2000人
+	UserList []string `json:"userlist"`
+
+	// 群聊的唯一标志，不能与已有的群重复；字符串类型，最长32个字符。只允许字符0-9及字母a-zA-Z。
+	// 如果不填，系统会随机生成群id
+	ChatId string `json:"chatid,omitempty"`
+}
+
+func (option CreateOption) validate() error {
+	if n := len(option.UserList); n < MinMembers || n > MaxMembers {
+		return fmt.Errorf("appchat: userlist must contain %d-%d members (got %d)", MinMembers, MaxMembers, n)
+	}
+	if option.ChatId != "" {
+		return ValidateChatId(option.ChatId)
+	}
+	return nil
+}
+
+type UpdateOptionFn func(data *updateData)
+
+type updateData struct {
+	ChatId      string   `json:"chatid"`
+	Name        string   `json:"name,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	AddUserList []string `json:"add_user_list,omitempty"`
+	DelUserList []string `json:"del_user_list,omitempty"`
+}
+
+// UpdateWithName 修改群聊名，最多50个utf8字符，超过将截断
+func UpdateWithName(name string) UpdateOptionFn {
+	return func(data *updateData) {
+		data.Name = name
+	}
+}
+
+// UpdateWithOwner 更换群主
+func UpdateWithOwner(owner string) UpdateOptionFn {
+	return func(data *updateData) {
+		data.Owner = owner
+	}
+}
+
+// UpdateWithAddUsers 添加群成员
+func UpdateWithAddUsers(userIds ...string) UpdateOptionFn {
+	return func(data *updateData) {
+		data.AddUserList = append(data.AddUserList, userIds...)
+	}
+}
+
+// UpdateWithDelUsers 移除群成员
+func UpdateWithDelUsers(userIds ...string) UpdateOptionFn {
+	return func(data *updateData) {
+		data.DelUserList = append(data.DelUserList, userIds...)
+	}
+}
+
+type SendOptionFn func(data *sendData)
+
+type sendData struct {
+	ChatId string
+	Safe   int
+}
+
+// SendWithSafe 设置是否是保密消息，0表示否，1表示是
+func SendWithSafe(safe int) SendOptionFn {
+	return func(data *sendData) {
+		data.Safe = safe
+	}
+}
+
+// 群聊支持的消息类型
+var supportedMsgTypes = map[string]bool{
+	"text":     true,
+	"image":    true,
+	"voice":    true,
+	"video":    true,
+	"file":     true,
+	"textcard": true,
+	"news":     true,
+	"mpnews":   true,
+	"markdown": true,
+}
+
+type WechatAppChat struct {
+	Client *wecom.Client
+}
+
+func NewWechatAppChat(client *wecom.Client) *WechatAppChat {
+	return &WechatAppChat{Client: client}
+}
+
+// Create 创建群聊会话，返回群聊的唯一标志
+//
+// 只允许企业自建应用调用，且应用的可见范围必须是根部门；群成员必须在应用的可见范围内。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90245
+func (w WechatAppChat) Create(ctx context.Context, option CreateOption) (chatId string, err error) {
+	if err = option.validate(); err != nil {
+		return "", err
+	}
+
+	out := struct {
+		ChatId string `json:"chatid"`
+	}{}
+	err = w.Client.Post(ctx, urlCreate, nil, option, nil, &out)
+	chatId = out.ChatId
+	return
+}
+
+// Update 修改群聊会话，可以修改群聊名、更换群主以及添加、移除群成员
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90246
+func (w WechatAppChat) Update(ctx context.Context, chatId string, options ...UpdateOptionFn) error {
+	if err := ValidateChatId(chatId); err != nil {
+		return err
+	}
+
+	data := updateData{ChatId: chatId}
+	for _, fn := range options {
+		fn(&data)
+	}
+	return w.Client.Post(ctx, urlUpdate, nil, data, nil, nil)
+}
+
+// Get 获取群聊会话
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90247
+func (w WechatAppChat) Get(ctx context.Context, chatId string) (*ChatInfo, error) {
+	if err := ValidateChatId(chatId); err != nil {
+		return nil, err
+	}
+
+	values := url.Values{}
+	values.Add("chatid", chatId)
+
+	out := struct {
+		ChatInfo ChatInfo `json:"chat_info"`
+	}{}
+	if err := w.Client.Get(ctx, urlGet, values, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out.ChatInfo, nil
+}
+
+// Send 应用推送消息到群聊，消息类型与应用消息相同
+//
+// 支持文本、图片、语音、视频、文件、文本卡片、图文、图文（mpnews）及markdown消息，
+// 发送前会校验消息内容，违反限制时返回*msg.ValidationError。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90248
+func (w WechatAppChat) Send(ctx context.Context, chatId string, message msg.Message, options ...SendOptionFn) error {
+	if err := ValidateChatId(chatId); err != nil {
+		return err
+	}
+	msgType := message.MsgType()
+	if !supportedMsgTypes[msgType] {
+		return fmt.Errorf("appchat: unsupported message type %q", msgType)
+	}
+	if err := msg.Validate(message); err != nil {
+		return err
+	}
+
+	data := sendData{ChatId: chatId}
+	for _, fn := range options {
+		fn(&data)
+	}
+
+	payload := map[string]interface{}{
+		"chatid":  data.ChatId,
+		"msgtype": msgType,
+		msgType:   message,
+		"safe":    data.Safe,
+	}
+	return w.Client.Post(ctx, urlSend, nil, payload, nil, nil)
+}
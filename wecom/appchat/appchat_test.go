@@ -0,0 +1,120 @@
+package appchat
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/testdata"
+	"github.com/huimingz/wechatgo/wecom"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+type appChatTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	appChat   *WechatAppChat
+}
+
+func (s *appChatTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+	conf := testdata.TestConf
+	client := wecom.NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+	s.appChat = NewWechatAppChat(client)
+}
+
+func (s *appChatTestSuite) TestShouldCreateChat() {
+	s.transport.RegisterPost(urlCreate, `{"errcode":0,"errmsg":"ok","chatid":"incident42"}`)
+
+	chatId, err := s.appChat.Create(context.Background(), CreateOption{
+		Name: "故障处理", Owner: "zhangsan", UserList: []string{"zhangsan", "lisi"}, ChatId: "incident42",
+	})
+
+	s.Require().NoError(err)
+	s.Equal("incident42", chatId)
+	s.Equal(map[string]any{
+		"name": "故障处理", "owner": "zhangsan", "userlist": []any{"zhangsan", "lisi"}, "chatid": "incident42",
+	}, s.transport.Requests()[0])
+}
+
+func (s *appChatTestSuite) TestShouldRejectInvalidCreateOption() {
+	_, err := s.appChat.Create(context.Background(), CreateOption{UserList: []string{"zhangsan"}})
+	s.Error(err)
+
+	_, err = s.appChat.Create(context.Background(), CreateOption{UserList: []string{"a", "b"}, ChatId: "incident-42"})
+	s.True(errors.Is(err, ErrInvalidChatId))
+	s.Empty(s.transport.Requests())
+}
+
+func (s *appChatTestSuite) TestShouldUpdateMembership() {
+	s.transport.RegisterPost(urlUpdate, `{"errcode":0,"errmsg":"ok"}`)
+
+	err := s.appChat.Update(context.Background(), "incident42",
+		UpdateWithOwner("lisi"), UpdateWithAddUsers("wangwu", "zhaoliu"), UpdateWithDelUsers("zhangsan"))
+
+	s.Require().NoError(err)
+	s.Equal(map[string]any{
+		"chatid":        "incident42",
+		"owner":         "lisi",
+		"add_user_list": []any{"wangwu", "zhaoliu"},
+		"del_user_list": []any{"zhangsan"},
+	}, s.transport.Requests()[0])
+}
+
+func (s *appChatTestSuite) TestShouldGetChat() {
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+urlGet, httpmock.NewStringResponder(http.StatusOK,
+		`{"errcode":0,"errmsg":"ok","chat_info":{"chatid":"incident42","name":"故障处理","owner":"zhangsan","userlist":["zhangsan","lisi"]}}`))
+
+	info, err := s.appChat.Get(context.Background(), "incident42")
+
+	s.Require().NoError(err)
+	s.Equal(&ChatInfo{ChatId: "incident42", Name: "故障处理", Owner: "zhangsan", UserList: []string{"zhangsan", "lisi"}}, info)
+}
+
+func (s *appChatTestSuite) TestShouldSendMessage() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok"}`)
+
+	err := s.appChat.Send(context.Background(), "incident42", msg.MarkdownMsg{Content: "**已恢复**"}, SendWithSafe(1))
+
+	s.Require().NoError(err)
+	s.Equal(map[string]any{
+		"chatid":   "incident42",
+		"msgtype":  "markdown",
+		"markdown": map[string]any{"content": "**已恢复**"},
+		"safe":     float64(1),
+	}, s.transport.Requests()[0])
+}
+
+func (s *appChatTestSuite) TestShouldRejectUnsupportedMessage() {
+	err := s.appChat.Send(context.Background(), "incident42", msg.TaskCardMsg{})
+
+	s.Error(err)
+	s.Empty(s.transport.Requests())
+}
+
+func (s *appChatTestSuite) TestShouldReturnApiError() {
+	s.transport.RegisterPost(urlSend, `{"errcode":86003,"errmsg":"chat not exist"}`)
+
+	err := s.appChat.Send(context.Background(), "incident42", msg.TextMsg{Content: "hello"})
+
+	s.Error(err)
+}
+
+func TestChatIdFromKey(t *testing.T) {
+	chatId := ChatIdFromKey("INC-2024-0042")
+	if err := ValidateChatId(chatId); err != nil {
+		t.Errorf("ChatIdFromKey() error = '%s'", err)
+	}
+	if chatId != ChatIdFromKey("INC-2024-0042") || chatId == ChatIdFromKey("INC-2024-0043") {
+		t.Errorf("ChatIdFromKey() error = 'chatid is not stable per key'")
+	}
+}
+
+func TestAppChatTestSuite(t *testing.T) {
+	suite.Run(t, new(appChatTestSuite))
+}
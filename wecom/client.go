@@ -82,6 +82,7 @@ func NewClient(corpId, corpSecret string, agentId int, options ...ClientOptionFn
 	client.CorpSecret = corpSecret
 	client.AgentId = agentId
 	client.baseUrl = _BASE_URL
+	client.storageKey = "accesstoken_" + corpSecret
 
 	for _, opt := range options {
 		opt(&client)
@@ -109,9 +110,6 @@ func NewClient(corpId, corpSecret string, agentId int, options ...ClientOptionFn
 
 // GetAccessTokenStorageKey 获取认证令牌缓存Key
 func (client *Client) GetAccessTokenStorageKey() string {
-	if client.storageKey == "" {
-		client.storageKey = "accesstoken_" + client.CorpSecret
-	}
 	return client.storageKey
 }
 
@@ -317,6 +315,11 @@ func (client *Client) Get(ctx context.Context, path string, values url.Values, e
 }
 
 func (client *Client) RawGet(ctx context.Context, path string, values url.Values) (resp *http.Response, err error) {
+	return client.RawGetWithHeader(ctx, path, values, nil)
+}
+
+// RawGetWithHeader 发送带有指定请求头的GET请求，返回原始响应，调用方需关闭响应体
+func (client *Client) RawGetWithHeader(ctx context.Context, path string, values url.Values, header http.Header) (resp *http.Response, err error) {
 	values, err = client.valuesTokenCompletion(ctx, values)
 	if err != nil {
 		return nil, err
@@ -326,6 +329,9 @@ func (client *Client) RawGet(ctx context.Context, path string, values url.Values
 	if err != nil {
 		return nil, err
 	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
 
 	request = request.WithContext(ctx)
 	resp, err = client.httpClient.Do(request)
@@ -367,3 +373,73 @@ func (client *Client) AdvPost(ctx context.Context, path, contentType string, url
 func (client *Client) Post(ctx context.Context, url_ string, values url.Values, data interface{}, errmsg wechatgo.WechatMsgInterface, out interface{}) error {
 	return client.AdvPost(ctx, url_, "application/json", values, data, errmsg, out)
 }
+
+// access token无效或已过期的错误码，重新获取access token后可以重试
+var tokenErrCodes = map[int]bool{40014: true, 42001: true}
+
+func isTokenError(err error) bool {
+	var wxErr wechatgo.WechatMsgInterface
+	return errors.As(err, &wxErr) && tokenErrCodes[wxErr.GetErrCode()]
+}
+
+// ErrNotRetryable 读取请求体时返回的错误满足errors.Is(err, ErrNotRetryable)时，PostStream不再重试，
+// 例如上传内容在读取过程中校验失败
+var ErrNotRetryable = errors.New("wecom: request is not retryable")
+
+// PostStream 以流的方式发送请求体，避免将请求体完整读入内存
+//
+// newBody每次调用都需返回一个从头开始的新请求体。access token无效、已过期或发生网络错误时，
+// 会再次调用newBody重试一次，newBody无法再次提供请求体时返回原来的错误；
+// 读取请求体返回ErrNotRetryable时不重试。
+// contentLength小于0时使用分块传输。
+func (client *Client) PostStream(ctx context.Context, path, contentType string, urlValues url.Values, contentLength int64, newBody func() (io.ReadCloser, error), errmsg wechatgo.WechatMsgInterface, out interface{}) error {
+	const maxAttempts = 2
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		values := url.Values{}
+		for k, v := range urlValues {
+			if k != "access_token" || attempt == 1 {
+				values[k] = v
+			}
+		}
+		values, err := client.valuesTokenCompletion(ctx, values)
+		if err != nil {
+			return err
+		}
+
+		body, err := newBody()
+		if err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, client.resourceURL(path, values), body)
+		if err != nil {
+			body.Close()
+			return err
+		}
+		request.ContentLength = contentLength
+		request.GetBody = newBody
+		request.Header.Set("Content-Type", contentType)
+
+		resp, err := client.httpClient.Do(request)
+		if err == nil {
+			err = client.respHandler(ctx, resp, errmsg, out)
+			if err == nil || !isTokenError(err) {
+				return err
+			}
+			if err := client.FetchAccessToken(ctx); err != nil {
+				return err
+			}
+		} else if ctx.Err() != nil || errors.Is(err, ErrNotRetryable) {
+			return err
+		}
+
+		client.log.Warn(ctx, fmt.Sprintf("PostStream: attempt %d failed, Error: %s", attempt, err))
+		lastErr = err
+	}
+	return lastErr
+}
@@ -12,7 +12,17 @@ func newCorpGroup(client *Client) *CorpGroup {
 	return &CorpGroup{client: client}
 }
 
+// AppShareInfos 获取应用共享信息
 func (g *CorpGroup) AppShareInfos(ctx context.Context, agentId int, option *corpGroupListOption) ([]CorpGroupData, error) {
+	page, err := g.ListAppShareInfo(ctx, agentId, option)
+	if err != nil {
+		return nil, err
+	}
+	return page.CorpList, nil
+}
+
+// ListAppShareInfo 分页获取应用共享信息，返回结果包含下一页的游标
+func (g *CorpGroup) ListAppShareInfo(ctx context.Context, agentId int, option *corpGroupListOption) (*CorpGroupSharePage, error) {
 	if option == nil {
 		option = NewCorpGroupListOption()
 	}
@@ -30,13 +40,39 @@ func (g *CorpGroup) AppShareInfos(ctx context.Context, agentId int, option *corp
 		Cursor:       option.Cursor,
 	}
 	uri := "/cgi-bin/corpgroup/corp/list_app_share_info"
-	var out struct {
-		CorpList []CorpGroupData `json:"corp_list"`
-	}
+	var out CorpGroupSharePage
 	if err := g.client.Post(ctx, uri, nil, payload, nil, &out); err != nil {
 		return nil, err
 	}
-	return out.CorpList, nil
+	return &out, nil
+}
+
+// AppShareInfoPager 应用共享信息的分页迭代器
+//
+// option中的Limit和Cursor会被迭代器的分页大小和游标覆盖
+func (g *CorpGroup) AppShareInfoPager(agentId int, option *corpGroupListOption, options ...PagerOptionFn) *Pager[CorpGroupData] {
+	if option == nil {
+		option = NewCorpGroupListOption()
+	}
+	return NewPager(func(ctx context.Context, cursor string, limit int) ([]CorpGroupData, string, error) {
+		opt := *option
+		opt.Cursor = nil
+		if cursor != "" {
+			opt.SetCursor(cursor)
+		}
+		if limit > 0 {
+			opt.SetLimit(uint64(limit))
+		}
+
+		page, err := g.ListAppShareInfo(ctx, agentId, &opt)
+		if err != nil {
+			return nil, "", err
+		}
+		if page.Ending == 1 {
+			return page.CorpList, "", nil
+		}
+		return page.CorpList, page.NextCursor, nil
+	}, options...)
 }
 
 // GetCorpToken 获取下游企业的 access_token
@@ -78,6 +114,12 @@ type CorpGroupData struct {
 	CorpName string `json:"corp_name"` // 企业名称
 }
 
+type CorpGroupSharePage struct {
+	Ending     int             `json:"ending"`      // 1表示分页拉取完毕
+	NextCursor string          `json:"next_cursor"` // 分页游标，下次请求时填写以获取之后分页的记录
+	CorpList   []CorpGroupData `json:"corp_list"`   // 应用共享信息
+}
+
 const (
 	CorpGroupBusinessTypeInter    corpGroupBusinessType = 0 // 企业互联/局校互联
 	CorpGroupBusinessTypeUpstream corpGroupBusinessType = 1 // 上下游企业
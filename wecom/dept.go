@@ -69,15 +69,44 @@ func (w WechatDept) GetList(ctx context.Context) ([]DeptInfo, error) {
 	return out.Department, err
 }
 
+// GetTree 获取部门列表并构建部门树，参见NewDeptTree
+func (w WechatDept) GetTree(ctx context.Context) (*DeptTree, error) {
+	depts, err := w.GetList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewDeptTree(depts)
+}
+
+// GetSubList 获取子部门列表，返回的部门信息只有部门id、父部门id及次序
+//
+// Deprecated: 使用GetIdList
 func (w WechatDept) GetSubList(ctx context.Context, id int) ([]DeptInfo, error) {
-	values := url.Values{}
-	values.Add("id", strconv.Itoa(id))
+	ids, err := w.GetIdList(ctx, id)
+	depts := make([]DeptInfo, len(ids))
+	for i, dept := range ids {
+		depts[i] = DeptInfo{Id: dept.Id, ParentId: dept.ParentId, Order: dept.Order}
+	}
+	return depts, err
+}
+
+// GetIdList 获取子部门id列表
+//
+// id为0时获取应用可见范围内的全部部门，否则获取该部门及其所有子部门，只返回部门id、父部门id及次序
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/95350
+func (w WechatDept) GetIdList(ctx context.Context, id int) ([]DeptId, error) {
+	var values url.Values
+	if id > 0 {
+		values = url.Values{}
+		values.Add("id", strconv.Itoa(id))
+	}
 
 	out := struct {
-		Department []DeptInfo `json:"department"`
+		DepartmentId []DeptId `json:"department_id"`
 	}{}
 	err := w.Client.Get(ctx, urlDeptSimpleList, values, nil, &out)
-	return out.Department, err
+	return out.DepartmentId, err
 }
 
 // GetUserList 获取部门成员
@@ -7,6 +7,13 @@ type DeptInfo struct {
 	Order    int    `json:"order"`    // 在父部门中的次序值。order值大的排序靠前。值范围是[0, 2^32)
 }
 
+// DeptId 部门id及其父部门、次序
+type DeptId struct {
+	Id       int `json:"id"`       // 部门id
+	ParentId int `json:"parentid"` // 父部门id，根部门为0
+	Order    int `json:"order"`    // 在父部门中的次序值，order值大的排序靠前
+}
+
 type DepartmentUserInfo struct {
 	UserId     string `json:"userid"`     // 成员UserID。对应管理端的帐号
 	Name       string `json:"name"`       // 成员名称
@@ -40,6 +40,33 @@ func (s *departmentTestSuite) TestShouldGetDepartments() {
 	s.NotEmpty(departments)
 }
 
+func (s *departmentTestSuite) TestShouldGetDepartmentTree() {
+	s.registerResponder(http.MethodGet, urlGetDepartments)
+
+	tree, err := s.dept.GetTree(context.Background())
+
+	s.NoError(err)
+	s.Equal("广州研发中心/邮箱产品部", tree.Path(3))
+}
+
+func (s *departmentTestSuite) TestShouldGetDepartmentIds() {
+	s.registerResponder(http.MethodGet, urlDeptSimpleList)
+
+	depts, err := s.dept.GetIdList(context.Background(), 2)
+
+	s.NoError(err)
+	s.Equal([]DeptId{{Id: 2, ParentId: 1, Order: 10}, {Id: 3, ParentId: 2, Order: 40}}, depts)
+}
+
+func (s *departmentTestSuite) TestShouldGetSubDepartments() {
+	s.registerResponder(http.MethodGet, urlDeptSimpleList)
+
+	depts, err := s.dept.GetSubList(context.Background(), 2)
+
+	s.NoError(err)
+	s.Equal([]DeptInfo{{Id: 2, ParentId: 1, Order: 10}, {Id: 3, ParentId: 2, Order: 40}}, depts)
+}
+
 func (s *departmentTestSuite) TestShouldRemoveDepartment() {
 	s.registerSuccessResponder(http.MethodGet, urlRemoveDepartment)
 
@@ -0,0 +1,274 @@
+package wecom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeptNode 部门树的节点
+type DeptNode struct {
+	DeptInfo
+	Parent   *DeptNode   // 父部门，根节点为nil
+	Children []*DeptNode // 子部门，按Order降序排列，Order相同时按id升序排列
+}
+
+// Depth 节点的深度，根节点为0
+func (n *DeptNode) Depth() int {
+	depth := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		depth++
+	}
+	return depth
+}
+
+// DeptTreeError 部门列表中存在重复的id或循环的父子关系
+//
+// 循环中的部门及其所有子部门不会加入部门树
+type DeptTreeError struct {
+	Duplicates []int   // 重复的部门id，只保留第一个出现的部门
+	Cycles     [][]int // 形成循环的部门id，每个循环从id最小的部门开始
+	Excluded   []int   // 因为循环没有加入部门树的部门id，升序排列
+}
+
+func (e *DeptTreeError) Error() string {
+	var reasons []string
+	if len(e.Duplicates) > 0 {
+		reasons = append(reasons, fmt.Sprintf("duplicate departments %v", e.Duplicates))
+	}
+	if len(e.Cycles) > 0 {
+		reasons = append(reasons, fmt.Sprintf("department cycles %v", e.Cycles))
+	}
+	return "invalid department tree: " + strings.Join(reasons, ", ")
+}
+
+// DeptTree 部门树
+//
+// 父部门不在列表中的部门作为根节点，通常是根部门（父部门id为0）或应用可见范围内的顶层部门
+type DeptTree struct {
+	nodes map[int]*DeptNode
+	roots []*DeptNode
+}
+
+// NewDeptTree 根据WechatDept.GetList返回的部门列表构建部门树
+//
+// 部门列表中存在重复的id或循环的父子关系时，返回由其余部门构成的部门树及*DeptTreeError
+func NewDeptTree(depts []DeptInfo) (*DeptTree, error) {
+	treeErr := &DeptTreeError{}
+	all := make(map[int]*DeptNode, len(depts))
+	for _, dept := range depts {
+		if _, ok := all[dept.Id]; ok {
+			treeErr.Duplicates = append(treeErr.Duplicates, dept.Id)
+			continue
+		}
+		all[dept.Id] = &DeptNode{DeptInfo: dept}
+	}
+
+	tree := &DeptTree{nodes: make(map[int]*DeptNode, len(all))}
+	for _, node := range all {
+		if parent, ok := all[node.ParentId]; ok {
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+		} else {
+			tree.roots = append(tree.roots, node)
+		}
+	}
+	sortDeptNodes(tree.roots)
+
+	// 从根节点无法到达的部门处于循环中或是循环中部门的子部门
+	for _, root := range tree.roots {
+		walkDeptNode(root, 0, func(node *DeptNode, depth int) error {
+			sortDeptNodes(node.Children)
+			tree.nodes[node.Id] = node
+			return nil
+		})
+	}
+	if len(tree.nodes) < len(all) {
+		treeErr.Cycles, treeErr.Excluded = findDeptCycles(all, tree.nodes)
+	}
+
+	if len(treeErr.Duplicates) > 0 || len(treeErr.Cycles) > 0 {
+		return tree, treeErr
+	}
+	return tree, nil
+}
+
+func sortDeptNodes(nodes []*DeptNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Order != nodes[j].Order {
+			return nodes[i].Order > nodes[j].Order
+		}
+		return nodes[i].Id < nodes[j].Id
+	})
+}
+
+func findDeptCycles(all, reachable map[int]*DeptNode) (cycles [][]int, excluded []int) {
+	for id := range all {
+		if _, ok := reachable[id]; !ok {
+			excluded = append(excluded, id)
+		}
+	}
+	sort.Ints(excluded)
+
+	seen := map[int]bool{}
+	for _, id := range excluded {
+		// 沿父部门向上查找，第一个重复访问的部门处于循环中
+		path := map[int]bool{}
+		node := all[id]
+		for !path[node.Id] && !seen[node.Id] {
+			path[node.Id] = true
+			node = node.Parent
+		}
+		for id := range path {
+			seen[id] = true
+		}
+		if !path[node.Id] {
+			continue
+		}
+
+		cycle := []int{node.Id}
+		for p := node.Parent; p != node; p = p.Parent {
+			cycle = append(cycle, p.Id)
+		}
+		start := 0
+		for i := range cycle {
+			if cycle[i] < cycle[start] {
+				start = i
+			}
+		}
+		cycles = append(cycles, append(cycle[start:], cycle[:start]...))
+	}
+	return cycles, excluded
+}
+
+// Get 根据部门id获取节点
+func (t *DeptTree) Get(id int) (*DeptNode, bool) {
+	node, ok := t.nodes[id]
+	return node, ok
+}
+
+// Len 部门树中的部门数量
+func (t *DeptTree) Len() int {
+	return len(t.nodes)
+}
+
+// Roots 根节点，按Order降序排列
+func (t *DeptTree) Roots() []*DeptNode {
+	return t.roots
+}
+
+// Orphans 父部门id不为0且父部门不在列表中的根节点，一般是应用可见范围内的顶层部门，也可能是部门列表不完整
+func (t *DeptTree) Orphans() []*DeptNode {
+	var orphans []*DeptNode
+	for _, root := range t.roots {
+		if root.ParentId != 0 {
+			orphans = append(orphans, root)
+		}
+	}
+	return orphans
+}
+
+// Ancestors 部门的所有上级部门，从根节点开始，不包含部门本身。部门不存在时返回nil
+func (t *DeptTree) Ancestors(id int) []*DeptNode {
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+
+	var ancestors []*DeptNode
+	for p := node.Parent; p != nil; p = p.Parent {
+		ancestors = append(ancestors, p)
+	}
+	for i, j := 0, len(ancestors)-1; i < j; i, j = i+1, j-1 {
+		ancestors[i], ancestors[j] = ancestors[j], ancestors[i]
+	}
+	return ancestors
+}
+
+// PathNames 从根节点到部门的部门名称，部门不存在时返回nil
+func (t *DeptTree) PathNames(id int) []string {
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, ancestor := range t.Ancestors(id) {
+		names = append(names, ancestor.Name)
+	}
+	return append(names, node.Name)
+}
+
+// Path 部门的完整路径，使用/连接部门名称，如"公司/研发部/后端组"。部门不存在时返回空字符串
+func (t *DeptTree) Path(id int) string {
+	return strings.Join(t.PathNames(id), "/")
+}
+
+// Walk 按先序遍历部门及其所有子部门，depth为相对于id部门的深度。id为0时遍历整棵树
+//
+// fn返回错误时停止遍历并返回该错误，部门不存在时不调用fn
+func (t *DeptTree) Walk(id int, fn func(node *DeptNode, depth int) error) error {
+	if id == 0 {
+		for _, root := range t.roots {
+			if err := walkDeptNode(root, 0, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	node, ok := t.nodes[id]
+	if !ok {
+		return nil
+	}
+	return walkDeptNode(node, 0, fn)
+}
+
+func walkDeptNode(node *DeptNode, depth int, fn func(node *DeptNode, depth int) error) error {
+	if err := fn(node, depth); err != nil {
+		return err
+	}
+	for _, child := range node.Children {
+		if err := walkDeptNode(child, depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subtree 部门及其所有子部门，按先序遍历的顺序排列。id为0时返回整棵树
+func (t *DeptTree) Subtree(id int) []*DeptNode {
+	var nodes []*DeptNode
+	t.Walk(id, func(node *DeptNode, depth int) error {
+		nodes = append(nodes, node)
+		return nil
+	})
+	return nodes
+}
+
+// Contains 部门ancestor是否为部门id本身或其上级部门
+func (t *DeptTree) Contains(ancestor, id int) bool {
+	node, ok := t.nodes[id]
+	for ; ok && node != nil; node = node.Parent {
+		if node.Id == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// UserPaths 成员所属部门的完整路径，顺序与user.Department一致，忽略不在部门树中的部门
+func (t *DeptTree) UserPaths(user UserInfo) []string {
+	var paths []string
+	for _, id := range user.Department {
+		if _, ok := t.nodes[id]; ok {
+			paths = append(paths, t.Path(id))
+		}
+	}
+	return paths
+}
+
+// UserMainPath 成员主部门的完整路径，主部门不在部门树中时返回空字符串
+func (t *DeptTree) UserMainPath(user UserInfo) string {
+	return t.Path(user.MainDepartMent)
+}
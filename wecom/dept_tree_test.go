@@ -0,0 +1,100 @@
+package wecom
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type deptTreeTestSuite struct {
+	suite.Suite
+	tree *DeptTree
+}
+
+func (s *deptTreeTestSuite) SetupTest() {
+	tree, err := NewDeptTree([]DeptInfo{
+		{Id: 4, Name: "后端组", ParentId: 2, Order: 10},
+		{Id: 5, Name: "前端组", ParentId: 2, Order: 20},
+		{Id: 2, Name: "研发部", ParentId: 1},
+		{Id: 3, Name: "销售部", ParentId: 1},
+		{Id: 1, Name: "公司", ParentId: 0},
+	})
+	s.Require().NoError(err)
+	s.tree = tree
+}
+
+func (s *deptTreeTestSuite) ids(nodes []*DeptNode) []int {
+	var ids []int
+	for _, node := range nodes {
+		ids = append(ids, node.Id)
+	}
+	return ids
+}
+
+func (s *deptTreeTestSuite) TestShouldBuildOrderedTree() {
+	s.Equal(5, s.tree.Len())
+	s.Equal([]int{1}, s.ids(s.tree.Roots()))
+	s.Empty(s.tree.Orphans())
+	s.Equal([]int{1, 2, 5, 4, 3}, s.ids(s.tree.Subtree(0)))
+	s.Equal([]int{2, 5, 4}, s.ids(s.tree.Subtree(2)))
+	s.Empty(s.tree.Subtree(99))
+
+	node, ok := s.tree.Get(4)
+	s.Require().True(ok)
+	s.Equal(2, node.Depth())
+	s.Equal("研发部", node.Parent.Name)
+}
+
+func (s *deptTreeTestSuite) TestShouldResolvePaths() {
+	s.Equal([]int{1, 2}, s.ids(s.tree.Ancestors(4)))
+	s.Equal("公司/研发部/后端组", s.tree.Path(4))
+	s.Equal("公司", s.tree.Path(1))
+	s.Equal("", s.tree.Path(99))
+	s.True(s.tree.Contains(2, 4))
+	s.True(s.tree.Contains(4, 4))
+	s.False(s.tree.Contains(3, 4))
+
+	user := UserInfo{UserId: "zhangsan", Department: []int{4, 99, 3}, MainDepartMent: 3}
+	s.Equal([]string{"公司/研发部/后端组", "公司/销售部"}, s.tree.UserPaths(user))
+	s.Equal("公司/销售部", s.tree.UserMainPath(user))
+}
+
+func (s *deptTreeTestSuite) TestShouldStopWalking() {
+	stop := errors.New("stop")
+	var visited []int
+	err := s.tree.Walk(0, func(node *DeptNode, depth int) error {
+		visited = append(visited, node.Id)
+		if node.Id == 5 {
+			return stop
+		}
+		return nil
+	})
+	s.Equal(stop, err)
+	s.Equal([]int{1, 2, 5}, visited)
+}
+
+func (s *deptTreeTestSuite) TestShouldDetectCyclesAndOrphans() {
+	tree, err := NewDeptTree([]DeptInfo{
+		{Id: 2, Name: "研发部", ParentId: 1},
+		{Id: 3, Name: "A", ParentId: 4},
+		{Id: 4, Name: "B", ParentId: 3},
+		{Id: 5, Name: "C", ParentId: 4},
+		{Id: 6, Name: "D", ParentId: 6},
+		{Id: 2, Name: "重复", ParentId: 1},
+	})
+
+	var treeErr *DeptTreeError
+	s.Require().True(errors.As(err, &treeErr))
+	s.Equal([]int{2}, treeErr.Duplicates)
+	s.Equal([][]int{{3, 4}, {6}}, treeErr.Cycles)
+	s.Equal([]int{3, 4, 5, 6}, treeErr.Excluded)
+
+	s.Equal(1, tree.Len())
+	s.Equal([]int{2}, s.ids(tree.Orphans()))
+	s.Equal("研发部", tree.Path(2))
+}
+
+func TestDeptTree(t *testing.T) {
+	suite.Run(t, new(deptTreeTestSuite))
+}
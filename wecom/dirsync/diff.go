@@ -0,0 +1,251 @@
+package dirsync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+// ChangeKind 变更类型
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"   // 新增
+	ChangeRemoved ChangeKind = "removed" // 删除
+	ChangeMoved   ChangeKind = "moved"   // 成员的所属部门或部门的父部门变化，可能同时有其他字段变化
+	ChangeUpdated ChangeKind = "updated" // 字段变化
+)
+
+// UserChange 成员变更
+type UserChange struct {
+	Kind   ChangeKind
+	UserId string
+	Old    *wecom.UserInfo // 变更前的成员，新增时为nil
+	New    *wecom.UserInfo // 变更后的成员，删除时为nil
+	Fields []string        // 变化的字段，使用JSON字段名，如department、mobile
+}
+
+// DeptChange 部门变更
+type DeptChange struct {
+	Kind   ChangeKind
+	Id     int
+	Old    *wecom.DeptInfo // 变更前的部门，新增时为nil
+	New    *wecom.DeptInfo // 变更后的部门，删除时为nil
+	Fields []string        // 变化的字段，使用JSON字段名，如name、parentid
+}
+
+// TagChange 标签变更
+type TagChange struct {
+	Kind               ChangeKind
+	Id                 int
+	Old                *Tag     // 变更前的标签，新增时为nil
+	New                *Tag     // 变更后的标签，删除时为nil
+	Fields             []string // 变化的字段，使用JSON字段名
+	AddedUsers         []string // 加入标签的成员
+	RemovedUsers       []string // 移出标签的成员
+	AddedDepartments   []int    // 加入标签的部门
+	RemovedDepartments []int    // 移出标签的部门
+}
+
+// Diff 两个快照之间的差异，各项变更按id升序排列
+type Diff struct {
+	Users       []UserChange
+	Departments []DeptChange
+	Tags        []TagChange
+}
+
+// Empty 是否没有任何变更
+func (d *Diff) Empty() bool {
+	return len(d.Users) == 0 && len(d.Departments) == 0 && len(d.Tags) == 0
+}
+
+// Compare 比较两个快照，old为nil时所有内容都作为新增
+//
+// 按JSON字段比较成员、部门及标签，字段无法编码为JSON时返回错误
+func Compare(old, new *Snapshot) (*Diff, error) {
+	if old == nil {
+		old = NewSnapshot()
+	}
+	diff := &Diff{}
+
+	for _, id := range unionKeys(old.Users, new.Users, func(a, b string) bool { return a < b }) {
+		change, ok, err := compareUser(id, old.Users, new.Users)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			diff.Users = append(diff.Users, change)
+		}
+	}
+	for _, id := range unionKeys(old.Departments, new.Departments, func(a, b int) bool { return a < b }) {
+		change, ok, err := compareDept(id, old.Departments, new.Departments)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			diff.Departments = append(diff.Departments, change)
+		}
+	}
+	for _, id := range unionKeys(old.Tags, new.Tags, func(a, b int) bool { return a < b }) {
+		change, ok, err := compareTag(id, old.Tags, new.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			diff.Tags = append(diff.Tags, change)
+		}
+	}
+	return diff, nil
+}
+
+func compareUser(id string, olds, news map[string]wecom.UserInfo) (UserChange, bool, error) {
+	o, inOld := olds[id]
+	n, inNew := news[id]
+	change := UserChange{UserId: id}
+	switch {
+	case !inOld:
+		change.Kind, change.New = ChangeAdded, &n
+	case !inNew:
+		change.Kind, change.Old = ChangeRemoved, &o
+	default:
+		fields, err := changedFields(o, n)
+		if err != nil || len(fields) == 0 {
+			return change, false, err
+		}
+		change.Fields = fields
+		change.Kind, change.Old, change.New = ChangeUpdated, &o, &n
+		if containsAny(change.Fields, "department", "main_department") {
+			change.Kind = ChangeMoved
+		}
+	}
+	return change, true, nil
+}
+
+func compareDept(id int, olds, news map[int]wecom.DeptInfo) (DeptChange, bool, error) {
+	o, inOld := olds[id]
+	n, inNew := news[id]
+	change := DeptChange{Id: id}
+	switch {
+	case !inOld:
+		change.Kind, change.New = ChangeAdded, &n
+	case !inNew:
+		change.Kind, change.Old = ChangeRemoved, &o
+	default:
+		fields, err := changedFields(o, n)
+		if err != nil || len(fields) == 0 {
+			return change, false, err
+		}
+		change.Fields = fields
+		change.Kind, change.Old, change.New = ChangeUpdated, &o, &n
+		if containsAny(change.Fields, "parentid") {
+			change.Kind = ChangeMoved
+		}
+	}
+	return change, true, nil
+}
+
+func compareTag(id int, olds, news map[int]Tag) (TagChange, bool, error) {
+	o, inOld := olds[id]
+	n, inNew := news[id]
+	change := TagChange{Id: id}
+	switch {
+	case !inOld:
+		change.Kind, change.New = ChangeAdded, &n
+		change.AddedUsers, change.AddedDepartments = n.Users, n.Departments
+	case !inNew:
+		change.Kind, change.Old = ChangeRemoved, &o
+		change.RemovedUsers, change.RemovedDepartments = o.Users, o.Departments
+	default:
+		fields, err := changedFields(o, n)
+		if err != nil || len(fields) == 0 {
+			return change, false, err
+		}
+		change.Fields = fields
+		change.Kind, change.Old, change.New = ChangeUpdated, &o, &n
+		change.AddedUsers, change.RemovedUsers = sliceDiff(o.Users, n.Users)
+		change.AddedDepartments, change.RemovedDepartments = sliceDiff(o.Departments, n.Departments)
+	}
+	return change, true, nil
+}
+
+// 返回两个map中所有的key，按less排序
+func unionKeys[K comparable, V any](a, b map[K]V, less func(a, b K) bool) []K {
+	keys := make([]K, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
+// 按JSON字段比较两个值，返回值不同的字段名，按字段名排序
+func changedFields(a, b any) ([]string, error) {
+	fa, err := jsonFields(a)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := jsonFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, name := range unionKeys(fa, fb, func(a, b string) bool { return a < b }) {
+		if !bytes.Equal(fa[name], fb[name]) {
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
+
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("dirsync: failed to encode %T: %w", v, err)
+	}
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(content, &fields); err != nil {
+		return nil, fmt.Errorf("dirsync: %T is not encoded as a JSON object: %w", v, err)
+	}
+	return fields, nil
+}
+
+func containsAny(fields []string, names ...string) bool {
+	for _, field := range fields {
+		for _, name := range names {
+			if field == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 返回new中新增的及old中被删除的元素
+func sliceDiff[T comparable](old, new []T) (added, removed []T) {
+	inOld := make(map[T]bool, len(old))
+	for _, v := range old {
+		inOld[v] = true
+	}
+	inNew := make(map[T]bool, len(new))
+	for _, v := range new {
+		inNew[v] = true
+		if !inOld[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range old {
+		if !inNew[v] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
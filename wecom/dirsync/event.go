@@ -0,0 +1,248 @@
+package dirsync
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+// ErrSnapshotOutdated 事件引用了快照中不存在的成员或部门，快照已经过期，需要重新全量同步
+var ErrSnapshotOutdated = errors.New("dirsync: snapshot is outdated, run a full sync")
+
+// 通讯录变更事件的变更类型
+const (
+	ChangeTypeCreateUser  = "create_user"
+	ChangeTypeUpdateUser  = "update_user"
+	ChangeTypeDeleteUser  = "delete_user"
+	ChangeTypeCreateParty = "create_party"
+	ChangeTypeUpdateParty = "update_party"
+	ChangeTypeDeleteParty = "delete_party"
+	ChangeTypeUpdateTag   = "update_tag"
+)
+
+// ChangeContactEvent 通讯录变更事件，即回调消息中Event为change_contact的事件
+//
+// 更新事件只包含变化的字段，指针类型的字段为nil表示未变化。部门及标签成员列表为逗号分隔的字符串
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90970
+type ChangeContactEvent struct {
+	XMLName      xml.Name `xml:"xml"`
+	ToUserName   string   `xml:"ToUserName"`
+	FromUserName string   `xml:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime"`
+	MsgType      string   `xml:"MsgType"`
+	Event        string   `xml:"Event"`
+	ChangeType   string   `xml:"ChangeType"`
+
+	// 成员事件
+	UserId         string  `xml:"UserID"`
+	NewUserId      string  `xml:"NewUserID"` // 变更后的userid，仅在userid变更时返回
+	Name           *string `xml:"Name"`
+	Department     *string `xml:"Department"`
+	MainDepartment *int    `xml:"MainDepartment"`
+	IsLeaderInDept *string `xml:"IsLeaderInDept"`
+	Position       *string `xml:"Position"`
+	Mobile         *string `xml:"Mobile"`
+	Gender         *string `xml:"Gender"`
+	Email          *string `xml:"Email"`
+	Status         *int    `xml:"Status"`
+	Avatar         *string `xml:"Avatar"`
+	Alias          *string `xml:"Alias"`
+	Telephone      *string `xml:"Telephone"`
+	Address        *string `xml:"Address"`
+
+	// 部门事件，部门名称使用Name字段
+	Id       int  `xml:"Id"`
+	ParentId *int `xml:"ParentId"`
+	Order    *int `xml:"Order"`
+
+	// 标签事件
+	TagId         int    `xml:"TagId"`
+	AddUserItems  string `xml:"AddUserItems"`
+	DelUserItems  string `xml:"DelUserItems"`
+	AddPartyItems string `xml:"AddPartyItems"`
+	DelPartyItems string `xml:"DelPartyItems"`
+}
+
+// Apply 将通讯录变更事件应用到快照
+//
+// 删除不存在的对象时忽略；更新不存在的成员或部门时返回ErrSnapshotOutdated，此时快照不会被修改
+func (s *Snapshot) Apply(event ChangeContactEvent) error {
+	switch event.ChangeType {
+	case ChangeTypeCreateUser:
+		user := wecom.UserInfo{UserId: event.UserId}
+		if err := event.applyUser(&user); err != nil {
+			return err
+		}
+		s.Users[user.UserId] = user
+	case ChangeTypeUpdateUser:
+		user, ok := s.Users[event.UserId]
+		if !ok {
+			return fmt.Errorf("%w: user %q not found", ErrSnapshotOutdated, event.UserId)
+		}
+		if err := event.applyUser(&user); err != nil {
+			return err
+		}
+		if event.NewUserId != "" && event.NewUserId != event.UserId {
+			delete(s.Users, event.UserId)
+			user.UserId = event.NewUserId
+			s.renameTagUser(event.UserId, event.NewUserId)
+		}
+		s.Users[user.UserId] = user
+	case ChangeTypeDeleteUser:
+		delete(s.Users, event.UserId)
+	case ChangeTypeCreateParty:
+		dept := wecom.DeptInfo{Id: event.Id}
+		event.applyDept(&dept)
+		s.Departments[dept.Id] = dept
+	case ChangeTypeUpdateParty:
+		dept, ok := s.Departments[event.Id]
+		if !ok {
+			return fmt.Errorf("%w: department %d not found", ErrSnapshotOutdated, event.Id)
+		}
+		event.applyDept(&dept)
+		s.Departments[dept.Id] = dept
+	case ChangeTypeDeleteParty:
+		delete(s.Departments, event.Id)
+	case ChangeTypeUpdateTag:
+		return s.applyTag(event)
+	default:
+		return fmt.Errorf("dirsync: unknown change type %q", event.ChangeType)
+	}
+	return nil
+}
+
+// 将事件中的字段写入user，只替换整个字段，不修改user中已有的切片
+func (e ChangeContactEvent) applyUser(user *wecom.UserInfo) error {
+	if e.Department != nil {
+		department, err := splitInts(*e.Department)
+		if err != nil {
+			return err
+		}
+		// 事件中没有部门内的排序值，原有的排序值与新的部门列表不对应
+		user.Department, user.Order = department, nil
+	}
+	if e.IsLeaderInDept != nil {
+		isLeader, err := splitInts(*e.IsLeaderInDept)
+		if err != nil {
+			return err
+		}
+		user.IsLeaderInDept = isLeader
+	}
+
+	setString(&user.Name, e.Name)
+	setString(&user.Position, e.Position)
+	setString(&user.Mobile, e.Mobile)
+	setString(&user.Gender, e.Gender)
+	setString(&user.Email, e.Email)
+	setString(&user.Avatar, e.Avatar)
+	setString(&user.Alias, e.Alias)
+	setString(&user.Telephone, e.Telephone)
+	setString(&user.Address, e.Address)
+	setInt(&user.MainDepartMent, e.MainDepartment)
+	setInt(&user.Status, e.Status)
+	return nil
+}
+
+func (e ChangeContactEvent) applyDept(dept *wecom.DeptInfo) {
+	setString(&dept.Name, e.Name)
+	setInt(&dept.ParentId, e.ParentId)
+	setInt(&dept.Order, e.Order)
+}
+
+func (s *Snapshot) applyTag(event ChangeContactEvent) error {
+	addUsers, delUsers := splitStrings(event.AddUserItems), splitStrings(event.DelUserItems)
+	addParties, err := splitInts(event.AddPartyItems)
+	if err != nil {
+		return err
+	}
+	delParties, err := splitInts(event.DelPartyItems)
+	if err != nil {
+		return err
+	}
+
+	tag := s.Tags[event.TagId]
+	tag.Id = event.TagId
+	tag.Users = applyMembers(tag.Users, addUsers, delUsers, func(a, b string) bool { return a < b })
+	tag.Departments = applyMembers(tag.Departments, addParties, delParties, func(a, b int) bool { return a < b })
+	s.Tags[tag.Id] = tag
+	return nil
+}
+
+func (s *Snapshot) renameTagUser(old, new string) {
+	for id, tag := range s.Tags {
+		for _, userId := range tag.Users {
+			if userId == old {
+				tag.Users = applyMembers(tag.Users, []string{new}, []string{old}, func(a, b string) bool { return a < b })
+				s.Tags[id] = tag
+				break
+			}
+		}
+	}
+}
+
+// 返回新的成员列表，按less排序，为空时返回nil
+func applyMembers[T comparable](members, add, del []T, less func(a, b T) bool) []T {
+	set := map[T]bool{}
+	for _, v := range members {
+		set[v] = true
+	}
+	for _, v := range add {
+		set[v] = true
+	}
+	for _, v := range del {
+		delete(set, v)
+	}
+	return sortedKeys(set, less)
+}
+
+func sortedKeys[T comparable](set map[T]bool, less func(a, b T) bool) []T {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]T, 0, len(set))
+	for v := range set {
+		keys = append(keys, v)
+	}
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
+func splitStrings(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func splitInts(s string) ([]int, error) {
+	var items []int
+	for _, item := range splitStrings(s) {
+		v, err := strconv.Atoi(item)
+		if err != nil {
+			return nil, fmt.Errorf("dirsync: invalid id list %q", s)
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+func setString(dst *string, src *string) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+func setInt(dst *int, src *int) {
+	if src != nil {
+		*dst = *src
+	}
+}
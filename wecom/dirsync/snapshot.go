@@ -0,0 +1,171 @@
+// Package dirsync 通讯录同步，拉取完整的通讯录快照并与上次的快照比较差异，两次全量同步之间通过通讯录变更事件增量更新
+package dirsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+// ErrNoSnapshot 还没有保存过快照，需要先进行全量同步
+var ErrNoSnapshot = errors.New("dirsync: no snapshot, run a full sync first")
+
+// Tag 标签及其成员
+type Tag struct {
+	Id          int      `json:"id"`          // 标签id
+	Name        string   `json:"name"`        // 标签名
+	Users       []string `json:"users"`       // 标签中的成员userid，升序排列
+	Departments []int    `json:"departments"` // 标签中的部门id，升序排列
+}
+
+// Snapshot 通讯录快照
+//
+// 快照中的值在更新时整体替换，不会修改已有值中的切片，因此可以安全地浅拷贝
+type Snapshot struct {
+	Time        time.Time                 `json:"time"`        // 全量同步的时间
+	Departments map[int]wecom.DeptInfo    `json:"departments"` // 部门，key为部门id
+	Users       map[string]wecom.UserInfo `json:"users"`       // 成员，key为userid
+	Tags        map[int]Tag               `json:"tags"`        // 标签，key为标签id，未同步标签时为空
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Departments: map[int]wecom.DeptInfo{},
+		Users:       map[string]wecom.UserInfo{},
+		Tags:        map[int]Tag{},
+	}
+}
+
+// Clone 复制快照
+func (s *Snapshot) Clone() *Snapshot {
+	c := NewSnapshot()
+	c.Time = s.Time
+	for id, dept := range s.Departments {
+		c.Departments[id] = dept
+	}
+	for id, user := range s.Users {
+		c.Users[id] = user
+	}
+	for id, tag := range s.Tags {
+		c.Tags[id] = tag
+	}
+	return c
+}
+
+// DepartmentUsers 部门下直属的成员userid，升序排列
+func (s *Snapshot) DepartmentUsers(deptId int) []string {
+	var users []string
+	for id, user := range s.Users {
+		for _, d := range user.Department {
+			if d == deptId {
+				users = append(users, id)
+				break
+			}
+		}
+	}
+	sort.Strings(users)
+	return users
+}
+
+// Store 快照的持久化存储，实现需要保证并发安全
+type Store interface {
+	// Load 读取上次保存的快照，不存在时返回ErrNoSnapshot
+	Load(ctx context.Context) (*Snapshot, error)
+
+	// Save 保存快照，替换已有的快照
+	Save(ctx context.Context, snapshot *Snapshot) error
+}
+
+type memoryStore struct {
+	mutex    *sync.Mutex
+	snapshot *Snapshot
+}
+
+// NewMemoryStore 内存存储，进程退出后快照丢失
+func NewMemoryStore() Store {
+	return &memoryStore{mutex: &sync.Mutex{}}
+}
+
+func (s *memoryStore) Load(ctx context.Context) (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.snapshot == nil {
+		return nil, ErrNoSnapshot
+	}
+	return s.snapshot.Clone(), nil
+}
+
+func (s *memoryStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.snapshot = snapshot.Clone()
+	return nil
+}
+
+type fileStore struct {
+	mutex *sync.Mutex
+	path  string
+}
+
+// NewFileStore 文件存储，快照以JSON格式保存在path，写入临时文件后重命名，不会留下不完整的文件
+//
+// 仅适用于单个进程使用同一文件的场景
+func NewFileStore(path string) Store {
+	return &fileStore{mutex: &sync.Mutex{}, path: path}
+}
+
+func (s *fileStore) Load(ctx context.Context) (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	content, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoSnapshot
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := NewSnapshot()
+	if err = json.Unmarshal(content, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *fileStore) Save(ctx context.Context, snapshot *Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), s.path)
+}
@@ -0,0 +1,165 @@
+package dirsync
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo/wecom"
+	"github.com/huimingz/wechatgo/wecom/tag"
+)
+
+type SyncerOptionFn func(option *syncerOption)
+
+type syncerOption struct {
+	tags bool
+}
+
+// SyncerWithTags 是否同步标签及标签成员，默认同步。应用没有标签的权限时可以关闭
+func SyncerWithTags(enable bool) SyncerOptionFn {
+	return func(option *syncerOption) {
+		option.tags = enable
+	}
+}
+
+// Syncer 通讯录同步器
+//
+// Sync拉取完整的通讯录并与上次保存的快照比较，Apply在两次全量同步之间应用通讯录变更事件，
+// 两者都会将更新后的快照保存到Store并返回本次的变更。同一个Syncer的调用是串行的
+type Syncer struct {
+	dept  *wecom.WechatDept
+	tag   *tag.WechatTag
+	store Store
+	mutex *sync.Mutex
+
+	option syncerOption
+}
+
+func NewSyncer(client *wecom.Client, store Store, options ...SyncerOptionFn) *Syncer {
+	option := syncerOption{tags: true}
+	for _, opt := range options {
+		opt(&option)
+	}
+	return &Syncer{
+		dept:   wecom.NewWechatDept(client),
+		tag:    tag.NewWechatTag(client),
+		store:  store,
+		mutex:  &sync.Mutex{},
+		option: option,
+	}
+}
+
+// Snapshot 拉取完整的通讯录快照，不会保存快照
+//
+// 成员通过应用可见范围内的顶层部门递归拉取，只能拉取到应用可见范围内的部门及成员
+func (s *Syncer) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snapshot := NewSnapshot()
+	snapshot.Time = time.Now()
+
+	depts, err := s.dept.GetList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, dept := range depts {
+		snapshot.Departments[dept.Id] = dept
+	}
+
+	for _, dept := range depts {
+		if _, ok := snapshot.Departments[dept.ParentId]; ok {
+			continue
+		}
+		users, err := s.dept.GetUserDetailList(ctx, dept.Id, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			snapshot.Users[user.UserId] = user
+		}
+	}
+
+	if s.option.tags {
+		if err = s.fetchTags(ctx, snapshot); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+func (s *Syncer) fetchTags(ctx context.Context, snapshot *Snapshot) error {
+	tags, err := s.tag.GetTagList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range tags {
+		_, users, parties, err := s.tag.GetUserList(ctx, info.TagId)
+		if err != nil {
+			return err
+		}
+		userIds := make([]string, 0, len(users))
+		for _, user := range users {
+			userIds = append(userIds, user.UserId)
+		}
+		snapshot.Tags[info.TagId] = Tag{
+			Id:          info.TagId,
+			Name:        info.TagName,
+			Users:       applyMembers(nil, userIds, nil, func(a, b string) bool { return a < b }),
+			Departments: applyMembers(nil, parties, nil, func(a, b int) bool { return a < b }),
+		}
+	}
+	return nil
+}
+
+// Sync 全量同步，返回与上次保存的快照之间的差异，没有保存过快照时所有内容都作为新增
+func (s *Syncer) Sync(ctx context.Context) (*Diff, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	old, err := s.store.Load(ctx)
+	if err != nil && !errors.Is(err, ErrNoSnapshot) {
+		return nil, err
+	}
+
+	snapshot, err := s.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := Compare(old, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.store.Save(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// Apply 将通讯录变更事件应用到保存的快照，返回事件带来的变更
+//
+// 成员的userid变更时，差异中表现为删除旧的userid并新增新的userid。
+// 没有保存过快照时返回ErrNoSnapshot，快照过期时返回ErrSnapshotOutdated，出现这两种错误时需要调用Sync
+func (s *Syncer) Apply(ctx context.Context, events ...ChangeContactEvent) (*Diff, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	old, err := s.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := old.Clone()
+	for _, event := range events {
+		if err = snapshot.Apply(event); err != nil {
+			return nil, err
+		}
+	}
+	diff, err := Compare(old, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.store.Save(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
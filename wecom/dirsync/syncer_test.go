@@ -0,0 +1,200 @@
+package dirsync
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/testdata"
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+type syncerTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	client    *wecom.Client
+
+	// 模拟的通讯录，key为department_id或tagid
+	depts    string
+	users    map[string]string
+	tags     string
+	tagUsers map[string]string
+}
+
+func (s *syncerTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+"/cgi-bin/department/list", func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","department":`+s.depts+`}`), nil
+	})
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+"/cgi-bin/user/list", func(req *http.Request) (*http.Response, error) {
+		s.Equal("1", req.URL.Query().Get("fetch_child"))
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","userlist":`+s.users[req.URL.Query().Get("department_id")]+`}`), nil
+	})
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+"/cgi-bin/tag/list", func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","taglist":`+s.tags+`}`), nil
+	})
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+"/cgi-bin/tag/get", func(req *http.Request) (*http.Response, error) {
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok",`+s.tagUsers[req.URL.Query().Get("tagid")]+`}`), nil
+	})
+
+	s.depts = `[{"id":1,"name":"公司","parentid":0},{"id":2,"name":"研发部","parentid":1},{"id":3,"name":"销售部","parentid":1}]`
+	s.users = map[string]string{"1": `[
+		{"userid":"zhangsan","name":"张三","department":[2],"main_department":2,"mobile":"13800000000"},
+		{"userid":"lisi","name":"李四","department":[3],"main_department":3},
+		{"userid":"wangwu","name":"王五","department":[3],"main_department":3}
+	]`}
+	s.tags = `[{"tagid":1,"tagname":"管理层"}]`
+	s.tagUsers = map[string]string{"1": `"tagname":"管理层","userlist":[{"userid":"zhangsan"}],"partylist":[3]`}
+
+	conf := testdata.TestConf
+	s.client = wecom.NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+}
+
+func (s *syncerTestSuite) TestShouldDiffFullSyncs() {
+	store := NewFileStore(filepath.Join(s.T().TempDir(), "snapshot.json"))
+	syncer := NewSyncer(s.client, store)
+	ctx := context.Background()
+
+	diff, err := syncer.Sync(ctx)
+	s.Require().NoError(err)
+	s.Len(diff.Users, 3)
+	s.Len(diff.Departments, 3)
+	s.Require().Len(diff.Tags, 1)
+	s.Equal(ChangeAdded, diff.Tags[0].Kind)
+	s.Equal([]string{"zhangsan"}, diff.Tags[0].AddedUsers)
+
+	s.users["1"] = `[
+		{"userid":"zhangsan","name":"张三","department":[3],"main_department":3,"mobile":"13800000000"},
+		{"userid":"lisi","name":"李四","department":[3],"main_department":3,"mobile":"13900000000"},
+		{"userid":"zhaoliu","name":"赵六","department":[2],"main_department":2}
+	]`
+	s.depts = `[{"id":1,"name":"公司","parentid":0},{"id":2,"name":"研发中心","parentid":1},{"id":3,"name":"销售部","parentid":2}]`
+	s.tagUsers["1"] = `"tagname":"管理层","userlist":[{"userid":"lisi"}],"partylist":[3]`
+
+	diff, err = syncer.Sync(ctx)
+	s.Require().NoError(err)
+
+	kinds := map[string]ChangeKind{}
+	for _, change := range diff.Users {
+		kinds[change.UserId] = change.Kind
+	}
+	s.Equal(map[string]ChangeKind{"zhangsan": ChangeMoved, "lisi": ChangeUpdated, "wangwu": ChangeRemoved, "zhaoliu": ChangeAdded}, kinds)
+	s.Equal("lisi", diff.Users[0].UserId, "changes are sorted by id")
+	s.Equal([]string{"mobile"}, diff.Users[0].Fields)
+
+	s.Require().Len(diff.Departments, 2)
+	s.Equal(DeptChange{Kind: ChangeUpdated, Id: 2, Old: diff.Departments[0].Old, New: diff.Departments[0].New, Fields: []string{"name"}}, diff.Departments[0])
+	s.Equal(ChangeMoved, diff.Departments[1].Kind)
+
+	s.Require().Len(diff.Tags, 1)
+	s.Equal([]string{"lisi"}, diff.Tags[0].AddedUsers)
+	s.Equal([]string{"zhangsan"}, diff.Tags[0].RemovedUsers)
+	s.Equal([]string{"users"}, diff.Tags[0].Fields)
+
+	diff, err = syncer.Sync(ctx)
+	s.Require().NoError(err)
+	s.True(diff.Empty())
+}
+
+func (s *syncerTestSuite) TestShouldApplyChangeContactEvents() {
+	syncer := NewSyncer(s.client, NewMemoryStore(), SyncerWithTags(false))
+	ctx := context.Background()
+
+	_, err := syncer.Apply(ctx, ChangeContactEvent{ChangeType: ChangeTypeDeleteUser, UserId: "lisi"})
+	s.True(errors.Is(err, ErrNoSnapshot))
+
+	_, err = syncer.Sync(ctx)
+	s.Require().NoError(err)
+	s.Zero(s.transport.GetCallCountInfo()["GET "+mock.BaseURL+"/cgi-bin/tag/list"])
+
+	var update ChangeContactEvent
+	s.Require().NoError(xml.Unmarshal([]byte(`<xml>
+		<ToUserName><![CDATA[toUser]]></ToUserName>
+		<FromUserName><![CDATA[sys]]></FromUserName>
+		<CreateTime>1403610513</CreateTime>
+		<MsgType><![CDATA[event]]></MsgType>
+		<Event><![CDATA[change_contact]]></Event>
+		<ChangeType>update_user</ChangeType>
+		<UserID><![CDATA[zhangsan]]></UserID>
+		<NewUserID><![CDATA[zhangsan001]]></NewUserID>
+		<Department><![CDATA[2,3]]></Department>
+		<Mobile><![CDATA[]]></Mobile>
+	</xml>`), &update))
+
+	diff, err := syncer.Apply(ctx,
+		update,
+		ChangeContactEvent{ChangeType: ChangeTypeDeleteUser, UserId: "lisi"},
+		ChangeContactEvent{ChangeType: ChangeTypeCreateParty, Id: 4, Name: stringPtr("市场部"), ParentId: intPtr(1)},
+	)
+	s.Require().NoError(err)
+	s.Require().Len(diff.Users, 3)
+	s.Equal(UserChange{Kind: ChangeRemoved, UserId: "lisi", Old: diff.Users[0].Old}, diff.Users[0])
+	s.Equal(ChangeRemoved, diff.Users[1].Kind)
+	s.Equal("zhangsan", diff.Users[1].UserId)
+	s.Equal(ChangeAdded, diff.Users[2].Kind)
+	s.Equal(wecom.UserInfo{UserId: "zhangsan001", Name: "张三", Department: []int{2, 3}, MainDepartMent: 2}, *diff.Users[2].New)
+	s.Require().Len(diff.Departments, 1)
+	s.Equal(wecom.DeptInfo{Id: 4, Name: "市场部", ParentId: 1}, *diff.Departments[0].New)
+
+	_, err = syncer.Apply(ctx, ChangeContactEvent{ChangeType: ChangeTypeUpdateUser, UserId: "nobody", Name: stringPtr("x")})
+	s.True(errors.Is(err, ErrSnapshotOutdated))
+
+	snapshot, err := NewMemoryStore().Load(ctx)
+	s.Nil(snapshot)
+	s.True(errors.Is(err, ErrNoSnapshot))
+}
+
+func (s *syncerTestSuite) TestShouldApplyTagEvent() {
+	snapshot := NewSnapshot()
+	snapshot.Tags[1] = Tag{Id: 1, Name: "管理层", Users: []string{"zhangsan"}}
+	old := snapshot.Clone()
+
+	s.Require().NoError(snapshot.Apply(ChangeContactEvent{ChangeType: ChangeTypeUpdateTag, TagId: 1, AddUserItems: "lisi,wangwu", DelUserItems: "zhangsan", AddPartyItems: "3"}))
+	s.Equal(Tag{Id: 1, Name: "管理层", Users: []string{"lisi", "wangwu"}, Departments: []int{3}}, snapshot.Tags[1])
+	s.Equal([]string{"zhangsan"}, old.Tags[1].Users, "clone is not modified")
+
+	s.Error(snapshot.Apply(ChangeContactEvent{ChangeType: "unknown"}))
+	s.Error(snapshot.Apply(ChangeContactEvent{ChangeType: ChangeTypeUpdateTag, TagId: 1, AddPartyItems: "a"}))
+}
+
+func (s *syncerTestSuite) TestShouldClearOrderWhenDepartmentChanged() {
+	snapshot := NewSnapshot()
+	snapshot.Users["zhangsan"] = wecom.UserInfo{UserId: "zhangsan", Name: "张三", Department: []int{1}, Order: []int{10}}
+
+	s.Require().NoError(snapshot.Apply(ChangeContactEvent{ChangeType: ChangeTypeUpdateUser, UserId: "zhangsan", Name: stringPtr("张三丰")}))
+	s.Equal([]int{10}, snapshot.Users["zhangsan"].Order)
+
+	s.Require().NoError(snapshot.Apply(ChangeContactEvent{ChangeType: ChangeTypeUpdateUser, UserId: "zhangsan", Department: stringPtr("2,3")}))
+	s.Equal([]int{2, 3}, snapshot.Users["zhangsan"].Department)
+	s.Nil(snapshot.Users["zhangsan"].Order)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func (s *syncerTestSuite) TestShouldReturnErrorWhenFieldsCanNotBeCompared() {
+	_, err := changedFields(1, 2)
+	s.Error(err)
+	_, err = changedFields(Tag{}, func() {})
+	s.Error(err)
+
+	fields, err := changedFields(Tag{Id: 1, Name: "a"}, Tag{Id: 1, Name: "b"})
+	s.NoError(err)
+	s.Equal([]string{"name"}, fields)
+}
+
+func TestSyncer(t *testing.T) {
+	suite.Run(t, new(syncerTestSuite))
+}
@@ -0,0 +1,73 @@
+package extcontact
+
+import (
+	"fmt"
+
+	"github.com/huimingz/wechatgo/wecom/media"
+)
+
+// 附件的消息类型
+const (
+	AttachmentImage       = "image"
+	AttachmentLink        = "link"
+	AttachmentMiniProgram = "miniprogram"
+	AttachmentVideo       = "video"
+	AttachmentFile        = "file"
+)
+
+// MaxWelcomeAttachments 欢迎语最多包含的附件数量
+const MaxWelcomeAttachments = 9
+
+type VideoMsg struct {
+	MediaId string `json:"media_id"` // 视频的media_id
+}
+
+type FileMsg struct {
+	MediaId string `json:"media_id"` // 文件的media_id
+}
+
+// Attachment 欢迎语的附件，MsgType决定使用哪个字段，建议通过NewXxxAttachment构造
+type Attachment struct {
+	MsgType     string          `json:"msgtype"`               // 附件类型，取值见Attachment常量
+	Image       *ImageMsg       `json:"image,omitempty"`       // 图片
+	Link        *LinkMsg        `json:"link,omitempty"`        // 图文消息
+	MiniProgram *MiniProgramMsg `json:"miniprogram,omitempty"` // 小程序
+	Video       *VideoMsg       `json:"video,omitempty"`       // 视频
+	File        *FileMsg        `json:"file,omitempty"`        // 文件
+}
+
+func NewImageAttachment(mediaId string) Attachment {
+	return Attachment{MsgType: AttachmentImage, Image: &ImageMsg{MediaId: mediaId}}
+}
+
+func NewLinkAttachment(link LinkMsg) Attachment {
+	return Attachment{MsgType: AttachmentLink, Link: &link}
+}
+
+func NewMiniProgramAttachment(miniProgram MiniProgramMsg) Attachment {
+	return Attachment{MsgType: AttachmentMiniProgram, MiniProgram: &miniProgram}
+}
+
+func NewVideoAttachment(mediaId string) Attachment {
+	return Attachment{MsgType: AttachmentVideo, Video: &VideoMsg{MediaId: mediaId}}
+}
+
+func NewFileAttachment(mediaId string) Attachment {
+	return Attachment{MsgType: AttachmentFile, File: &FileMsg{MediaId: mediaId}}
+}
+
+// NewMediaAttachment 根据上传素材返回的信息构造附件，附件类型与素材类型一致
+//
+// 欢迎语使用media.WechatMedia.UploadMedia上传的临时素材；
+// media.WechatMedia.UploadAttachment上传的附件资源只能用于对应的朋友圈、商品图册场景
+func NewMediaAttachment(info *media.MediaInfo) (Attachment, error) {
+	switch media.MediaType(info.Type) {
+	case media.MediaTypeImage:
+		return NewImageAttachment(info.MediaId), nil
+	case media.MediaTypeVideo:
+		return NewVideoAttachment(info.MediaId), nil
+	case media.MediaTypeFile:
+		return NewFileAttachment(info.MediaId), nil
+	}
+	return Attachment{}, fmt.Errorf("extcontact: media type %q can't be used as attachment", info.Type)
+}
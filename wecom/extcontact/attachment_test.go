@@ -0,0 +1,47 @@
+package extcontact
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/wecom/media"
+)
+
+type attachmentTestSuite struct {
+	suite.Suite
+}
+
+func (s *attachmentTestSuite) TestShouldEncodeAttachments() {
+	video, err := NewMediaAttachment(&media.MediaInfo{Type: "video", MediaId: "MEDIA1"})
+	s.Require().NoError(err)
+
+	content, err := json.Marshal([]Attachment{
+		NewImageAttachment("MEDIA0"),
+		video,
+		NewLinkAttachment(LinkMsg{Title: "title", Url: "https://example.com"}),
+	})
+	s.Require().NoError(err)
+	s.JSONEq(`[
+		{"msgtype":"image","image":{"media_id":"MEDIA0"}},
+		{"msgtype":"video","video":{"media_id":"MEDIA1"}},
+		{"msgtype":"link","link":{"title":"title","url":"https://example.com"}}
+	]`, string(content))
+}
+
+func (s *attachmentTestSuite) TestShouldRejectVoiceAttachment() {
+	_, err := NewMediaAttachment(&media.MediaInfo{Type: "voice", MediaId: "MEDIA1"})
+	s.Error(err)
+}
+
+func (s *attachmentTestSuite) TestShouldLimitWelcomeAttachments() {
+	msg := WelcomeMsg{WelcomeCode: "code", Attachments: make([]Attachment, MaxWelcomeAttachments+1)}
+	err := WechatContact{}.SendWelcomeMsg(context.Background(), msg)
+	s.Error(err)
+}
+
+func TestAttachment(t *testing.T) {
+	suite.Run(t, new(attachmentTestSuite))
+}
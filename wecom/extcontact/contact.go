@@ -3,7 +3,9 @@ package extcontact
 
 import (
 	"context"
+	"fmt"
 	"net/url"
+	"strconv"
 
 	"github.com/huimingz/wechatgo/wecom"
 )
@@ -113,6 +115,8 @@ type WelcomeMsg struct {
 	Image          ImageMsg       `json:"image"`        // 图片
 	Link           LinkMsg        `json:"link"`         // 图文消息
 	MiniProgramMsg MiniProgramMsg `json:"miniprogram"`  // 小程序
+
+	Attachments []Attachment `json:"attachments,omitempty"` // 附件，最多MaxWelcomeAttachments个，可以是图片、图文、小程序、视频或文件
 }
 
 type UnassignedUser struct {
@@ -255,6 +259,9 @@ func (w WechatContact) GetUserBehaviorData(ctx context.Context, userIds []string
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/91688
 func (w WechatContact) SendWelcomeMsg(ctx context.Context, welcomeMsg WelcomeMsg) error {
+	if len(welcomeMsg.Attachments) > MaxWelcomeAttachments {
+		return fmt.Errorf("extcontact: welcome message has %d attachments, at most %d", len(welcomeMsg.Attachments), MaxWelcomeAttachments)
+	}
 	return w.Client.Post(ctx, urlSendWelcomeMsg, nil, welcomeMsg, nil, nil)
 }
 
@@ -268,7 +275,10 @@ func (w WechatContact) GetUnassignedList(ctx context.Context, pageId, pageSize i
 	data := struct {
 		PageId   int `json:"page_id,omitempty"`   // 分页查询，要查询页号，从0开始
 		PageSize int `json:"page_size,omitempty"` // 每次返回的最大记录数，默认为1000，最大值为1000
-	}{}
+	}{
+		PageId:   pageId,
+		PageSize: pageSize,
+	}
 	out := struct {
 		Info   []UnassignedUser `json:"info"`
 		IsLast bool             `json:"is_last"`
@@ -280,6 +290,28 @@ func (w WechatContact) GetUnassignedList(ctx context.Context, pageId, pageSize i
 	return
 }
 
+// UnassignedListPager 离职成员客户列表的分页迭代器
+//
+// 分页大小默认为1000
+func (w WechatContact) UnassignedListPager(options ...wecom.PagerOptionFn) *wecom.Pager[UnassignedUser] {
+	options = append([]wecom.PagerOptionFn{wecom.PagerWithLimit(1000)}, options...)
+	return wecom.NewPager(func(ctx context.Context, cursor string, limit int) ([]UnassignedUser, string, error) {
+		pageId := 0
+		if cursor != "" {
+			var err error
+			if pageId, err = strconv.Atoi(cursor); err != nil {
+				return nil, "", err
+			}
+		}
+
+		userlist, isLast, err := w.GetUnassignedList(ctx, pageId, limit)
+		if err != nil || isLast {
+			return userlist, "", err
+		}
+		return userlist, strconv.Itoa(pageId + 1), nil
+	}, options...)
+}
+
 // 离职成员的外部联系人再分配
 //
 // 企业可通过此接口，将已离职成员的外部联系人分配给另一个成员接替联系。
@@ -0,0 +1,353 @@
+// Package logging 将达到指定级别的日志以markdown消息的形式发送到企业微信
+//
+// Forwarder在后台批量发送日志，合并重复的日志并限制发送频率；缓冲区满时丢弃日志，
+// 记录日志的调用方永远不会被阻塞。Logger实现了wechatgo.Logger，Go 1.21及以上版本
+// 可以通过NewSlogHandler接入log/slog。
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/wecom/msg"
+	"github.com/huimingz/wechatgo/wecom/notify"
+)
+
+// 默认配置
+const (
+	DefaultBufferSize      = 1000
+	DefaultBatchSize       = 20
+	DefaultFlushInterval   = time.Second * 5
+	DefaultMaxMessageBytes = 512
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch {
+	case l >= LevelError:
+		return "ERROR"
+	case l >= LevelWarn:
+		return "WARN"
+	case l >= LevelInfo:
+		return "INFO"
+	}
+	return "DEBUG"
+}
+
+func (l Level) color() msg.FontColor {
+	switch {
+	case l >= LevelError:
+		return msg.FontColorWarning
+	case l >= LevelWarn:
+		return msg.FontColorComment
+	}
+	return msg.FontColorInfo
+}
+
+// Attr 日志的附加字段
+type Attr struct {
+	Key   string
+	Value string
+}
+
+// Record 一条日志
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Attrs   []Attr
+}
+
+// 相同级别、内容及字段的日志在同一批次中合并
+func (r Record) key() string {
+	b := strings.Builder{}
+	b.WriteString(r.Level.String())
+	b.WriteString("\x00")
+	b.WriteString(r.Message)
+	for _, attr := range r.Attrs {
+		b.WriteString("\x00" + attr.Key + "=" + attr.Value)
+	}
+	return b.String()
+}
+
+type ForwarderOptionFn func(forwarder *Forwarder)
+
+// ForwarderWithLevel 发送的最低日志级别，默认为LevelError
+func ForwarderWithLevel(level Level) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.level = level
+	}
+}
+
+// ForwarderWithBufferSize 缓冲的日志条数，缓冲区满时丢弃新的日志，默认为1000
+func ForwarderWithBufferSize(size int) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.bufferSize = size
+	}
+}
+
+// ForwarderWithBatchSize 每条消息最多包含的日志条数（合并后），默认为20
+func ForwarderWithBatchSize(size int) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.batchSize = size
+	}
+}
+
+// ForwarderWithFlushInterval 批量发送的时间间隔，默认为5秒
+func ForwarderWithFlushInterval(interval time.Duration) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.flushInterval = interval
+	}
+}
+
+// ForwarderWithRateLimiter 发送消息的限流器，默认每分钟最多发送10条消息
+func ForwarderWithRateLimiter(limiter wechatgo.RateLimiter) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.limiter = limiter
+	}
+}
+
+// ForwarderWithTitle 消息的标题，默认为“日志告警”
+func ForwarderWithTitle(title string) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.title = title
+	}
+}
+
+// ForwarderWithMaxMessageBytes 单条日志内容的最大字节数，超过时截断，默认为512
+func ForwarderWithMaxMessageBytes(n int) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.maxMessageBytes = n
+	}
+}
+
+// ForwarderWithLogger 记录发送失败的日志，不能使用转发到当前Forwarder的Logger
+func ForwarderWithLogger(logger wechatgo.Logger) ForwarderOptionFn {
+	return func(forwarder *Forwarder) {
+		forwarder.log = logger
+	}
+}
+
+// Forwarder 批量发送日志到企业微信
+//
+// target可以使用notify.AppTarget发送给成员、部门或标签，或notify.RobotTarget发送到群机器人。
+// 创建后在后台运行，使用完毕后需调用Close发送剩余的日志。
+type Forwarder struct {
+	target          notify.Target
+	level           Level
+	bufferSize      int
+	batchSize       int
+	flushInterval   time.Duration
+	limiter         wechatgo.RateLimiter
+	title           string
+	maxMessageBytes int
+	log             wechatgo.Logger
+
+	records chan Record
+	mutex   *sync.Mutex
+	dropped int // 缓冲区满时丢弃的日志条数
+	closed  bool
+	done    chan struct{}
+	ctx     context.Context // 发送日志使用的ctx，Close超时时取消
+	cancel  context.CancelFunc
+}
+
+func NewForwarder(target notify.Target, options ...ForwarderOptionFn) *Forwarder {
+	forwarder := &Forwarder{
+		target: target,
+		level:  LevelError,
+		mutex:  &sync.Mutex{},
+		done:   make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(forwarder)
+	}
+
+	if forwarder.bufferSize <= 0 {
+		forwarder.bufferSize = DefaultBufferSize
+	}
+	if forwarder.batchSize <= 0 {
+		forwarder.batchSize = DefaultBatchSize
+	}
+	if forwarder.flushInterval <= 0 {
+		forwarder.flushInterval = DefaultFlushInterval
+	}
+	if forwarder.limiter == nil {
+		forwarder.limiter = wechatgo.NewRateLimiter(10, time.Minute)
+	}
+	if forwarder.title == "" {
+		forwarder.title = "日志告警"
+	}
+	if forwarder.maxMessageBytes <= 0 {
+		forwarder.maxMessageBytes = DefaultMaxMessageBytes
+	}
+	if forwarder.log == nil {
+		forwarder.log = wechatgo.DefaultLogger()
+	}
+
+	forwarder.records = make(chan Record, forwarder.bufferSize)
+	forwarder.ctx, forwarder.cancel = context.WithCancel(context.Background())
+	go forwarder.run()
+	return forwarder
+}
+
+// Enabled 指定级别的日志是否会被发送
+func (f *Forwarder) Enabled(level Level) bool {
+	return level >= f.level
+}
+
+// Forward 将日志放入缓冲区，不阻塞；级别低于最低级别、缓冲区已满或已关闭时丢弃并返回false
+func (f *Forwarder) Forward(record Record) bool {
+	if !f.Enabled(record.Level) {
+		return false
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		return false
+	}
+	select {
+	case f.records <- record:
+		return true
+	default:
+		f.dropped++
+		return false
+	}
+}
+
+// Close 停止接收日志，发送缓冲区中剩余的日志，ctx被取消时取消正在进行的发送并不再等待
+func (f *Forwarder) Close(ctx context.Context) error {
+	f.mutex.Lock()
+	if !f.closed {
+		f.closed = true
+		close(f.records)
+	}
+	f.mutex.Unlock()
+
+	select {
+	case <-f.done:
+		f.cancel()
+		return nil
+	case <-ctx.Done():
+		f.cancel()
+		return ctx.Err()
+	}
+}
+
+type batchEntry struct {
+	record Record
+	count  int
+}
+
+type batch struct {
+	entries []*batchEntry
+	index   map[string]*batchEntry
+}
+
+func newBatch() *batch {
+	return &batch{index: map[string]*batchEntry{}}
+}
+
+func (b *batch) add(record Record) {
+	key := record.key()
+	if entry, ok := b.index[key]; ok {
+		entry.count++
+		return
+	}
+	entry := &batchEntry{record: record, count: 1}
+	b.index[key] = entry
+	b.entries = append(b.entries, entry)
+}
+
+func (f *Forwarder) run() {
+	defer close(f.done)
+	ticker := time.NewTicker(f.flushInterval)
+	defer ticker.Stop()
+
+	current := newBatch()
+	for {
+		select {
+		case record, ok := <-f.records:
+			if !ok {
+				f.flush(current)
+				return
+			}
+			current.add(record)
+			if len(current.entries) >= f.batchSize {
+				f.flush(current)
+				current = newBatch()
+			}
+		case <-ticker.C:
+			f.flush(current)
+			current = newBatch()
+		}
+	}
+}
+
+func (f *Forwarder) flush(b *batch) {
+	f.mutex.Lock()
+	dropped := f.dropped
+	f.dropped = 0
+	f.mutex.Unlock()
+
+	if len(b.entries) == 0 && dropped == 0 {
+		return
+	}
+
+	ctx := f.ctx
+	if err := f.limiter.Wait(ctx); err != nil {
+		return
+	}
+	if err := f.target.Send(ctx, f.format(b, dropped)); err != nil {
+		f.log.Error(ctx, fmt.Sprintf("Forwarder: failed to send %d log records, Error: %s", len(b.entries), err))
+	}
+}
+
+func (f *Forwarder) format(b *batch, dropped int) msg.Message {
+	builder := msg.NewMarkdownBuilder()
+	builder.Heading(3, f.title)
+
+	for _, entry := range b.entries {
+		record := entry.record
+		builder.Newline().
+			Color(record.Level.color(), record.Level.String()).
+			Text(" ").
+			Color(msg.FontColorComment, record.Time.Format("2006-01-02 15:04:05")).
+			Newline().
+			Bold(msg.TruncateBytes(record.Message, f.maxMessageBytes))
+		if entry.count > 1 {
+			builder.Text(fmt.Sprintf(" (x%d)", entry.count))
+		}
+		builder.Newline()
+
+		if len(record.Attrs) > 0 {
+			attrs := make([]string, len(record.Attrs))
+			for i, attr := range record.Attrs {
+				attrs[i] = attr.Key + "=" + attr.Value
+			}
+			builder.Color(msg.FontColorComment, msg.TruncateBytes(strings.Join(attrs, " "), f.maxMessageBytes)).Newline()
+		}
+	}
+
+	if dropped > 0 {
+		builder.Newline().Color(msg.FontColorWarning, fmt.Sprintf("缓冲区已满，丢弃了%d条日志", dropped))
+	}
+	return builder.Message()
+}
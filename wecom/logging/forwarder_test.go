@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+type recordingTarget struct {
+	mutex    sync.Mutex
+	messages []string
+	block    chan struct{}
+}
+
+func (t *recordingTarget) Send(ctx context.Context, message msg.Message) error {
+	if t.block != nil {
+		select {
+		case <-t.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.messages = append(t.messages, message.(msg.MarkdownMsg).Content)
+	return nil
+}
+
+func (t *recordingTarget) sent() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]string{}, t.messages...)
+}
+
+type forwarderTestSuite struct {
+	suite.Suite
+	target *recordingTarget
+}
+
+func (s *forwarderTestSuite) SetupTest() {
+	s.target = &recordingTarget{}
+}
+
+func (s *forwarderTestSuite) newForwarder(options ...ForwarderOptionFn) *Forwarder {
+	options = append([]ForwarderOptionFn{
+		ForwarderWithFlushInterval(time.Hour),
+		ForwarderWithRateLimiter(wechatgo.NewRateLimiter(100, time.Second)),
+	}, options...)
+	return NewForwarder(s.target, options...)
+}
+
+func (s *forwarderTestSuite) TestShouldBatchAndCoalesceRecords() {
+	forwarder := s.newForwarder()
+	s.False(forwarder.Forward(Record{Level: LevelWarn, Message: "ignored"}))
+	for i := 0; i < 3; i++ {
+		s.True(forwarder.Forward(Record{Level: LevelError, Message: "db timeout", Attrs: []Attr{{Key: "db", Value: "main"}}}))
+	}
+	s.True(forwarder.Forward(Record{Level: LevelError, Message: "cache_miss"}))
+	s.NoError(forwarder.Close(context.Background()))
+
+	sent := s.target.sent()
+	s.Require().Len(sent, 1)
+	s.Contains(sent[0], "### 日志告警")
+	s.Contains(sent[0], "**db timeout** (x3)")
+	s.Contains(sent[0], `<font color="comment">db=main</font>`)
+	s.Contains(sent[0], `**cache\_miss**`)
+	s.NotContains(sent[0], "ignored")
+
+	s.False(forwarder.Forward(Record{Level: LevelError, Message: "after close"}))
+}
+
+func (s *forwarderTestSuite) TestShouldFlushWhenBatchIsFull() {
+	forwarder := s.newForwarder(ForwarderWithBatchSize(2), ForwarderWithLevel(LevelWarn))
+	forwarder.Forward(Record{Level: LevelWarn, Message: "a"})
+	forwarder.Forward(Record{Level: LevelError, Message: "b"})
+
+	s.Eventually(func() bool { return len(s.target.sent()) == 1 }, time.Second, time.Millisecond*10)
+	forwarder.Forward(Record{Level: LevelError, Message: "c"})
+	s.NoError(forwarder.Close(context.Background()))
+	s.Len(s.target.sent(), 2)
+}
+
+func (s *forwarderTestSuite) TestShouldFlushPeriodically() {
+	forwarder := s.newForwarder(ForwarderWithFlushInterval(time.Millisecond * 20))
+	defer forwarder.Close(context.Background())
+
+	forwarder.Forward(Record{Level: LevelError, Message: "periodic"})
+	s.Eventually(func() bool { return len(s.target.sent()) == 1 }, time.Second, time.Millisecond*10)
+}
+
+func (s *forwarderTestSuite) TestShouldNotBlockWhenBufferIsFull() {
+	s.target.block = make(chan struct{})
+	forwarder := s.newForwarder(ForwarderWithBufferSize(2), ForwarderWithBatchSize(1))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			forwarder.Forward(Record{Level: LevelError, Message: "flood"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		s.Fail("Forward blocked")
+	}
+
+	close(s.target.block)
+	s.NoError(forwarder.Close(context.Background()))
+	s.Contains(strings.Join(s.target.sent(), "\n"), "丢弃了")
+}
+
+func (s *forwarderTestSuite) TestCloseShouldRespectContext() {
+	s.target.block = make(chan struct{})
+	defer close(s.target.block)
+	forwarder := s.newForwarder()
+	forwarder.Forward(Record{Level: LevelError, Message: "stuck"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	s.True(errors.Is(forwarder.Close(ctx), context.DeadlineExceeded))
+
+	select {
+	case <-forwarder.done:
+	case <-time.After(time.Second):
+		s.Fail("pending send is not canceled after Close")
+	}
+	s.Empty(s.target.sent())
+}
+
+func (s *forwarderTestSuite) TestLoggerShouldForwardErrors() {
+	forwarder := s.newForwarder()
+	logger := NewLogger(forwarder, nil)
+	ctx := context.Background()
+	logger.Info(ctx, "started")
+	logger.Error(ctx, "failed: ", 42)
+	s.NoError(forwarder.Close(ctx))
+
+	sent := s.target.sent()
+	s.Require().Len(sent, 1)
+	s.Contains(sent[0], "**failed: 42**")
+	s.NotContains(sent[0], "started")
+}
+
+func TestForwarder(t *testing.T) {
+	suite.Run(t, new(forwarderTestSuite))
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/huimingz/wechatgo"
+)
+
+// Logger 实现wechatgo.Logger，日志输出到next，同时将达到级别的日志转发到Forwarder
+type Logger struct {
+	forwarder *Forwarder
+	next      wechatgo.Logger
+}
+
+// NewLogger next为nil时仅转发日志
+func NewLogger(forwarder *Forwarder, next wechatgo.Logger) *Logger {
+	return &Logger{forwarder: forwarder, next: next}
+}
+
+func (l *Logger) forward(level Level, args []interface{}) {
+	if l.forwarder.Enabled(level) {
+		l.forwarder.Forward(Record{Level: level, Message: fmt.Sprint(args...)})
+	}
+}
+
+func (l *Logger) Debug(ctx context.Context, args ...interface{}) {
+	if l.next != nil {
+		l.next.Debug(ctx, args...)
+	}
+	l.forward(LevelDebug, args)
+}
+
+func (l *Logger) Info(ctx context.Context, args ...interface{}) {
+	if l.next != nil {
+		l.next.Info(ctx, args...)
+	}
+	l.forward(LevelInfo, args)
+}
+
+func (l *Logger) Warn(ctx context.Context, args ...interface{}) {
+	if l.next != nil {
+		l.next.Warn(ctx, args...)
+	}
+	l.forward(LevelWarn, args)
+}
+
+func (l *Logger) Error(ctx context.Context, args ...interface{}) {
+	if l.next != nil {
+		l.next.Error(ctx, args...)
+	}
+	l.forward(LevelError, args)
+}
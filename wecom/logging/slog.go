@@ -0,0 +1,87 @@
+//go:build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// SlogHandler 实现slog.Handler，日志交给next处理，同时将达到级别的日志转发到Forwarder
+type SlogHandler struct {
+	forwarder *Forwarder
+	next      slog.Handler
+	attrs     []Attr
+	group     string // 当前分组的前缀，如"request."
+}
+
+// NewSlogHandler next为nil时仅转发日志
+func NewSlogHandler(forwarder *Forwarder, next slog.Handler) *SlogHandler {
+	return &SlogHandler{forwarder: forwarder, next: next}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.forwarder.Enabled(Level(level)) {
+		return true
+	}
+	return h.next != nil && h.next.Enabled(ctx, level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.forwarder.Enabled(Level(record.Level)) {
+		attrs := append([]Attr{}, h.attrs...)
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs = appendSlogAttr(attrs, h.group, attr)
+			return true
+		})
+		h.forwarder.Forward(Record{Time: record.Time, Level: Level(record.Level), Message: record.Message, Attrs: attrs})
+	}
+
+	if h.next != nil && h.next.Enabled(ctx, record.Level) {
+		return h.next.Handle(ctx, record)
+	}
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.attrs = append([]Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		handler.attrs = appendSlogAttr(handler.attrs, h.group, attr)
+	}
+	if h.next != nil {
+		handler.next = h.next.WithAttrs(attrs)
+	}
+	return &handler
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	handler := *h
+	handler.group = h.group + name + "."
+	if h.next != nil {
+		handler.next = h.next.WithGroup(name)
+	}
+	return &handler
+}
+
+// 展开分组，分组内的字段以“分组.字段”命名
+func appendSlogAttr(attrs []Attr, prefix string, attr slog.Attr) []Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, child := range attr.Value.Group() {
+			attrs = appendSlogAttr(attrs, prefix, child)
+		}
+		return attrs
+	}
+	return append(attrs, Attr{Key: strings.TrimSuffix(prefix+attr.Key, "."), Value: attr.Value.String()})
+}
@@ -0,0 +1,40 @@
+//go:build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/huimingz/wechatgo"
+)
+
+func TestSlogHandler(t *testing.T) {
+	target := &recordingTarget{}
+	forwarder := NewForwarder(target,
+		ForwarderWithFlushInterval(time.Hour),
+		ForwarderWithRateLimiter(wechatgo.NewRateLimiter(100, time.Second)))
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(NewSlogHandler(forwarder, slog.NewTextHandler(buf, nil)))
+	logger = logger.With("service", "api").WithGroup("req")
+
+	logger.Info("request handled", "path", "/users")
+	logger.Error("request failed", "path", "/orders", slog.Group("user", "id", 7))
+	assert.NoError(t, forwarder.Close(context.Background()))
+
+	assert.Contains(t, buf.String(), "request handled")
+	assert.Contains(t, buf.String(), "request failed")
+
+	sent := target.sent()
+	if assert.Len(t, sent, 1) {
+		assert.Contains(t, sent[0], "**request failed**")
+		assert.Contains(t, sent[0], "service=api req.path=/orders req.user.id=7")
+		assert.NotContains(t, sent[0], "request handled")
+	}
+}
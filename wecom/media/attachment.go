@@ -0,0 +1,71 @@
+package media
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strconv"
+)
+
+const urlUploadAttachment string = "/cgi-bin/media/upload_attachment"
+
+// AttachmentType 附件的使用场景
+type AttachmentType int
+
+const (
+	AttachmentTypeMoment  AttachmentType = 1 // 朋友圈
+	AttachmentTypeProduct AttachmentType = 2 // 商品图册
+)
+
+// Supports 附件场景是否支持该素材类型，朋友圈支持图片、视频及普通文件，商品图册仅支持图片
+func (t AttachmentType) Supports(mediaType MediaType) bool {
+	switch t {
+	case AttachmentTypeMoment:
+		return mediaType == MediaTypeImage || mediaType == MediaTypeVideo || mediaType == MediaTypeFile
+	case AttachmentTypeProduct:
+		return mediaType == MediaTypeImage
+	}
+	return false
+}
+
+// UploadAttachment 上传附件资源
+//
+// 附件资源只能在attachmentType对应的场景中使用，不能用于发送消息，media_id三天内有效。
+// r的处理方式及上传前的校验与UploadMedia相同，attachmentType不支持mediaType时返回*MediaValidationError。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/95098
+func (w WechatMedia) UploadAttachment(ctx context.Context, filename string, mediaType MediaType, attachmentType AttachmentType, r io.Reader) (*MediaInfo, error) {
+	source, err := readerSource(filename, r)
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+	return w.uploadAttachment(ctx, mediaType, attachmentType, source)
+}
+
+// UploadAttachmentFromOpener 上传附件资源，内容通过open打开，重试时会重新打开
+//
+// size为内容的字节数，未知时传入-1，此时使用分块传输
+func (w WechatMedia) UploadAttachmentFromOpener(ctx context.Context, filename string, mediaType MediaType, attachmentType AttachmentType, size int64, open Opener) (*MediaInfo, error) {
+	return w.uploadAttachment(ctx, mediaType, attachmentType, uploadSource{filename: filename, size: size, open: open, reopenable: true})
+}
+
+func (w WechatMedia) uploadAttachment(ctx context.Context, mediaType MediaType, attachmentType AttachmentType, source uploadSource) (*MediaInfo, error) {
+	mediaInfo := MediaInfo{}
+	limit, ok := mediaType.Limit()
+	if !ok || !attachmentType.Supports(mediaType) {
+		return &mediaInfo, &MediaValidationError{Type: mediaType, Filename: source.filename, Reason: "unsupported attachment type " + strconv.Itoa(int(attachmentType))}
+	}
+	source, err := source.validate(mediaType, limit)
+	if err != nil {
+		return &mediaInfo, err
+	}
+
+	body := newMultipartBody("media", source)
+
+	values := url.Values{}
+	values.Add("media_type", string(mediaType))
+	values.Add("attachment_type", strconv.Itoa(int(attachmentType)))
+
+	err = w.Client.PostStream(ctx, urlUploadAttachment, body.contentType, values, body.ContentLength(), body.Open, nil, &mediaInfo)
+	return &mediaInfo, err
+}
@@ -0,0 +1,43 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type attachmentTestSuite struct {
+	mockTestSuite
+}
+
+func (s *attachmentTestSuite) TestShouldUploadAttachment() {
+	s.registerUploadResponder(urlUploadAttachment, `{"errcode":0,"errmsg":"ok","type":"image","media_id":"MEDIA1","created_at":"1380000000"}`)
+
+	info, err := s.media.UploadAttachment(context.Background(), "cover.png", MediaTypeImage, AttachmentTypeProduct, strings.NewReader("\x89PNG\r\n\x1a\ncover"))
+	s.Require().NoError(err)
+	s.Equal(MediaInfo{Type: "image", MediaId: "MEDIA1", CreatedAt: "1380000000"}, *info)
+
+	s.Require().Len(s.uploads, 1)
+	s.Equal("image", s.uploads[0].query.Get("media_type"))
+	s.Equal("2", s.uploads[0].query.Get("attachment_type"))
+	s.Equal("cover.png", s.uploads[0].filename)
+}
+
+func (s *attachmentTestSuite) TestShouldRejectUnsupportedAttachment() {
+	var validationErr *MediaValidationError
+
+	_, err := s.media.UploadAttachment(context.Background(), "a.txt", MediaTypeFile, AttachmentTypeProduct, strings.NewReader("content"))
+	s.True(errors.As(err, &validationErr))
+	_, err = s.media.UploadAttachment(context.Background(), "a.amr", MediaTypeVoice, AttachmentTypeMoment, strings.NewReader("#!AMR\ncontent"))
+	s.True(errors.As(err, &validationErr))
+	_, err = s.media.UploadAttachment(context.Background(), "a.png", MediaTypeImage, AttachmentType(9), strings.NewReader("\x89PNG\r\n\x1a\ncover"))
+	s.True(errors.As(err, &validationErr))
+	s.Empty(s.uploads)
+}
+
+func TestAttachment(t *testing.T) {
+	suite.Run(t, new(attachmentTestSuite))
+}
@@ -0,0 +1,228 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/storage"
+)
+
+// DefaultMediaCacheTTL 缓存media_id的默认有效期，略短于media_id的三天有效期，避免使用即将过期的media_id
+const DefaultMediaCacheTTL = time.Hour*72 - time.Hour
+
+type CachedUploaderOptionFn func(uploader *CachedUploader)
+
+// CachedUploaderWithTTL 缓存的有效期，默认为DefaultMediaCacheTTL，不应超过三天
+func CachedUploaderWithTTL(ttl time.Duration) CachedUploaderOptionFn {
+	return func(uploader *CachedUploader) {
+		uploader.ttl = ttl
+	}
+}
+
+// CachedUploaderWithTempDir 只能读取一次的内容在计算摘要时暂存的目录，默认为系统临时目录
+func CachedUploaderWithTempDir(dir string) CachedUploaderOptionFn {
+	return func(uploader *CachedUploader) {
+		uploader.tempDir = dir
+	}
+}
+
+func CachedUploaderWithLogger(logger wechatgo.Logger) CachedUploaderOptionFn {
+	return func(uploader *CachedUploader) {
+		uploader.log = logger
+	}
+}
+
+// 正在进行的上传
+type uploadCall struct {
+	done chan struct{}
+	info *MediaInfo
+	err  error
+}
+
+// CachedUploader 按内容缓存media_id的临时素材上传
+//
+// 临时素材的media_id在三天内有效，且可以在企业内的应用之间共享。上传前计算内容的SHA-256摘要，
+// 缓存中存在相同类型、相同内容的media_id时直接返回，否则上传并缓存。普通文件在消息中会显示文件名，
+// 因此文件名也作为普通文件缓存key的一部分。同时上传相同内容时只会上传一次，其他调用等待上传结果。
+//
+// media_id不能跨企业使用，缓存key包含企业ID，多个企业可以共享同一个storage.Storage。
+// 多个进程共享缓存时应使用持久化的storage.Storage。
+type CachedUploader struct {
+	media   *WechatMedia
+	storage storage.Storage
+	ttl     time.Duration
+	tempDir string
+	log     wechatgo.Logger
+
+	mutex *sync.Mutex
+	calls map[string]*uploadCall
+}
+
+func NewCachedUploader(media *WechatMedia, s storage.Storage, options ...CachedUploaderOptionFn) *CachedUploader {
+	uploader := &CachedUploader{
+		media:   media,
+		storage: s,
+		mutex:   &sync.Mutex{},
+		calls:   map[string]*uploadCall{},
+	}
+	for _, opt := range options {
+		opt(uploader)
+	}
+
+	if uploader.ttl <= 0 {
+		uploader.ttl = DefaultMediaCacheTTL
+	}
+	if uploader.log == nil {
+		uploader.log = wechatgo.DefaultLogger()
+	}
+	return uploader
+}
+
+// UploadMedia 上传临时素材，相同内容的media_id已缓存时不再上传，参数与WechatMedia.UploadMedia相同
+//
+// io.ReadSeeker读取一次计算摘要后重新定位上传；只能读取一次的内容会先暂存到临时文件
+func (u *CachedUploader) UploadMedia(ctx context.Context, filename, type_ string, r io.Reader) (*MediaInfo, error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return &MediaInfo{}, err
+		}
+		sum, err := digest(seeker)
+		if err != nil {
+			return &MediaInfo{}, err
+		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return &MediaInfo{}, err
+		}
+		return u.upload(ctx, u.cacheKey(filename, type_, sum), func() (*MediaInfo, error) {
+			return u.media.UploadMedia(ctx, filename, type_, seeker)
+		})
+	}
+
+	file, err := os.CreateTemp(u.tempDir, "wecom-media-*")
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, h), r); err != nil {
+		return &MediaInfo{}, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return &MediaInfo{}, err
+	}
+	return u.upload(ctx, u.cacheKey(filename, type_, h), func() (*MediaInfo, error) {
+		return u.media.UploadMedia(ctx, filename, type_, file)
+	})
+}
+
+// UploadMediaFromOpener 上传临时素材，相同内容的media_id已缓存时不再上传，参数与WechatMedia.UploadMediaFromOpener相同
+//
+// 计算摘要时会额外打开一次内容
+func (u *CachedUploader) UploadMediaFromOpener(ctx context.Context, filename, type_ string, size int64, open Opener) (*MediaInfo, error) {
+	rc, err := open()
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+	sum, err := digest(rc)
+	rc.Close()
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+
+	return u.upload(ctx, u.cacheKey(filename, type_, sum), func() (*MediaInfo, error) {
+		return u.media.UploadMediaFromOpener(ctx, filename, type_, size, open)
+	})
+}
+
+func digest(r io.Reader) (hash.Hash, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, r)
+	return h, err
+}
+
+func (u *CachedUploader) cacheKey(filename, type_ string, sum hash.Hash) string {
+	if MediaType(type_) == MediaTypeFile {
+		sum.Write([]byte("\x00" + filename))
+	}
+	return fmt.Sprintf("media_%s_%s_%s", u.media.Client.CorpId, type_, hex.EncodeToString(sum.Sum(nil)))
+}
+
+func (u *CachedUploader) lookup(ctx context.Context, key string) (*MediaInfo, bool) {
+	value := u.storage.Get(ctx, key)
+	if value == "" {
+		return nil, false
+	}
+
+	info := &MediaInfo{}
+	if err := json.Unmarshal([]byte(value), info); err != nil || info.MediaId == "" {
+		u.log.Warn(ctx, fmt.Sprintf("CachedUploader: ignore invalid cache %s", key))
+		return nil, false
+	}
+	return info, true
+}
+
+func (u *CachedUploader) upload(ctx context.Context, key string, upload func() (*MediaInfo, error)) (*MediaInfo, error) {
+	if info, ok := u.lookup(ctx, key); ok {
+		return info, nil
+	}
+
+	u.mutex.Lock()
+	if call, ok := u.calls[key]; ok {
+		u.mutex.Unlock()
+		select {
+		case <-call.done:
+			if call.err != nil {
+				return &MediaInfo{}, call.err
+			}
+			info := *call.info
+			return &info, nil
+		case <-ctx.Done():
+			return &MediaInfo{}, ctx.Err()
+		}
+	}
+	call := &uploadCall{done: make(chan struct{})}
+	u.calls[key] = call
+	u.mutex.Unlock()
+
+	defer func() {
+		u.mutex.Lock()
+		delete(u.calls, key)
+		u.mutex.Unlock()
+		close(call.done)
+	}()
+
+	// 等待锁的过程中其他调用可能已完成上传
+	if info, ok := u.lookup(ctx, key); ok {
+		call.info = info
+		return info, nil
+	}
+
+	call.info, call.err = upload()
+	if call.err != nil {
+		return call.info, call.err
+	}
+
+	data, err := json.Marshal(call.info)
+	if err == nil {
+		err = u.storage.Set(ctx, key, string(data), u.ttl)
+	}
+	if err != nil {
+		u.log.Warn(ctx, fmt.Sprintf("CachedUploader: failed to cache media %s, Error: %s", call.info.MediaId, err))
+	}
+	info := *call.info
+	return &info, nil
+}
@@ -0,0 +1,148 @@
+package media
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/storage"
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+type cacheTestSuite struct {
+	mockTestSuite
+	uploader *CachedUploader
+}
+
+func (s *cacheTestSuite) SetupTest() {
+	s.mockTestSuite.SetupTest()
+	s.uploader = NewCachedUploader(s.media, storage.NewMemoryStorage())
+}
+
+func (s *cacheTestSuite) uploadCount() int {
+	count := 0
+	for key, n := range s.transport.GetCallCountInfo() {
+		if strings.HasPrefix(key, "POST "+mock.BaseURL+urlUploadMedia) {
+			count += n
+		}
+	}
+	return count
+}
+
+func (s *cacheTestSuite) TestShouldReuseCachedMediaId() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA1"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA2"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA3"}`)
+	ctx := context.Background()
+
+	info, err := s.uploader.UploadMedia(ctx, "report.pdf", "file", strings.NewReader("pdf content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId)
+
+	info, err = s.uploader.UploadMedia(ctx, "report.pdf", "file", io.MultiReader(strings.NewReader("pdf content")))
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId, "same content read from a non-seekable reader")
+
+	info, err = s.uploader.UploadMediaFromOpener(ctx, "report.pdf", "file", 11, func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("pdf content")), nil
+	})
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId)
+	s.Equal(1, s.uploadCount())
+
+	info, err = s.uploader.UploadMedia(ctx, "summary.pdf", "file", strings.NewReader("pdf content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA2", info.MediaId, "files with different names are cached separately")
+
+	info, err = s.uploader.UploadMedia(ctx, "report.pdf", "file", strings.NewReader("new pdf content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA3", info.MediaId)
+	s.Equal(3, s.uploadCount())
+	s.Equal("new pdf content", string(s.uploads[2].content))
+}
+
+func (s *cacheTestSuite) TestShouldSeparateCacheByCorp() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA1"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA2"}`)
+	shared := storage.NewMemoryStorage()
+	other := wecom.NewClient("othercorp", "secret", 1000001, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+
+	_, err := NewCachedUploader(s.media, shared).UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Require().NoError(err)
+	info, err := NewCachedUploader(NewWechatMedia(other), shared).UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA2", info.MediaId)
+}
+
+func (s *cacheTestSuite) TestShouldExpireCache() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA1"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA2"}`)
+	uploader := NewCachedUploader(s.media, storage.NewMemoryStorage(), CachedUploaderWithTTL(time.Millisecond*10))
+
+	_, err := uploader.UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Require().NoError(err)
+	time.Sleep(time.Millisecond * 20)
+
+	info, err := uploader.UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA2", info.MediaId)
+}
+
+func (s *cacheTestSuite) TestShouldNotCacheFailedUpload() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":40004,"errmsg":"invalid media size"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA1"}`)
+
+	_, err := s.uploader.UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Error(err)
+
+	info, err := s.uploader.UploadMedia(context.Background(), "a.txt", "file", strings.NewReader("content"))
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId)
+}
+
+func (s *cacheTestSuite) TestShouldDeduplicateConcurrentUploads() {
+	release := make(chan struct{})
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlUploadMedia, func(req *http.Request) (*http.Response, error) {
+		io.Copy(io.Discard, req.Body)
+		<-release
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","type":"image","media_id":"MEDIA1"}`), nil
+	})
+
+	const n = 5
+	wg := sync.WaitGroup{}
+	results := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			info, err := s.uploader.UploadMedia(context.Background(), "logo.png", "image", strings.NewReader("\x89PNG\r\n\x1a\nlogo"))
+			s.NoError(err)
+			results[i] = info.MediaId
+		}(i)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+
+	s.Equal(1, s.uploadCount())
+	for _, mediaId := range results {
+		s.Equal("MEDIA1", mediaId)
+	}
+}
+
+func TestCachedUploader(t *testing.T) {
+	suite.Run(t, new(cacheTestSuite))
+}
@@ -0,0 +1,318 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/huimingz/wechatgo"
+)
+
+// ErrSizeMismatch 下载的字节数与响应声明的文件大小不一致
+var ErrSizeMismatch = errors.New("media: downloaded size mismatch")
+
+// RangeNotSatisfiableError 请求的起始位置不小于文件大小，服务器返回416
+type RangeNotSatisfiableError struct {
+	Size int64 // Content-Range中声明的完整文件的字节数，未知时为-1
+}
+
+func (e *RangeNotSatisfiableError) Error() string {
+	return fmt.Sprintf("media: requested range not satisfiable, file size %d", e.Size)
+}
+
+// MediaFile 素材文件的信息
+type MediaFile struct {
+	Filename    string // 文件名，支持RFC 5987编码的filename*参数
+	ContentType string // 文件的MIME类型
+	Size        int64  // 完整文件的字节数，未知时为-1
+}
+
+// Download 素材下载的响应
+type Download struct {
+	MediaFile
+	Offset int64         // Body在文件中的起始位置，服务器不支持Range请求时为0
+	Length int64         // Body的字节数，未知时为-1
+	Body   io.ReadCloser // 文件内容，调用方需关闭
+}
+
+type DownloadOptionFn func(option *downloadOption)
+
+type downloadOption struct {
+	offset int64
+}
+
+// DownloadWithOffset 从文件的offset位置开始下载，用于断点续传
+//
+// 服务器不支持Range请求时会返回完整的文件，此时Download.Offset为0
+func DownloadWithOffset(offset int64) DownloadOptionFn {
+	return func(option *downloadOption) {
+		option.offset = offset
+	}
+}
+
+// Download 下载临时素材，返回文件信息及内容
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90254
+func (w WechatMedia) Download(ctx context.Context, mediaId string, options ...DownloadOptionFn) (*Download, error) {
+	return w.download(ctx, urlGetMedia, mediaId, options...)
+}
+
+// DownloadVoice 下载JSSDK上传的高清语音素材，返回文件信息及内容
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90255
+func (w WechatMedia) DownloadVoice(ctx context.Context, mediaId string, options ...DownloadOptionFn) (*Download, error) {
+	return w.download(ctx, urlGetVoice, mediaId, options...)
+}
+
+func (w WechatMedia) download(ctx context.Context, path, mediaId string, options ...DownloadOptionFn) (*Download, error) {
+	option := downloadOption{}
+	for _, opt := range options {
+		opt(&option)
+	}
+
+	values := url.Values{}
+	values.Add("media_id", mediaId)
+
+	var header http.Header
+	if option.offset > 0 {
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", option.offset)}}
+	}
+
+	resp, err := w.Client.RawGetWithHeader(ctx, path, values, header)
+	if err != nil {
+		return nil, err
+	}
+
+	download, err := w.parseDownload(resp)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return download, nil
+}
+
+func (w WechatMedia) parseDownload(resp *http.Response) (*Download, error) {
+	if err := w.checkMediaHeader(resp.Header); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return nil, &RangeNotSatisfiableError{Size: parseUnsatisfiedRange(resp.Header.Get("Content-Range"))}
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("http response status code[%d] != 200", resp.StatusCode)
+	}
+
+	// 出错时响应体为JSON格式的错误信息且没有Content-Disposition，文本类型的素材文件则带有Content-Disposition
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if (mediaType == "application/json" || mediaType == "text/plain") && resp.Header.Get("Content-Disposition") == "" {
+		return nil, parseErrorBody(resp.Body)
+	}
+
+	filename, err := w.getMediaFilename(resp.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &Download{
+		MediaFile: MediaFile{Filename: filename, ContentType: contentType, Size: resp.ContentLength},
+		Length:    resp.ContentLength,
+		Body:      resp.Body,
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		start, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return nil, err
+		}
+		download.Offset = start
+		download.Size = total
+	}
+	return download, nil
+}
+
+// 接口出错时返回JSON格式的错误信息
+func parseErrorBody(body io.Reader) error {
+	content, err := io.ReadAll(io.LimitReader(body, 64*1024))
+	if err != nil {
+		return err
+	}
+
+	errmsg := &wechatgo.WechatMessageError{}
+	if err = json.Unmarshal(content, errmsg); err != nil {
+		return fmt.Errorf("media: unexpected response: %s", content)
+	}
+	if errmsg.GetErrCode() != 0 {
+		return errmsg
+	}
+	return fmt.Errorf("media: unexpected response: %s", content)
+}
+
+// 解析Content-Range: bytes start-end/total，total未知时为-1
+func parseContentRange(value string) (start, total int64, err error) {
+	invalid := fmt.Errorf("media: invalid Content-Range %q", value)
+
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, invalid
+	}
+	rangeSpec, totalSpec, ok := strings.Cut(strings.TrimPrefix(value, "bytes "), "/")
+	if !ok {
+		return 0, 0, invalid
+	}
+	startSpec, _, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return 0, 0, invalid
+	}
+
+	if start, err = strconv.ParseInt(startSpec, 10, 64); err != nil {
+		return 0, 0, invalid
+	}
+	if totalSpec == "*" {
+		return start, -1, nil
+	}
+	if total, err = strconv.ParseInt(totalSpec, 10, 64); err != nil {
+		return 0, 0, invalid
+	}
+	return start, total, nil
+}
+
+// 解析416响应的Content-Range: bytes */total，无法解析时为-1
+func parseUnsatisfiedRange(value string) int64 {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "bytes */") {
+		return -1
+	}
+	total, err := strconv.ParseInt(strings.TrimPrefix(value, "bytes */"), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+// SaveMedia 下载临时素材并保存到path，参见SaveToFile
+func (w WechatMedia) SaveMedia(ctx context.Context, mediaId, path string) (*MediaFile, error) {
+	return SaveToFile(ctx, path, func(ctx context.Context, offset int64) (*Download, error) {
+		return w.Download(ctx, mediaId, DownloadWithOffset(offset))
+	})
+}
+
+// SaveVoice 下载高清语音素材并保存到path，参见SaveToFile
+func (w WechatMedia) SaveVoice(ctx context.Context, mediaId, path string) (*MediaFile, error) {
+	return SaveToFile(ctx, path, func(ctx context.Context, offset int64) (*Download, error) {
+		return w.DownloadVoice(ctx, mediaId, DownloadWithOffset(offset))
+	})
+}
+
+// SaveToFile 将下载的内容原子地保存到path
+//
+// 内容先写入path.part，校验大小并同步到磁盘后再重命名为path，不会留下不完整的path。
+// 下载中断时保留path.part，再次调用时从已下载的位置继续下载；大小不一致时删除path.part并返回ErrSizeMismatch。
+// path.part已下载完整但重命名失败时，服务器对续传请求返回416，此时直接完成重命名，返回的MediaFile只有Size；
+// 返回416且内容大小与path.part不一致时，丢弃path.part从头下载。
+func SaveToFile(ctx context.Context, path string, download func(ctx context.Context, offset int64) (*Download, error)) (*MediaFile, error) {
+	partPath := path + ".part"
+	file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := download(ctx, offset)
+	var rangeErr *RangeNotSatisfiableError
+	if errors.As(err, &rangeErr) && offset > 0 {
+		if rangeErr.Size == offset {
+			if err = commitPartFile(file, partPath, path); err != nil {
+				return nil, err
+			}
+			return &MediaFile{Size: offset}, nil
+		}
+
+		// path.part比服务器上的内容长，无法续传，丢弃后从头下载
+		if err = file.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		offset = 0
+		d, err = download(ctx, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer d.Body.Close()
+
+	// 服务器返回的内容不是从已下载的位置开始时，从头重新写入
+	if d.Offset != offset {
+		if d.Offset != 0 {
+			return nil, fmt.Errorf("media: unexpected download offset %d, expected %d", d.Offset, offset)
+		}
+		if err = file.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := io.Copy(file, d.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	written := d.Offset + n
+	expected := d.Size
+	if expected < 0 && d.Length >= 0 {
+		expected = d.Offset + d.Length
+	}
+	if expected >= 0 && written != expected {
+		file.Close()
+		os.Remove(partPath)
+		return nil, fmt.Errorf("%w: expected %d bytes but got %d", ErrSizeMismatch, expected, written)
+	}
+
+	if err = commitPartFile(file, partPath, path); err != nil {
+		return nil, err
+	}
+
+	info := d.MediaFile
+	info.Size = written
+	return &info, nil
+}
+
+// 将下载完成的path.part同步到磁盘后重命名为path
+func commitPartFile(file *os.File, partPath, path string) error {
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath, path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// 同步目录，确保重命名已写入磁盘，部分平台不支持时忽略
+func syncDir(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		f.Sync()
+		f.Close()
+	}
+}
@@ -0,0 +1,208 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/mock"
+)
+
+const mediaContent = "0123456789abcdefghij"
+
+type downloadTestSuite struct {
+	mockTestSuite
+	ranges []string
+}
+
+func (s *downloadTestSuite) SetupTest() {
+	s.mockTestSuite.SetupTest()
+	s.ranges = nil
+}
+
+// 注册返回mediaContent的下载接口，supportRange为false时忽略Range请求头
+func (s *downloadTestSuite) registerDownloadResponder(url string, supportRange bool, content string) {
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+url, func(req *http.Request) (*http.Response, error) {
+		s.ranges = append(s.ranges, req.Header.Get("Range"))
+
+		status, body := http.StatusOK, content
+		header := http.Header{
+			"Content-Type":        {"image/png"},
+			"Content-Disposition": {`attachment; filename="fallback.png"; filename*=UTF-8''%E5%9B%BE%E7%89%87.png`},
+		}
+		var offset int
+		if r := req.Header.Get("Range"); r != "" && supportRange {
+			offset, _ = strconv.Atoi(r[len("bytes=") : len(r)-1])
+			if offset >= len(content) {
+				resp := httpmock.NewStringResponse(http.StatusRequestedRangeNotSatisfiable, "")
+				resp.Header = http.Header{"Content-Range": {"bytes */" + strconv.Itoa(len(content))}}
+				return resp, nil
+			}
+			status, body = http.StatusPartialContent, content[offset:]
+			header.Set("Content-Range", "bytes "+strconv.Itoa(offset)+"-"+strconv.Itoa(len(mediaContent)-1)+"/"+strconv.Itoa(len(mediaContent)))
+		}
+
+		resp := httpmock.NewStringResponse(status, body)
+		resp.Header = header
+		resp.ContentLength = int64(len(body))
+		return resp, nil
+	})
+}
+
+func (s *downloadTestSuite) TestShouldReturnMetadata() {
+	s.registerDownloadResponder(urlGetMedia, true, mediaContent)
+
+	d, err := s.media.Download(context.Background(), "MEDIA1")
+	s.Require().NoError(err)
+	defer d.Body.Close()
+
+	s.Equal("图片.png", d.Filename)
+	s.Equal("image/png", d.ContentType)
+	s.EqualValues(len(mediaContent), d.Size)
+	s.EqualValues(len(mediaContent), d.Length)
+	s.EqualValues(0, d.Offset)
+	content, err := io.ReadAll(d.Body)
+	s.NoError(err)
+	s.Equal(mediaContent, string(content))
+
+	body, fn, err := s.media.GetMedia(context.Background(), "MEDIA1")
+	s.Require().NoError(err)
+	body.Close()
+	s.Equal("图片.png", fn)
+}
+
+func (s *downloadTestSuite) TestShouldDownloadFromOffset() {
+	s.registerDownloadResponder(urlGetVoice, true, mediaContent)
+
+	d, err := s.media.DownloadVoice(context.Background(), "MEDIA1", DownloadWithOffset(15))
+	s.Require().NoError(err)
+	defer d.Body.Close()
+
+	s.Equal([]string{"bytes=15-"}, s.ranges)
+	s.EqualValues(15, d.Offset)
+	s.EqualValues(5, d.Length)
+	s.EqualValues(len(mediaContent), d.Size)
+}
+
+func (s *downloadTestSuite) TestShouldReturnErrorFromJSONBody() {
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+urlGetMedia,
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":40007,"errmsg":"invalid media_id"}`).HeaderSet(http.Header{"Content-Type": {"application/json"}}))
+
+	_, err := s.media.Download(context.Background(), "MEDIA1")
+	var wxErr *wechatgo.WechatMessageError
+	s.Require().True(errors.As(err, &wxErr))
+	s.Equal(40007, wxErr.GetErrCode())
+}
+
+func (s *downloadTestSuite) TestShouldDownloadTextMedia() {
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+urlGetMedia,
+		httpmock.NewStringResponder(http.StatusOK, "plain text file").HeaderSet(http.Header{
+			"Content-Type":        {"text/plain; charset=utf-8"},
+			"Content-Disposition": {`attachment; filename="notes.txt"`},
+		}))
+
+	d, err := s.media.Download(context.Background(), "MEDIA1")
+	s.Require().NoError(err)
+	defer d.Body.Close()
+
+	s.Equal("notes.txt", d.Filename)
+	content, err := io.ReadAll(d.Body)
+	s.NoError(err)
+	s.Equal("plain text file", string(content))
+}
+
+func (s *downloadTestSuite) TestShouldCheckStatusCode() {
+	s.transport.RegisterResponder(http.MethodGet, mock.BaseURL+urlGetMedia, httpmock.NewStringResponder(http.StatusBadGateway, "bad gateway"))
+
+	_, _, err := s.media.GetMedia(context.Background(), "MEDIA1")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "502")
+}
+
+func (s *downloadTestSuite) TestShouldResumeSaveToFile() {
+	s.registerDownloadResponder(urlGetMedia, true, mediaContent)
+	path := filepath.Join(s.T().TempDir(), "media.png")
+	s.Require().NoError(os.WriteFile(path+".part", []byte(mediaContent[:8]), 0o644))
+
+	info, err := s.media.SaveMedia(context.Background(), "MEDIA1", path)
+	s.Require().NoError(err)
+	s.Equal("图片.png", info.Filename)
+	s.EqualValues(len(mediaContent), info.Size)
+	s.Equal([]string{"bytes=8-"}, s.ranges)
+
+	content, err := os.ReadFile(path)
+	s.NoError(err)
+	s.Equal(mediaContent, string(content))
+	_, err = os.Stat(path + ".part")
+	s.True(os.IsNotExist(err))
+}
+
+func (s *downloadTestSuite) TestShouldCompleteWhenPartFileIsComplete() {
+	s.registerDownloadResponder(urlGetMedia, true, mediaContent)
+	path := filepath.Join(s.T().TempDir(), "media.png")
+	s.Require().NoError(os.WriteFile(path+".part", []byte(mediaContent), 0o644))
+
+	info, err := s.media.SaveMedia(context.Background(), "MEDIA1", path)
+	s.Require().NoError(err)
+	s.EqualValues(len(mediaContent), info.Size)
+	s.Equal([]string{"bytes=20-"}, s.ranges)
+
+	content, err := os.ReadFile(path)
+	s.NoError(err)
+	s.Equal(mediaContent, string(content))
+	_, err = os.Stat(path + ".part")
+	s.True(os.IsNotExist(err))
+}
+
+func (s *downloadTestSuite) TestShouldRestartWhenPartFileIsLonger() {
+	s.registerDownloadResponder(urlGetMedia, true, mediaContent)
+	path := filepath.Join(s.T().TempDir(), "media.png")
+	s.Require().NoError(os.WriteFile(path+".part", []byte(mediaContent+"stale"), 0o644))
+
+	info, err := s.media.SaveMedia(context.Background(), "MEDIA1", path)
+	s.Require().NoError(err)
+	s.EqualValues(len(mediaContent), info.Size)
+	s.Equal([]string{"bytes=25-", ""}, s.ranges)
+
+	content, err := os.ReadFile(path)
+	s.NoError(err)
+	s.Equal(mediaContent, string(content))
+}
+
+func (s *downloadTestSuite) TestShouldRestartWhenRangeIsNotSupported() {
+	s.registerDownloadResponder(urlGetVoice, false, mediaContent)
+	path := filepath.Join(s.T().TempDir(), "voice.speex")
+	s.Require().NoError(os.WriteFile(path+".part", []byte("stale content!!"), 0o644))
+
+	_, err := s.media.SaveVoice(context.Background(), "MEDIA1", path)
+	s.Require().NoError(err)
+	content, err := os.ReadFile(path)
+	s.NoError(err)
+	s.Equal(mediaContent, string(content))
+}
+
+func (s *downloadTestSuite) TestShouldVerifySize() {
+	path := filepath.Join(s.T().TempDir(), "media.png")
+	_, err := SaveToFile(context.Background(), path, func(ctx context.Context, offset int64) (*Download, error) {
+		return &Download{MediaFile: MediaFile{Size: 100}, Length: -1, Body: io.NopCloser(httpmock.NewRespBodyFromString(mediaContent))}, nil
+	})
+	s.True(errors.Is(err, ErrSizeMismatch))
+
+	_, err = os.Stat(path)
+	s.True(os.IsNotExist(err))
+	_, err = os.Stat(path + ".part")
+	s.True(os.IsNotExist(err))
+}
+
+func TestDownload(t *testing.T) {
+	suite.Run(t, new(downloadTestSuite))
+}
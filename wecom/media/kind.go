@@ -0,0 +1,158 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+// MediaType 临时素材的类型
+type MediaType string
+
+const (
+	MediaTypeImage MediaType = "image" // 图片，不超过10MB，支持JPG、PNG格式
+	MediaTypeVoice MediaType = "voice" // 语音，不超过2MB，播放长度不超过60s，仅支持AMR格式
+	MediaTypeVideo MediaType = "video" // 视频，不超过10MB，支持MP4格式
+	MediaTypeFile  MediaType = "file"  // 普通文件，不超过20MB
+)
+
+// MinMediaBytes 素材的最小字节数，所有类型的素材都需大于等于5个字节
+const MinMediaBytes = 5
+
+// 素材格式
+const (
+	formatJPG = "jpg"
+	formatPNG = "png"
+	formatAMR = "amr"
+	formatMP4 = "mp4"
+)
+
+// MediaLimit 素材的大小及格式限制
+type MediaLimit struct {
+	MaxBytes int64    // 最大字节数
+	Formats  []string // 支持的格式，为空时不限制
+}
+
+var mediaLimits = map[MediaType]MediaLimit{
+	MediaTypeImage: {MaxBytes: 10 * 1024 * 1024, Formats: []string{formatJPG, formatPNG}},
+	MediaTypeVoice: {MaxBytes: 2 * 1024 * 1024, Formats: []string{formatAMR}},
+	MediaTypeVideo: {MaxBytes: 10 * 1024 * 1024, Formats: []string{formatMP4}},
+	MediaTypeFile:  {MaxBytes: 20 * 1024 * 1024},
+}
+
+// 永久图片的限制
+var perpetualImgLimit = MediaLimit{MaxBytes: 2 * 1024 * 1024, Formats: []string{formatJPG, formatPNG}}
+
+// Limit 素材类型的限制，未知的类型返回false
+func (t MediaType) Limit() (MediaLimit, bool) {
+	limit, ok := mediaLimits[t]
+	return limit, ok
+}
+
+// MediaValidationError 素材不符合类型的限制
+type MediaValidationError struct {
+	Type     MediaType // 素材类型
+	Filename string    // 文件名
+	Reason   string    // 原因
+}
+
+func (e *MediaValidationError) Error() string {
+	return fmt.Sprintf("media: invalid %s %q: %s", e.Type, e.Filename, e.Reason)
+}
+
+// Is 内容不符合限制时重试也不会成功，上传过程中校验失败时PostStream不再重试
+func (e *MediaValidationError) Is(target error) bool {
+	return target == wecom.ErrNotRetryable
+}
+
+// 文件扩展名对应的格式
+var extensionFormats = map[string]string{
+	".jpg":  formatJPG,
+	".jpeg": formatJPG,
+	".png":  formatPNG,
+	".amr":  formatAMR,
+	".mp4":  formatMP4,
+}
+
+// 根据文件头识别格式，无法识别时返回空字符串
+func sniffFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return formatJPG
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG
+	case bytes.HasPrefix(head, []byte("#!AMR")):
+		return formatAMR
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return formatMP4
+	}
+	return ""
+}
+
+// 识别格式，优先根据文件头识别，无法识别时使用文件扩展名
+func detectFormat(filename string, head []byte) string {
+	if format := sniffFormat(head); format != "" {
+		return format
+	}
+	return extensionFormats[strings.ToLower(filepath.Ext(filename))]
+}
+
+// DetectMediaType 根据文件头及文件扩展名识别素材类型，无法识别时为MediaTypeFile
+//
+// head为文件开头的内容，至少需要12个字节才能识别MP4格式
+func DetectMediaType(filename string, head []byte) MediaType {
+	switch detectFormat(filename, head) {
+	case formatJPG, formatPNG:
+		return MediaTypeImage
+	case formatAMR:
+		return MediaTypeVoice
+	case formatMP4:
+		return MediaTypeVideo
+	}
+	return MediaTypeFile
+}
+
+// Validate 校验素材的大小及格式，size未知时传入-1，此时仅校验格式
+func (t MediaType) Validate(filename string, size int64, head []byte) error {
+	limit, ok := t.Limit()
+	if !ok {
+		return &MediaValidationError{Type: t, Filename: filename, Reason: "unknown media type"}
+	}
+	return limit.validate(t, filename, size, head)
+}
+
+func (l MediaLimit) validate(t MediaType, filename string, size int64, head []byte) error {
+	if err := l.validateSize(t, filename, size); err != nil {
+		return err
+	}
+	if len(l.Formats) == 0 {
+		return nil
+	}
+
+	format := detectFormat(filename, head)
+	for _, f := range l.Formats {
+		if f == format {
+			return nil
+		}
+	}
+	return &MediaValidationError{Type: t, Filename: filename,
+		Reason: fmt.Sprintf("unsupported format, expected %s", strings.Join(l.Formats, "/"))}
+}
+
+func (l MediaLimit) validateSize(t MediaType, filename string, size int64) error {
+	if size < 0 {
+		return nil
+	}
+	if size < MinMediaBytes {
+		return &MediaValidationError{Type: t, Filename: filename,
+			Reason: fmt.Sprintf("size %d bytes is less than %d bytes", size, MinMediaBytes)}
+	}
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		return &MediaValidationError{Type: t, Filename: filename,
+			Reason: fmt.Sprintf("size %d bytes exceeds %d bytes", size, l.MaxBytes)}
+	}
+	return nil
+}
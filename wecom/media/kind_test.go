@@ -0,0 +1,103 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+)
+
+var (
+	jpgHead = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}
+	pngHead = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	amrHead = []byte("#!AMR\n\x3c\x00\x00\x00")
+	mp4Head = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00")
+)
+
+type kindTestSuite struct {
+	mockTestSuite
+}
+
+func (s *kindTestSuite) TestShouldDetectMediaType() {
+	testcases := []struct {
+		filename string
+		head     []byte
+		expected MediaType
+	}{
+		{"photo", jpgHead, MediaTypeImage},
+		{"photo.bin", pngHead, MediaTypeImage},
+		{"voice", amrHead, MediaTypeVoice},
+		{"movie", mp4Head, MediaTypeVideo},
+		{"photo.JPEG", nil, MediaTypeImage},
+		{"clip.mp4", []byte("unknown"), MediaTypeVideo},
+		{"report.pdf", []byte("%PDF-1.7"), MediaTypeFile},
+	}
+
+	for _, tt := range testcases {
+		s.Equal(tt.expected, DetectMediaType(tt.filename, tt.head), tt.filename)
+	}
+}
+
+func (s *kindTestSuite) TestShouldValidateSizeAndFormat() {
+	s.NoError(MediaTypeImage.Validate("a.png", 1024, pngHead))
+	s.NoError(MediaTypeFile.Validate("a.zip", 20*1024*1024, nil))
+	s.NoError(MediaTypeVoice.Validate("a.amr", -1, nil))
+
+	var validationErr *MediaValidationError
+	err := MediaTypeImage.Validate("a.gif", 1024, []byte("GIF89a"))
+	s.Require().True(errors.As(err, &validationErr))
+	s.Equal(MediaTypeImage, validationErr.Type)
+	s.Contains(err.Error(), "expected jpg/png")
+
+	s.Contains(MediaTypeVideo.Validate("a.mp4", 10*1024*1024+1, mp4Head).Error(), "exceeds")
+	s.Contains(MediaTypeFile.Validate("a.txt", 4, nil).Error(), "less than 5 bytes")
+	s.Contains(MediaType("doc").Validate("a.doc", 100, nil).Error(), "unknown media type")
+}
+
+func (s *kindTestSuite) TestShouldRejectInvalidMediaBeforeUpload() {
+	_, err := s.media.UploadMedia(context.Background(), "voice.mp3", string(MediaTypeVoice), strings.NewReader("ID3 mp3 content"))
+	var validationErr *MediaValidationError
+	s.True(errors.As(err, &validationErr))
+
+	_, err = s.media.UploadPerpetualImg(context.Background(), "big.jpg", bytes.NewReader(append(jpgHead, make([]byte, 2*1024*1024)...)))
+	s.True(errors.As(err, &validationErr))
+	s.Empty(s.uploads)
+}
+
+func (s *kindTestSuite) TestShouldRejectOversizedStreamWhileUploading() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlUploadMedia, func(req *http.Request) (*http.Response, error) {
+		_, err := io.ReadAll(req.Body)
+		return nil, err
+	})
+
+	content := io.MultiReader(bytes.NewReader(amrHead), bytes.NewReader(make([]byte, 2*1024*1024)))
+	_, err := s.media.UploadMedia(context.Background(), "voice.amr", string(MediaTypeVoice), content)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "exceeds")
+}
+
+func (s *kindTestSuite) TestShouldUploadWithDetectedType() {
+	s.registerUploadResponder(urlUploadMedia, `{"errcode":0,"errmsg":"ok","type":"image","media_id":"MEDIA1"}`)
+
+	data, err := os.ReadFile("test_file.jpg")
+	s.Require().NoError(err)
+
+	info, err := s.media.UploadMediaAuto(context.Background(), "upload", io.MultiReader(bytes.NewReader(data)))
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId)
+	s.Require().Len(s.uploads, 1)
+	s.Equal(data, s.uploads[0].content, "sniffed head is sent with the content")
+	s.Equal("image", s.uploads[0].mediaType)
+}
+
+func TestKind(t *testing.T) {
+	suite.Run(t, new(kindTestSuite))
+}
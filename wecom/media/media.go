@@ -2,11 +2,10 @@
 package media
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"io"
-	"mime/multipart"
+	"mime"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -41,27 +40,58 @@ func NewWechatMedia(client *wecom.Client) *WechatMedia {
 //
 // 素材上传得到media_id，该media_id仅三天内有效，media_id在同一企业内应用之间可以共享
 //
+// 内容以流的方式上传，不会完整读入内存。r为io.ReadSeeker（如*os.File）时从当前位置开始上传，
+// 请求体带有Content-Length，失败后可以重试；其他类型只能读取一次，需要重试时使用UploadMediaFromOpener。
+//
+// type_取值见MediaType，上传前会按照类型校验大小及格式，不符合时返回*MediaValidationError。
+//
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90253
 func (w WechatMedia) UploadMedia(ctx context.Context, filename, type_ string, r io.Reader) (*MediaInfo, error) {
-	mediaInfo := MediaInfo{}
-	bodyBuf := new(bytes.Buffer)
-	bodyWriter := multipart.NewWriter(bodyBuf)
+	source, err := readerSource(filename, r)
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+	return w.uploadMedia(ctx, type_, source)
+}
 
-	fileWriter, err := bodyWriter.CreateFormFile("media", filename)
+// UploadMediaFromOpener 上传临时素材，内容通过open打开，重试时会重新打开
+//
+// size为内容的字节数，未知时传入-1，此时使用分块传输。校验格式时会额外打开一次内容读取文件头
+func (w WechatMedia) UploadMediaFromOpener(ctx context.Context, filename, type_ string, size int64, open Opener) (*MediaInfo, error) {
+	return w.uploadMedia(ctx, type_, uploadSource{filename: filename, size: size, open: open, reopenable: true})
+}
+
+// UploadMediaAuto 上传临时素材，根据文件头及文件扩展名识别素材类型，无法识别时作为普通文件上传
+func (w WechatMedia) UploadMediaAuto(ctx context.Context, filename string, r io.Reader) (*MediaInfo, error) {
+	source, err := readerSource(filename, r)
 	if err != nil {
-		return &mediaInfo, err
+		return &MediaInfo{}, err
+	}
+	head, source, err := source.sniff()
+	if err != nil {
+		return &MediaInfo{}, err
+	}
+	return w.uploadMedia(ctx, string(DetectMediaType(filename, head)), source)
+}
+
+func (w WechatMedia) uploadMedia(ctx context.Context, type_ string, source uploadSource) (*MediaInfo, error) {
+	mediaInfo := MediaInfo{}
+	mediaType := MediaType(type_)
+	limit, ok := mediaType.Limit()
+	if !ok {
+		return &mediaInfo, &MediaValidationError{Type: mediaType, Filename: source.filename, Reason: "unknown media type"}
 	}
-	_, err = io.Copy(fileWriter, r)
+	source, err := source.validate(mediaType, limit)
 	if err != nil {
 		return &mediaInfo, err
 	}
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
+
+	body := newMultipartBody("media", source)
 
 	values := url.Values{}
 	values.Add("type", type_)
 
-	err = w.Client.AdvPost(ctx, urlUploadMedia, contentType, values, bodyBuf, nil, &mediaInfo)
+	err = w.Client.PostStream(ctx, urlUploadMedia, body.contentType, values, body.ContentLength(), body.Open, nil, &mediaInfo)
 	return &mediaInfo, err
 }
 
@@ -81,16 +111,19 @@ func (w WechatMedia) checkMediaHeader(header http.Header) error {
 	return nil
 }
 
-// 获取素材文件名
+// 获取素材文件名，优先使用RFC 5987编码的filename*参数
 func (w WechatMedia) getMediaFilename(header http.Header) (fn string, err error) {
-	var disposition string
-	if v, ok := header["Content-Disposition"]; ok {
-		disposition = v[0]
-	} else {
+	disposition := header.Get("Content-Disposition")
+	if disposition == "" {
 		err = errors.New("can't find 'Content-Disposition' from response header")
 		return
 	}
 
+	if _, params, err := mime.ParseMediaType(disposition); err == nil && params["filename"] != "" {
+		return params["filename"], nil
+	}
+
+	// 文件名中包含未编码的特殊字符时无法按标准解析
 	compile, err := regexp.Compile(".*filename=\"(?P<filename>[^\"]*)\"")
 	if err != nil {
 		return
@@ -107,91 +140,68 @@ func (w WechatMedia) getMediaFilename(header http.Header) (fn string, err error)
 
 // 获取临时素材
 //
+// 需要文件类型、大小或断点续传时使用Download
+//
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90254
 func (w WechatMedia) GetMedia(ctx context.Context, mediaId string) (body io.ReadCloser, fn string, err error) {
-	values := url.Values{}
-	values.Add("media_id", mediaId)
-
-	resp, err := w.Client.RawGet(ctx, urlGetMedia, values)
+	download, err := w.Download(ctx, mediaId)
 	if err != nil {
-		return
-	}
-
-	// 检查错误信息
-	err = w.checkMediaHeader(resp.Header)
-	if err != nil {
-		return
-	}
-
-	// 获取文件名
-	fn, err = w.getMediaFilename(resp.Header)
-	if err != nil {
-		return
+		return nil, "", err
 	}
-
-	body = resp.Body
-	return
+	return download.Body, download.Filename, nil
 }
 
 // 上传永久图片
 //
+// 内容以流的方式上传，r的处理方式与UploadMedia相同。图片大小为5B~2MB，仅支持JPG、PNG格式，
+// 不符合时返回*MediaValidationError
+//
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90256
 func (w WechatMedia) UploadPerpetualImg(ctx context.Context, filename string, r io.Reader) (url string, err error) {
-	bodyBuf := new(bytes.Buffer)
-	bodyWriter := multipart.NewWriter(bodyBuf)
-
-	fileWritter, err := bodyWriter.CreateFormFile("media", filename)
+	source, err := readerSource(filename, r)
 	if err != nil {
-		return
-	}
-
-	_, err = io.Copy(fileWritter, r)
-	if err != nil {
-		return
+		return "", err
 	}
+	return w.uploadPerpetualImg(ctx, source)
+}
 
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
+// UploadPerpetualImgFromOpener 上传永久图片，内容通过open打开，重试时会重新打开
+//
+// size为内容的字节数，未知时传入-1，此时使用分块传输
+func (w WechatMedia) UploadPerpetualImgFromOpener(ctx context.Context, filename string, size int64, open Opener) (url string, err error) {
+	return w.uploadPerpetualImg(ctx, uploadSource{filename: filename, size: size, open: open, reopenable: true})
+}
 
+func (w WechatMedia) uploadPerpetualImg(ctx context.Context, source uploadSource) (string, error) {
 	type imgInfo struct {
 		Url string `json:"url"`
 	}
 
+	source, err := source.validate(MediaTypeImage, perpetualImgLimit)
+	if err != nil {
+		return "", err
+	}
+
+	body := newMultipartBody("media", source)
 	imgInfo_ := imgInfo{}
-	err = w.Client.AdvPost(ctx, urlUploadPerpetualImg, contentType, nil, bodyBuf, nil, &imgInfo_)
+	err = w.Client.PostStream(ctx, urlUploadPerpetualImg, body.contentType, nil, body.ContentLength(), body.Open, nil, &imgInfo_)
 	if err != nil {
-		return
+		return "", err
 	}
-	url = imgInfo_.Url
-	return
+	return imgInfo_.Url, nil
 }
 
 // 获取高清语音素材
 //
 // 可以使用本接口获取从JSSDK的uploadVoice接口上传的临时语音素材，格式为speex，16K
 // 采样率。该音频比上文的临时素材获取接口（格式为amr，8K采样率）更加清晰，适合用作语音
-// 识别等对音质要求较高的业务。
+// 识别等对音质要求较高的业务。需要文件类型、大小或断点续传时使用DownloadVoice
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90255
 func (w WechatMedia) GetVoice(ctx context.Context, mediaId string) (body io.ReadCloser, fn string, err error) {
-	values := url.Values{}
-	values.Add("media_id", mediaId)
-
-	resp, err := w.Client.RawGet(ctx, urlGetVoice, values)
+	download, err := w.DownloadVoice(ctx, mediaId)
 	if err != nil {
 		return nil, "", err
 	}
-
-	err = w.checkMediaHeader(resp.Header)
-	if err != nil {
-		return
-	}
-
-	fn, err = w.getMediaFilename(resp.Header)
-	if err != nil {
-		return
-	}
-
-	body = resp.Body
-	return
+	return download.Body, download.Filename, nil
 }
@@ -0,0 +1,298 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"sync"
+)
+
+// ErrNotReopenable 上传内容只能读取一次，无法重试
+var ErrNotReopenable = errors.New("media: upload content can not be reopened")
+
+// Opener 打开待上传的内容，重试时会再次调用，每次都需返回从头开始的新内容
+type Opener func() (io.ReadCloser, error)
+
+// 识别格式需要读取的文件头字节数
+const sniffLen = 512
+
+// 待上传的文件
+type uploadSource struct {
+	filename   string
+	size       int64 // 内容的字节数，未知时为-1
+	open       Opener
+	reopenable bool
+}
+
+// 读取文件头，只能读取一次的内容会将文件头拼接回原内容
+func (s uploadSource) sniff() ([]byte, uploadSource, error) {
+	rc, err := s.open()
+	if err != nil {
+		return nil, s, err
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		rc.Close()
+		return nil, s, err
+	}
+	head = head[:n]
+
+	if s.reopenable {
+		return head, s, rc.Close()
+	}
+
+	once := &sync.Once{}
+	s.open = func() (io.ReadCloser, error) {
+		var body io.ReadCloser
+		once.Do(func() {
+			body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(head), rc), rc}
+		})
+		if body == nil {
+			return nil, ErrNotReopenable
+		}
+		return body, nil
+	}
+	return head, s, nil
+}
+
+// 校验内容是否符合限制，内容长度未知时在上传过程中校验大小
+func (s uploadSource) validate(t MediaType, limit MediaLimit) (uploadSource, error) {
+	head, s, err := s.sniff()
+	if err != nil {
+		return s, err
+	}
+	if err = limit.validate(t, s.filename, s.size, head); err != nil {
+		return s, err
+	}
+
+	if s.size < 0 {
+		open := s.open
+		s.open = func() (io.ReadCloser, error) {
+			rc, err := open()
+			if err != nil {
+				return nil, err
+			}
+			return &limitedReader{ReadCloser: rc, t: t, filename: s.filename, limit: limit}, nil
+		}
+	}
+	return s, nil
+}
+
+// 读取时校验内容长度
+type limitedReader struct {
+	io.ReadCloser
+	t        MediaType
+	filename string
+	limit    MediaLimit
+	n        int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if r.limit.MaxBytes > 0 && r.n > r.limit.MaxBytes {
+		return n, r.limit.validateSize(r.t, r.filename, r.n)
+	}
+	if err == io.EOF {
+		if sizeErr := r.limit.validateSize(r.t, r.filename, r.n); sizeErr != nil {
+			return n, sizeErr
+		}
+	}
+	return n, err
+}
+
+type lener interface {
+	Len() int
+}
+
+// 根据io.Reader创建上传内容
+//
+// io.ReadSeeker从当前位置开始上传，重试时重新定位到该位置；实现了Len()的类型（如bytes.Reader）
+// 可以获取内容长度；其他类型只能读取一次且长度未知，使用分块传输。
+func readerSource(filename string, r io.Reader) (uploadSource, error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return uploadSource{}, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return uploadSource{}, err
+		}
+		if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+			return uploadSource{}, err
+		}
+
+		return uploadSource{filename: filename, size: end - start, reopenable: true, open: func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(seeker), nil
+		}}, nil
+	}
+
+	size := int64(-1)
+	if l, ok := r.(lener); ok {
+		size = int64(l.Len())
+	}
+
+	once := &sync.Once{}
+	return uploadSource{filename: filename, size: size, open: func() (io.ReadCloser, error) {
+		var rc io.ReadCloser
+		once.Do(func() { rc = io.NopCloser(r) })
+		if rc == nil {
+			return nil, ErrNotReopenable
+		}
+		return rc, nil
+	}}, nil
+}
+
+// 上传内容的multipart请求体
+type multipartBody struct {
+	source      uploadSource
+	field       string
+	boundary    string
+	contentType string
+
+	mutex *sync.Mutex
+	last  **pipeBody // 最近一次打开的请求体
+}
+
+func newMultipartBody(field string, source uploadSource) multipartBody {
+	writer := multipart.NewWriter(io.Discard)
+	return multipartBody{
+		source:      source,
+		field:       field,
+		boundary:    writer.Boundary(),
+		contentType: writer.FormDataContentType(),
+		mutex:       &sync.Mutex{},
+		last:        new(*pipeBody),
+	}
+}
+
+// 在后台写入内容的管道
+type pipeBody struct {
+	*io.PipeReader
+	done chan struct{} // 写入内容的goroutine退出时关闭
+}
+
+// Close 关闭管道并等待写入内容的goroutine退出，之后不会再读取内容
+func (b *pipeBody) Close() error {
+	err := b.PipeReader.Close()
+	<-b.done
+	return err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// ContentLength 请求体的字节数，内容长度未知时为-1
+func (b multipartBody) ContentLength() int64 {
+	if b.source.size < 0 {
+		return -1
+	}
+
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(b.boundary); err != nil {
+		return -1
+	}
+	if _, err := writer.CreateFormFile(b.field, b.source.filename); err != nil {
+		return -1
+	}
+	if err := writer.Close(); err != nil {
+		return -1
+	}
+	return counter.n + b.source.size
+}
+
+// Open 打开新的请求体，内容在后台写入管道，不会完整读入内存
+//
+// 重试时上一次的请求体可能尚未被HTTP客户端关闭，先关闭并等待其停止读取内容，
+// 避免两次请求同时读取同一个io.ReadSeeker
+func (b multipartBody) Open() (io.ReadCloser, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if last := *b.last; last != nil {
+		last.Close()
+	}
+
+	src, err := b.source.open()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	body := &pipeBody{PipeReader: pr, done: make(chan struct{})}
+	go func() {
+		defer close(body.done)
+		defer src.Close()
+		pw.CloseWithError(b.write(pw, src))
+	}()
+	*b.last = body
+	return body, nil
+}
+
+func (b multipartBody) write(w io.Writer, src io.Reader) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(b.boundary); err != nil {
+		return err
+	}
+
+	part, err := writer.CreateFormFile(b.field, b.source.filename)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(part, src)
+	if err != nil {
+		return err
+	}
+	if b.source.size >= 0 && n != b.source.size {
+		return fmt.Errorf("media: content length mismatch, expected %d bytes but read %d", b.source.size, n)
+	}
+	return writer.Close()
+}
+
+// UploadBody 以流的方式上传文件的multipart请求体，供其他上传文件的接口（如群机器人）使用
+type UploadBody struct {
+	body multipartBody
+}
+
+// NewUploadBody 根据io.Reader创建上传文件的请求体，field为文件的表单字段名
+//
+// r的处理方式与WechatMedia.UploadMedia相同，内容不会完整读入内存
+func NewUploadBody(field, filename string, r io.Reader) (*UploadBody, error) {
+	source, err := readerSource(filename, r)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadBody{body: newMultipartBody(field, source)}, nil
+}
+
+// ContentType 请求体的Content-Type，包含multipart的分隔符
+func (b *UploadBody) ContentType() string {
+	return b.body.contentType
+}
+
+// ContentLength 请求体的字节数，内容长度未知时为-1
+func (b *UploadBody) ContentLength() int64 {
+	return b.body.ContentLength()
+}
+
+// Open 打开新的请求体，r只能读取一次时再次打开返回ErrNotReopenable
+func (b *UploadBody) Open() (io.ReadCloser, error) {
+	return b.body.Open()
+}
@@ -0,0 +1,160 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+)
+
+const (
+	urlUploadByURL          = "/cgi-bin/media/upload_by_url"
+	urlGetUploadByURLResult = "/cgi-bin/media/get_upload_by_url_result"
+)
+
+// 异步上传的场景
+const (
+	UploadSceneWelcome = 1 // 客户联系入群欢迎语素材
+)
+
+// 异步上传任务的状态
+const (
+	UploadStatusProcessing = 1 // 处理中
+	UploadStatusCompleted  = 2 // 完成
+	UploadStatusFailed     = 3 // 异常失败
+)
+
+// ErrUploadByURLFailed 异步上传任务异常失败，但结果中没有错误码
+var ErrUploadByURLFailed = errors.New("media: upload by url job failed without error detail")
+
+// UploadByURLOption 异步上传临时素材的参数
+type UploadByURLOption struct {
+	Scene    int       `json:"scene"`    // 场景值，取值见UploadScene常量，为0时使用UploadSceneWelcome
+	Type     MediaType `json:"type"`     // 媒体文件类型，目前仅支持MediaTypeVideo及MediaTypeFile
+	Filename string    `json:"filename"` // 文件名，标识文件展示的名称
+	Url      string    `json:"url"`      // 文件cdn url，url要求支持Range分块下载
+	Md5      string    `json:"md5"`      // 文件md5，对比从url下载下来的文件md5是否一致
+}
+
+// UploadByURLResult 异步上传任务的结果
+type UploadByURLResult struct {
+	Status int `json:"status"` // 任务状态，取值见UploadStatus常量
+	Detail struct {
+		ErrCode   int    `json:"errcode"`
+		ErrMsg    string `json:"errmsg"`
+		MediaId   string `json:"media_id"`
+		CreatedAt string `json:"created_at"`
+	} `json:"detail"`
+}
+
+// Err 任务异常失败时的错误，结果中没有错误码时返回ErrUploadByURLFailed
+func (r UploadByURLResult) Err() error {
+	if r.Status != UploadStatusFailed {
+		return nil
+	}
+	if r.Detail.ErrCode == 0 {
+		if r.Detail.ErrMsg != "" {
+			return fmt.Errorf("%w: %s", ErrUploadByURLFailed, r.Detail.ErrMsg)
+		}
+		return ErrUploadByURLFailed
+	}
+	return wechatgo.NewWXMsgError(r.Detail.ErrCode, r.Detail.ErrMsg)
+}
+
+// UploadByURLJob 异步上传任务
+type UploadByURLJob struct {
+	JobId     string    // 任务id，最长为128字节，60分钟内有效
+	MediaType MediaType // 媒体文件类型
+
+	media WechatMedia
+}
+
+// UploadByURL 异步上传临时素材，返回的任务可以通过Wait等待上传完成
+//
+// 适用于企业微信服务器从url下载不超过200MB的视频或文件，生成的media_id仅三天内有效
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/96219
+func (w WechatMedia) UploadByURL(ctx context.Context, option UploadByURLOption) (*UploadByURLJob, error) {
+	if option.Scene == 0 {
+		option.Scene = UploadSceneWelcome
+	}
+	if option.Type != MediaTypeVideo && option.Type != MediaTypeFile {
+		return nil, &MediaValidationError{Type: option.Type, Filename: option.Filename, Reason: "only video and file can be uploaded by url"}
+	}
+
+	resp := struct {
+		JobId string `json:"jobid"`
+	}{}
+	if err := w.Client.Post(ctx, urlUploadByURL, nil, option, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &UploadByURLJob{JobId: resp.JobId, MediaType: option.Type, media: w}, nil
+}
+
+// UploadByURLJob 根据任务id获取异步上传任务，用于在其他进程中查询任务结果
+func (w WechatMedia) UploadByURLJob(jobId string, mediaType MediaType) *UploadByURLJob {
+	return &UploadByURLJob{JobId: jobId, MediaType: mediaType, media: w}
+}
+
+// Result 查询任务的结果
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/96219
+func (j *UploadByURLJob) Result(ctx context.Context) (*UploadByURLResult, error) {
+	data := map[string]string{"jobid": j.JobId}
+	result := UploadByURLResult{}
+	err := j.media.Client.Post(ctx, urlGetUploadByURLResult, nil, data, nil, &result)
+	return &result, err
+}
+
+type WaitOptionFn func(option *waitOption)
+
+type waitOption struct {
+	backoff func(attempt int) time.Duration
+}
+
+// WaitWithBackoff 两次查询之间的等待时间，attempt为已查询的次数，默认从1秒开始每次翻倍，最长30秒
+func WaitWithBackoff(backoff func(attempt int) time.Duration) WaitOptionFn {
+	return func(option *waitOption) {
+		option.backoff = backoff
+	}
+}
+
+func defaultWaitBackoff(attempt int) time.Duration {
+	const max = time.Second * 30
+	if attempt > 5 {
+		return max
+	}
+	return time.Second << uint(attempt-1)
+}
+
+// Wait 轮询任务的结果直到完成，任务异常失败时返回UploadByURLResult.Err()，ctx被取消时返回ctx.Err()
+func (j *UploadByURLJob) Wait(ctx context.Context, options ...WaitOptionFn) (*MediaInfo, error) {
+	option := waitOption{backoff: defaultWaitBackoff}
+	for _, opt := range options {
+		opt(&option)
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := j.Result(ctx)
+		if err != nil {
+			return &MediaInfo{}, err
+		}
+
+		switch result.Status {
+		case UploadStatusCompleted:
+			return &MediaInfo{Type: string(j.MediaType), MediaId: result.Detail.MediaId, CreatedAt: result.Detail.CreatedAt}, nil
+		case UploadStatusFailed:
+			return &MediaInfo{}, result.Err()
+		}
+
+		timer := time.NewTimer(option.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &MediaInfo{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
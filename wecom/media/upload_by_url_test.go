@@ -0,0 +1,89 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo"
+)
+
+type uploadByURLTestSuite struct {
+	mockTestSuite
+}
+
+func noBackoff(int) time.Duration {
+	return time.Millisecond
+}
+
+func (s *uploadByURLTestSuite) TestShouldUploadAndWaitForResult() {
+	s.transport.RegisterPost(urlUploadByURL, `{"errcode":0,"errmsg":"ok","jobid":"job1"}`)
+	s.transport.RegisterPost(urlGetUploadByURLResult,
+		`{"errcode":0,"errmsg":"ok","status":1}`,
+		`{"errcode":0,"errmsg":"ok","status":2,"detail":{"errcode":0,"errmsg":"ok","media_id":"MEDIA1","created_at":"1380000000"}}`)
+
+	job, err := s.media.UploadByURL(context.Background(), UploadByURLOption{
+		Type: MediaTypeVideo, Filename: "video.mp4", Url: "https://cdn.example.com/video.mp4", Md5: "md5sum",
+	})
+	s.Require().NoError(err)
+	s.Equal("job1", job.JobId)
+	s.Equal(map[string]any{
+		"scene": float64(1), "type": "video", "filename": "video.mp4", "url": "https://cdn.example.com/video.mp4", "md5": "md5sum",
+	}, s.transport.Requests()[0])
+
+	info, err := job.Wait(context.Background(), WaitWithBackoff(noBackoff))
+	s.Require().NoError(err)
+	s.Equal(MediaInfo{Type: "video", MediaId: "MEDIA1", CreatedAt: "1380000000"}, *info)
+	s.Len(s.transport.Requests(), 3)
+	s.Equal(map[string]any{"jobid": "job1"}, s.transport.Requests()[2])
+}
+
+func (s *uploadByURLTestSuite) TestShouldReturnJobError() {
+	s.transport.RegisterPost(urlGetUploadByURLResult,
+		`{"errcode":0,"errmsg":"ok","status":3,"detail":{"errcode":830001,"errmsg":"url download failed"}}`)
+
+	_, err := s.media.UploadByURLJob("job1", MediaTypeFile).Wait(context.Background(), WaitWithBackoff(noBackoff))
+	var wxErr *wechatgo.WechatMessageError
+	s.Require().True(errors.As(err, &wxErr))
+	s.Equal(830001, wxErr.GetErrCode())
+}
+
+func (s *uploadByURLTestSuite) TestShouldReturnErrorWhenFailedWithoutDetail() {
+	s.Nil(UploadByURLResult{Status: UploadStatusCompleted}.Err())
+	s.True(errors.Is(UploadByURLResult{Status: UploadStatusFailed}.Err(), ErrUploadByURLFailed))
+
+	result := UploadByURLResult{Status: UploadStatusFailed}
+	result.Detail.ErrMsg = "unknown"
+	err := result.Err()
+	s.True(errors.Is(err, ErrUploadByURLFailed))
+	s.Contains(err.Error(), "unknown")
+}
+
+func (s *uploadByURLTestSuite) TestShouldStopWaitingWhenContextIsCanceled() {
+	s.transport.RegisterPost(urlGetUploadByURLResult, `{"errcode":0,"errmsg":"ok","status":1}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*30)
+	defer cancel()
+	_, err := s.media.UploadByURLJob("job1", MediaTypeFile).Wait(ctx, WaitWithBackoff(func(int) time.Duration { return time.Hour }))
+	s.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func (s *uploadByURLTestSuite) TestShouldRejectUnsupportedType() {
+	_, err := s.media.UploadByURL(context.Background(), UploadByURLOption{Type: MediaTypeImage, Filename: "a.png"})
+	var validationErr *MediaValidationError
+	s.True(errors.As(err, &validationErr))
+	s.Empty(s.transport.Requests())
+}
+
+func (s *uploadByURLTestSuite) TestDefaultBackoff() {
+	s.Equal(time.Second, defaultWaitBackoff(1))
+	s.Equal(time.Second*16, defaultWaitBackoff(5))
+	s.Equal(time.Second*30, defaultWaitBackoff(6))
+}
+
+func TestUploadByURL(t *testing.T) {
+	suite.Run(t, new(uploadByURLTestSuite))
+}
@@ -0,0 +1,202 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/testdata"
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+type uploadedFile struct {
+	mediaType     string
+	query         url.Values
+	contentLength int64
+	filename      string
+	content       []byte
+}
+
+type mockTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	media     *WechatMedia
+	uploads   []uploadedFile
+}
+
+func (s *mockTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+	s.uploads = nil
+
+	conf := testdata.TestConf
+	client := wecom.NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+	s.media = NewWechatMedia(client)
+}
+
+// 解析上传的文件，记录后返回bodies中的下一个响应
+func (s *mockTestSuite) registerUploadResponder(url string, bodies ...string) {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+url, func(req *http.Request) (*http.Response, error) {
+		content, err := io.ReadAll(req.Body)
+		s.Require().NoError(err)
+		if req.ContentLength >= 0 {
+			s.EqualValues(len(content), req.ContentLength)
+		}
+
+		_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		s.Require().NoError(err)
+		part, err := multipart.NewReader(bytes.NewReader(content), params["boundary"]).NextPart()
+		s.Require().NoError(err)
+		s.Equal("media", part.FormName())
+		data, err := io.ReadAll(part)
+		s.Require().NoError(err)
+
+		s.uploads = append(s.uploads, uploadedFile{mediaType: req.URL.Query().Get("type"), query: req.URL.Query(), contentLength: req.ContentLength, filename: part.FileName(), content: data})
+		body := bodies[0]
+		if len(bodies) > 1 {
+			bodies = bodies[1:]
+		}
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+}
+
+type uploadTestSuite struct {
+	mockTestSuite
+}
+
+func (s *uploadTestSuite) TestShouldStreamFileWithContentLength() {
+	s.registerUploadResponder(urlUploadMedia, `{"errcode":0,"errmsg":"ok","type":"image","media_id":"MEDIA1","created_at":"1380000000"}`)
+
+	file, err := os.Open("test_file.jpg")
+	s.Require().NoError(err)
+	defer file.Close()
+	expected, err := os.ReadFile("test_file.jpg")
+	s.Require().NoError(err)
+
+	info, err := s.media.UploadMedia(context.Background(), "test.jpg", "image", file)
+	s.Require().NoError(err)
+	s.Equal("MEDIA1", info.MediaId)
+
+	s.Require().Len(s.uploads, 1)
+	s.Equal("test.jpg", s.uploads[0].filename)
+	s.Equal(expected, s.uploads[0].content)
+	s.Greater(s.uploads[0].contentLength, int64(len(expected)))
+}
+
+func (s *uploadTestSuite) TestShouldUseChunkedTransferWhenSizeIsUnknown() {
+	s.registerUploadResponder(urlUploadPerpetualImg, `{"errcode":0,"errmsg":"ok","url":"http://p.qpic.cn/img"}`)
+
+	url, err := s.media.UploadPerpetualImg(context.Background(), "a.png", io.MultiReader(strings.NewReader("png-data")))
+	s.Require().NoError(err)
+	s.Equal("http://p.qpic.cn/img", url)
+	s.Require().Len(s.uploads, 1)
+	s.EqualValues(-1, s.uploads[0].contentLength)
+	s.Equal("png-data", string(s.uploads[0].content))
+}
+
+func (s *uploadTestSuite) TestShouldReopenSeekerWhenTokenExpired() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":42001,"errmsg":"access_token expired"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA2"}`)
+
+	reader := strings.NewReader("skip:payload")
+	_, err := reader.Seek(5, io.SeekStart)
+	s.Require().NoError(err)
+
+	info, err := s.media.UploadMedia(context.Background(), "a.txt", "file", reader)
+	s.Require().NoError(err)
+	s.Equal("MEDIA2", info.MediaId)
+	s.Require().Len(s.uploads, 2)
+	s.Equal("payload", string(s.uploads[0].content))
+	s.Equal("payload", string(s.uploads[1].content))
+	s.Equal(2, s.transport.GetCallCountInfo()["GET "+mock.BaseURL+"/cgi-bin/gettoken"])
+}
+
+func (s *uploadTestSuite) TestShouldStopPreviousBodyBeforeReopening() {
+	source, err := readerSource("a.txt", strings.NewReader("payload"))
+	s.Require().NoError(err)
+	body := newMultipartBody("media", source)
+
+	first, err := body.Open()
+	s.Require().NoError(err)
+	second, err := body.Open()
+	s.Require().NoError(err)
+	defer second.Close()
+
+	_, err = first.Read(make([]byte, 1))
+	s.True(errors.Is(err, io.ErrClosedPipe), "previous body is closed when reopening")
+	content, err := io.ReadAll(second)
+	s.Require().NoError(err)
+	s.Contains(string(content), "payload")
+	s.NoError(first.Close())
+}
+
+func (s *uploadTestSuite) TestShouldReopenWithOpener() {
+	s.registerUploadResponder(urlUploadMedia,
+		`{"errcode":40014,"errmsg":"invalid access_token"}`,
+		`{"errcode":0,"errmsg":"ok","type":"file","media_id":"MEDIA3"}`)
+
+	opened := 0
+	info, err := s.media.UploadMediaFromOpener(context.Background(), "a.txt", "file", 7, func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(strings.NewReader("content")), nil
+	})
+	s.Require().NoError(err)
+	s.Equal("MEDIA3", info.MediaId)
+	s.Equal(3, opened, "opened once for sniffing and once per attempt")
+}
+
+func (s *uploadTestSuite) TestShouldReturnOriginalErrorWhenNotReopenable() {
+	s.registerUploadResponder(urlUploadMedia, `{"errcode":42001,"errmsg":"access_token expired"}`)
+
+	_, err := s.media.UploadMedia(context.Background(), "a.txt", "file", io.MultiReader(strings.NewReader("content")))
+	s.Require().Error(err)
+	s.False(errors.Is(err, ErrNotReopenable))
+	s.Contains(err.Error(), "42001")
+	s.Len(s.uploads, 1)
+}
+
+func (s *uploadTestSuite) TestShouldNotRetryWhenValidationFailsWhileUploading() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlUploadMedia, func(req *http.Request) (*http.Response, error) {
+		_, err := io.ReadAll(req.Body)
+		return nil, err
+	})
+
+	opened := 0
+	_, err := s.media.UploadMediaFromOpener(context.Background(), "a.amr", "voice", -1, func() (io.ReadCloser, error) {
+		opened++
+		return io.NopCloser(io.MultiReader(strings.NewReader("#!AMR\n"), bytes.NewReader(make([]byte, 3*1024*1024)))), nil
+	})
+
+	var validationErr *MediaValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	s.Equal(2, opened, "opened once for sniffing and once for the only attempt")
+}
+
+func (s *uploadTestSuite) TestShouldFailWhenContentLengthMismatch() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlUploadMedia, func(req *http.Request) (*http.Response, error) {
+		_, err := io.ReadAll(req.Body)
+		return nil, err
+	})
+
+	_, err := s.media.UploadMediaFromOpener(context.Background(), "a.txt", "file", 10, func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("short")), nil
+	})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "content length mismatch")
+}
+
+func TestUpload(t *testing.T) {
+	suite.Run(t, new(uploadTestSuite))
+}
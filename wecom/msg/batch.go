@@ -0,0 +1,204 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// 单次发送的接收者数量上限
+const (
+	MaxUsersPerSend   = 1000
+	MaxPartiesPerSend = 100
+	MaxTagsPerSend    = 100
+)
+
+// BatchResult 单个批次的发送结果
+type BatchResult struct {
+	Recipients Recipients  // 本批次的接收者
+	Result     *SendResult // 本批次的发送结果
+	Err        error       // 本批次的错误
+}
+
+// BatchError 分批发送时部分或全部批次发送失败
+type BatchError struct {
+	Total  int           // 总批次数
+	Failed []BatchResult // 发送失败的批次
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d batches failed, first error: %s", len(e.Failed), e.Total, e.Failed[0].Err)
+}
+
+// Unwrap 返回第一个失败批次的错误
+func (e *BatchError) Unwrap() error {
+	return e.Failed[0].Err
+}
+
+// SplitRecipients 按照单次发送的接收者数量上限拆分接收者
+//
+// 成员、部门、标签分别按上限分段，第i个批次包含各自的第i段
+func SplitRecipients(to Recipients) []Recipients {
+	if to.All {
+		return []Recipients{to}
+	}
+
+	count := maxInt(
+		chunkCount(len(to.Users), MaxUsersPerSend),
+		chunkCount(len(to.Parties), MaxPartiesPerSend),
+		chunkCount(len(to.Tags), MaxTagsPerSend),
+	)
+	if count <= 1 {
+		return []Recipients{to}
+	}
+
+	batches := make([]Recipients, count)
+	for i := range batches {
+		batches[i] = Recipients{
+			Users:   chunk(to.Users, i, MaxUsersPerSend),
+			Parties: chunk(to.Parties, i, MaxPartiesPerSend),
+			Tags:    chunk(to.Tags, i, MaxTagsPerSend),
+		}
+	}
+	return batches
+}
+
+func chunkCount(length, size int) int {
+	return (length + size - 1) / size
+}
+
+func chunk[T any](items []T, index, size int) []T {
+	start := index * size
+	if start >= len(items) {
+		return nil
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+func maxInt(values ...int) int {
+	max := 0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+// 发送所有批次，concurrency为最大并发数
+func (w WechatMsg) sendBatches(ctx context.Context, batches []Recipients, message Message, option sendOption) []BatchResult {
+	results := make([]BatchResult, len(batches))
+	concurrency := option.concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for i, batch := range batches {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, batch Recipients) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = w.sendBatch(ctx, batch, message, option)
+		}(i, batch)
+	}
+	wg.Wait()
+	return results
+}
+
+func (w WechatMsg) sendBatch(ctx context.Context, to Recipients, message Message, option sendOption) BatchResult {
+	if w.limiter != nil {
+		if err := w.limiter.Wait(ctx); err != nil {
+			return BatchResult{Recipients: to, Result: &SendResult{}, Err: err}
+		}
+	}
+
+	resp, err := w.send(ctx, w.newSendPayload(to, message, option))
+	return BatchResult{Recipients: to, Result: resp.result(), Err: err}
+}
+
+// SendProgress 拆分及分批发送的进度，发送失败后从该进度继续，不重复发送已成功的部分
+//
+// 拆分后的各部分依次发送，某一部分存在失败的批次时不再发送后续部分
+type SendProgress struct {
+	Part    int         // 下一次发送的部分，从0开始
+	Pending *Recipients // 尚未收到该部分的接收者，为nil时为全部接收者
+}
+
+// Started 是否已有部分内容发送成功
+func (p SendProgress) Started() bool {
+	return p.Part > 0 || p.Pending != nil
+}
+
+func failedBatches(batches []BatchResult) []BatchResult {
+	var failed []BatchResult
+	for _, batch := range batches {
+		if batch.Err != nil {
+			failed = append(failed, batch)
+		}
+	}
+	return failed
+}
+
+// 合并所有批次的接收者
+func batchRecipients(batches []BatchResult) Recipients {
+	merged := Recipients{}
+	for _, batch := range batches {
+		merged.Users = append(merged.Users, batch.Recipients.Users...)
+		merged.Parties = append(merged.Parties, batch.Recipients.Parties...)
+		merged.Tags = append(merged.Tags, batch.Recipients.Tags...)
+	}
+	return merged
+}
+
+// 合并所有批次的发送结果
+func mergeBatchResults(batches []BatchResult) (*SendResult, error) {
+	if len(batches) == 1 {
+		result := *batches[0].Result
+		if result.MsgId != "" {
+			result.MsgIds = []string{result.MsgId}
+		}
+		result.Batches = batches
+		return &result, batches[0].Err
+	}
+
+	merged := &SendResult{ErrMsg: "ok", Batches: batches}
+	batchErr := &BatchError{Total: len(batches)}
+	for _, batch := range batches {
+		result := batch.Result
+		if batch.Err != nil {
+			batchErr.Failed = append(batchErr.Failed, batch)
+			if len(batchErr.Failed) == 1 {
+				merged.ErrCode = result.ErrCode
+				merged.ErrMsg = batch.Err.Error()
+			}
+		}
+		if result.MsgId != "" {
+			merged.MsgIds = append(merged.MsgIds, result.MsgId)
+		}
+		if merged.ResponseCode == "" {
+			merged.ResponseCode = result.ResponseCode
+		}
+		merged.InvalidUser = append(merged.InvalidUser, result.InvalidUser...)
+		merged.InvalidParty = append(merged.InvalidParty, result.InvalidParty...)
+		merged.InvalidTag = append(merged.InvalidTag, result.InvalidTag...)
+		merged.UnlicensedUser = append(merged.UnlicensedUser, result.UnlicensedUser...)
+	}
+	if len(merged.MsgIds) > 0 {
+		merged.MsgId = merged.MsgIds[0]
+	}
+
+	if len(batchErr.Failed) > 0 {
+		return merged, batchErr
+	}
+	return merged, nil
+}
@@ -0,0 +1,119 @@
+package msg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+)
+
+type batchTestSuite struct {
+	mockTestSuite
+}
+
+func (s *batchTestSuite) users(n int) []string {
+	users := make([]string, n)
+	for i := range users {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+	return users
+}
+
+func (s *batchTestSuite) TestShouldNotSplitWithinLimits() {
+	to := NewRecipients(s.users(MaxUsersPerSend), make([]int, MaxPartiesPerSend), nil)
+
+	batches := SplitRecipients(to)
+
+	s.Len(batches, 1)
+}
+
+func (s *batchTestSuite) TestShouldSplitByLargestList() {
+	to := NewRecipients(s.users(1500), make([]int, 250), []int{1})
+
+	batches := SplitRecipients(to)
+
+	s.Require().Len(batches, 3)
+	s.Len(batches[0].Users, 1000)
+	s.Len(batches[1].Users, 500)
+	s.Empty(batches[2].Users)
+	s.Len(batches[0].Parties, 100)
+	s.Len(batches[2].Parties, 50)
+	s.Equal([]int{1}, batches[0].Tags)
+	s.Empty(batches[1].Tags)
+}
+
+func (s *batchTestSuite) TestShouldNotSplitSendToAll() {
+	s.Len(SplitRecipients(Recipients{All: true, Users: s.users(2000)}), 1)
+}
+
+func (s *batchTestSuite) TestShouldMergeBatchResults() {
+	count := 0
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend, func(req *http.Request) (*http.Response, error) {
+		count++
+		body := fmt.Sprintf(`{"errcode":0,"errmsg":"ok","msgid":"msgid%d","invaliduser":"bad%d"}`, count, count)
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+
+	result, err := s.msg.Send(context.Background(), ToUsers(s.users(2500)...), TextMsg{Content: "hello"}, SendWithConcurrency(1))
+
+	s.NoError(err)
+	s.Equal(3, count)
+	s.Equal([]string{"msgid1", "msgid2", "msgid3"}, result.MsgIds)
+	s.Equal("msgid1", result.MsgId)
+	s.Equal([]string{"bad1", "bad2", "bad3"}, result.InvalidUser)
+	s.Len(result.Batches, 3)
+}
+
+func (s *batchTestSuite) TestShouldReturnBatchErrorWhenBatchFailed() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend, func(req *http.Request) (*http.Response, error) {
+		body := `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`
+		content, err := io.ReadAll(req.Body)
+		s.NoError(err)
+		if strings.Contains(string(content), "user1000") {
+			body = `{"errcode":45009,"errmsg":"api freq out of limit"}`
+		}
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+
+	result, err := s.msg.Send(context.Background(), ToUsers(s.users(1500)...), TextMsg{Content: "hello"}, SendWithConcurrency(2))
+
+	var batchErr *BatchError
+	s.Require().True(errors.As(err, &batchErr))
+	s.Equal(2, batchErr.Total)
+	s.Len(batchErr.Failed, 1)
+	s.Equal(45009, result.ErrCode)
+	s.Equal([]string{"msgid1"}, result.MsgIds)
+
+	var msgErr *MsgError
+	s.True(errors.As(err, &msgErr))
+}
+
+func (s *batchTestSuite) TestShouldReturnBatchErrorFromLegacyMethod() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend, func(req *http.Request) (*http.Response, error) {
+		body := `{"errcode":0,"errmsg":"ok","msgid":"msgid1","invaliduser":"user0"}`
+		content, err := io.ReadAll(req.Body)
+		s.NoError(err)
+		if strings.Contains(string(content), "user1000") {
+			body = `{"errcode":45009,"errmsg":"api freq out of limit"}`
+		}
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+
+	err := s.msg.SendText(context.Background(), s.users(1500), nil, nil, TextMsg{Content: "hello"}, false)
+
+	var batchErr *BatchError
+	s.Require().True(errors.As(err, &batchErr))
+	s.Len(batchErr.Failed, 1)
+}
+
+func TestBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(batchTestSuite))
+}
@@ -0,0 +1,84 @@
+package msg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// 已注册的消息类型及其解码函数
+type messageDecoder struct {
+	typ    reflect.Type
+	decode func(data []byte) (Message, error)
+}
+
+var (
+	decoderMutex    = &sync.RWMutex{}
+	messageDecoders = map[string]messageDecoder{}
+)
+
+func init() {
+	RegisterMessageType[TextMsg]()
+	RegisterMessageType[ImageMsg]()
+	RegisterMessageType[VoiceMsg]()
+	RegisterMessageType[VideoMsg]()
+	RegisterMessageType[FileMsg]()
+	RegisterMessageType[TextCardMsg]()
+	RegisterMessageType[NewsMsg]()
+	RegisterMessageType[MPNewsMsg]()
+	RegisterMessageType[MarkdownMsg]()
+	RegisterMessageType[MiniProgramNoticeMsg]()
+	RegisterMessageType[TaskCardMsg]()
+	RegisterMessageType[TemplateCardMsg]()
+}
+
+// RegisterMessageType 注册消息类型，注册后DecodeMessage可以解码该类型的消息
+//
+// 消息类型以MsgType()区分，本包的消息类型已注册，自定义的消息类型需要持久化时需先注册。
+// MsgType()已被其他类型注册时返回错误，不会替换已注册的类型；重复注册同一类型不会出错。
+func RegisterMessageType[T Message]() error {
+	var zero T
+	typ := reflect.TypeOf(&zero).Elem()
+	decoderMutex.Lock()
+	defer decoderMutex.Unlock()
+	if registered, ok := messageDecoders[zero.MsgType()]; ok {
+		if registered.typ != typ {
+			return fmt.Errorf("msg: message type %q is already registered by %s", zero.MsgType(), registered.typ)
+		}
+		return nil
+	}
+
+	messageDecoders[zero.MsgType()] = messageDecoder{typ: typ, decode: func(data []byte) (Message, error) {
+		var message T
+		err := json.Unmarshal(data, &message)
+		return message, err
+	}}
+	return nil
+}
+
+// EncodedMessage 序列化后的消息，用于持久化
+type EncodedMessage struct {
+	MsgType string          `json:"msgtype"`
+	Content json.RawMessage `json:"content"`
+}
+
+// EncodeMessage 序列化消息
+func EncodeMessage(message Message) (EncodedMessage, error) {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return EncodedMessage{}, err
+	}
+	return EncodedMessage{MsgType: message.MsgType(), Content: content}, nil
+}
+
+// DecodeMessage 反序列化消息，消息类型未注册时返回错误
+func DecodeMessage(encoded EncodedMessage) (Message, error) {
+	decoderMutex.RLock()
+	decoder, ok := messageDecoders[encoded.MsgType]
+	decoderMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("msg: unregistered message type %q", encoded.MsgType)
+	}
+	return decoder.decode(encoded.Content)
+}
@@ -0,0 +1,188 @@
+package msg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const urlLinkedCorpSend = "/cgi-bin/linkedcorp/message/send"
+
+// 互联企业消息支持的消息类型
+var linkedCorpMsgTypes = map[string]bool{
+	"text":               true,
+	"image":              true,
+	"voice":              true,
+	"video":              true,
+	"file":               true,
+	"textcard":           true,
+	"news":               true,
+	"mpnews":             true,
+	"markdown":           true,
+	"miniprogram_notice": true,
+}
+
+// LinkedUser 互联企业成员，格式为CorpId/UserId
+func LinkedUser(corpId, userId string) string {
+	return corpId + "/" + userId
+}
+
+// LinkedDepartment 互联企业部门，格式为LinkedId/DepartmentId
+func LinkedDepartment(linkedId string, departmentId int) string {
+	return linkedId + "/" + strconv.Itoa(departmentId)
+}
+
+// ParseLinkedId 解析CorpId/UserId或LinkedId/DepartmentId格式的id，
+// 本企业的id（不包含“/”）返回的prefix为空
+func ParseLinkedId(id string) (prefix, localId string) {
+	if i := strings.LastIndexByte(id, '/'); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return "", id
+}
+
+// LinkedRecipients 互联企业消息的接收者，Users、Parties、Tags及All不能同时为空
+type LinkedRecipients struct {
+	Users   []string // 成员ID列表，本企业成员直接使用userid，互联企业成员使用LinkedUser，最多支持1000个
+	Parties []string // 部门ID列表，本企业部门直接使用部门id，互联企业部门使用LinkedDepartment，最多支持100个
+	Tags    []string // 本企业的标签ID列表，最多支持100个
+	All     bool     // 发送给应用可见范围内的所有人（包括互联企业的成员），为true时忽略其他字段
+}
+
+// IsEmpty 是否未指定任何接收者
+func (r LinkedRecipients) IsEmpty() bool {
+	return !r.All && len(r.Users) == 0 && len(r.Parties) == 0 && len(r.Tags) == 0
+}
+
+// LinkedSendResult 互联企业消息的发送结果
+type LinkedSendResult struct {
+	ErrCode      int      `json:"errcode"`      // 返回码
+	ErrMsg       string   `json:"errmsg"`       // 对返回码的文本描述内容
+	InvalidUser  []string `json:"invaliduser"`  // 不合法的成员，格式与请求中的相同
+	InvalidParty []string `json:"invalidparty"` // 不合法的部门，格式与请求中的相同
+	InvalidTag   []string `json:"invalidtag"`   // 不合法的标签
+	Sent         int      `json:"-"`            // 已成功发送的消息条数，拆分为多条发送时部分发送失败也会大于0
+}
+
+// HasInvalid 是否存在不合法的接收者
+func (r LinkedSendResult) HasInvalid() bool {
+	return len(r.InvalidUser) > 0 || len(r.InvalidParty) > 0 || len(r.InvalidTag) > 0
+}
+
+// InvalidLinkedUsers 不合法的互联企业成员，按CorpId分组，本企业成员的CorpId为空
+func (r LinkedSendResult) InvalidLinkedUsers() map[string][]string {
+	return groupLinkedIds(r.InvalidUser)
+}
+
+// InvalidLinkedParties 不合法的互联企业部门，按LinkedId分组，本企业部门的LinkedId为空
+func (r LinkedSendResult) InvalidLinkedParties() map[string][]string {
+	return groupLinkedIds(r.InvalidParty)
+}
+
+func groupLinkedIds(ids []string) map[string][]string {
+	if len(ids) == 0 {
+		return nil
+	}
+	groups := map[string][]string{}
+	for _, id := range ids {
+		prefix, localId := ParseLinkedId(id)
+		groups[prefix] = append(groups[prefix], localId)
+	}
+	return groups
+}
+
+func (r LinkedSendResult) GetErrCode() int {
+	return r.ErrCode
+}
+
+func (r LinkedSendResult) GetErrMsg() string {
+	return r.ErrMsg
+}
+
+func (r LinkedSendResult) Error() string {
+	return fmt.Sprintf("errcode=%d, errmsg='%s'", r.ErrCode, r.ErrMsg)
+}
+
+type linkedSendPayload struct {
+	ToUser  []string `json:"touser,omitempty"`
+	ToParty []string `json:"toparty,omitempty"`
+	ToTag   []string `json:"totag,omitempty"`
+	ToAll   int      `json:"toall,omitempty"`
+	MsgType string   `json:"msgtype"`
+	AgentId int      `json:"agentid"`
+	Safe    int      `json:"safe,omitempty"`
+
+	message Message
+}
+
+func (p linkedSendPayload) MarshalJSON() ([]byte, error) {
+	type plain linkedSendPayload
+	return marshalWithMessage(plain(p), p.message)
+}
+
+// SendLinkedCorp 发送互联企业消息
+//
+// 消息类型与应用消息相同，支持文本、图片、语音、视频、文件、文本卡片、图文、图文（mpnews）、
+// markdown及小程序通知消息。支持SendWithAgentId、SendWithSafe以及截断、拆分、校验相关的选项。
+//
+// 部分接收者不合法时不返回错误，不合法的接收者列表通过LinkedSendResult返回。
+//
+// 通过SendWithSplit拆分为多条消息依次发送时，某条消息发送失败后不再发送后续的消息，返回错误及已合并的结果：
+// 之前的消息已送达，已成功发送的条数为LinkedSendResult.Sent，ErrCode、ErrMsg为失败的消息的返回码。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90250
+func (w WechatMsg) SendLinkedCorp(ctx context.Context, to LinkedRecipients, message Message, options ...SendOption) (*LinkedSendResult, error) {
+	if msgType := message.MsgType(); !linkedCorpMsgTypes[msgType] {
+		return &LinkedSendResult{}, fmt.Errorf("linkedcorp: unsupported message type %q", msgType)
+	}
+
+	option := w.newSendOption(options...)
+	messages, err := w.prepareMessages(message, option)
+	if err != nil {
+		return &LinkedSendResult{}, err
+	}
+
+	merged := &LinkedSendResult{ErrMsg: "ok"}
+	for _, message := range messages {
+		payload := linkedSendPayload{
+			MsgType: message.MsgType(),
+			AgentId: option.agentId,
+			Safe:    option.safe,
+			message: message,
+		}
+		if to.All {
+			payload.ToAll = 1
+		} else {
+			payload.ToUser, payload.ToParty, payload.ToTag = to.Users, to.Parties, to.Tags
+		}
+
+		result := LinkedSendResult{}
+		if err = w.Client.Post(ctx, urlLinkedCorpSend, nil, payload, &result, nil); err != nil {
+			merged.ErrCode, merged.ErrMsg = result.ErrCode, result.ErrMsg
+			return merged, err
+		}
+		merged.Sent++
+		// 拆分发送时每条消息返回相同的不合法接收者，合并时去重
+		merged.InvalidUser = appendUnique(merged.InvalidUser, result.InvalidUser...)
+		merged.InvalidParty = appendUnique(merged.InvalidParty, result.InvalidParty...)
+		merged.InvalidTag = appendUnique(merged.InvalidTag, result.InvalidTag...)
+	}
+	return merged, nil
+}
+
+func appendUnique(items []string, values ...string) []string {
+	for _, v := range values {
+		exists := false
+		for _, item := range items {
+			if item == v {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			items = append(items, v)
+		}
+	}
+	return items
+}
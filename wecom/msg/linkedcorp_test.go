@@ -0,0 +1,92 @@
+package msg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+)
+
+type linkedCorpTestSuite struct {
+	mockTestSuite
+}
+
+func (s *linkedCorpTestSuite) TestShouldSendWithLinkedRecipients() {
+	s.transport.RegisterPost(urlLinkedCorpSend, `{"errcode":0,"errmsg":"ok",`+
+		`"invaliduser":["userid1","wwcorp1/userid2"],"invalidparty":["wh0001/2"],"invalidtag":["3"]}`)
+
+	result, err := s.msg.SendLinkedCorp(context.Background(), LinkedRecipients{
+		Users:   []string{"userid1", LinkedUser("wwcorp1", "userid2")},
+		Parties: []string{"1", LinkedDepartment("wh0001", 2)},
+		Tags:    []string{"3"},
+	}, TextMsg{Content: "hello"}, SendWithAgentId(1000003), SendWithSafe(1))
+
+	s.Require().NoError(err)
+	s.Equal(map[string]any{
+		"touser":  []any{"userid1", "wwcorp1/userid2"},
+		"toparty": []any{"1", "wh0001/2"},
+		"totag":   []any{"3"},
+		"msgtype": "text",
+		"agentid": float64(1000003),
+		"safe":    float64(1),
+		"text":    map[string]any{"content": "hello"},
+	}, s.transport.Requests()[0])
+	s.True(result.HasInvalid())
+	s.Equal(map[string][]string{"": {"userid1"}, "wwcorp1": {"userid2"}}, result.InvalidLinkedUsers())
+	s.Equal(map[string][]string{"wh0001": {"2"}}, result.InvalidLinkedParties())
+	s.Equal([]string{"3"}, result.InvalidTag)
+}
+
+func (s *linkedCorpTestSuite) TestShouldSendToAll() {
+	s.transport.RegisterPost(urlLinkedCorpSend, `{"errcode":0,"errmsg":"ok"}`)
+
+	result, err := s.msg.SendLinkedCorp(context.Background(), LinkedRecipients{All: true, Users: []string{"ignored"}},
+		MarkdownMsg{Content: "**hello**"})
+
+	s.Require().NoError(err)
+	s.False(result.HasInvalid())
+	s.Equal(float64(1), s.transport.Requests()[0]["toall"])
+	s.NotContains(s.transport.Requests()[0], "touser")
+}
+
+func (s *linkedCorpTestSuite) TestShouldReturnApiError() {
+	s.transport.RegisterPost(urlLinkedCorpSend, `{"errcode":81013,"errmsg":"user & party & tag all invalid"}`)
+
+	result, err := s.msg.SendLinkedCorp(context.Background(), LinkedRecipients{Users: []string{"x/y"}}, TextMsg{Content: "hello"})
+
+	var resultErr *LinkedSendResult
+	s.Require().True(errors.As(err, &resultErr))
+	s.Equal(81013, result.ErrCode)
+}
+
+func (s *linkedCorpTestSuite) TestShouldReturnMergedResultWhenSplitPartFailed() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlLinkedCorpSend,
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok","invaliduser":["x/z"]}`).
+			Then(httpmock.NewStringResponder(http.StatusOK, `{"errcode":45009,"errmsg":"api freq out of limit"}`)))
+
+	result, err := s.msg.SendLinkedCorp(context.Background(), LinkedRecipients{Users: []string{"x/y", "x/z"}},
+		TextMsg{Content: strings.Repeat("a", MaxTextBytes*2+1)}, SendWithSplit())
+
+	s.Require().Error(err)
+	s.Equal(1, result.Sent)
+	s.Equal(45009, result.ErrCode)
+	s.Equal([]string{"x/z"}, result.InvalidUser)
+	s.Equal(2, s.transport.GetCallCountInfo()["POST "+mock.BaseURL+urlLinkedCorpSend])
+}
+
+func (s *linkedCorpTestSuite) TestShouldRejectUnsupportedMessage() {
+	_, err := s.msg.SendLinkedCorp(context.Background(), LinkedRecipients{All: true}, TemplateCardMsg{})
+
+	s.Error(err)
+	s.Empty(s.transport.Requests())
+}
+
+func TestLinkedCorpTestSuite(t *testing.T) {
+	suite.Run(t, new(linkedCorpTestSuite))
+}
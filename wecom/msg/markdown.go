@@ -0,0 +1,132 @@
+package msg
+
+import (
+	"strings"
+)
+
+// FontColor markdown消息支持的字体颜色
+type FontColor string
+
+const (
+	FontColorInfo    FontColor = "info"    // 绿色
+	FontColorComment FontColor = "comment" // 灰色
+	FontColorWarning FontColor = "warning" // 橙红色
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `&lt;`,
+)
+
+// EscapeMarkdown 转义文本中的markdown标记，使其按原样显示
+func EscapeMarkdown(s string) string {
+	s = markdownEscaper.Replace(s)
+	if strings.HasPrefix(s, "#") || strings.HasPrefix(s, ">") {
+		s = `\` + s
+	}
+	return s
+}
+
+// MarkdownBuilder 企业微信markdown消息构造器
+//
+// 仅生成企业微信支持的markdown子集：标题、加粗、链接、行内代码、引用、字体颜色。
+// 所有传入的文本都会被转义，原样显示。
+type MarkdownBuilder struct {
+	builder strings.Builder
+}
+
+func NewMarkdownBuilder() *MarkdownBuilder {
+	return &MarkdownBuilder{}
+}
+
+// 开始新的块级元素，当前行非空时先换行
+func (b *MarkdownBuilder) block() {
+	content := b.builder.String()
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		b.builder.WriteString("\n")
+	}
+}
+
+// Heading 标题，level取值为1~6
+func (b *MarkdownBuilder) Heading(level int, text string) *MarkdownBuilder {
+	if level < 1 {
+		level = 1
+	} else if level > 6 {
+		level = 6
+	}
+
+	b.block()
+	b.builder.WriteString(strings.Repeat("#", level) + " " + EscapeMarkdown(text) + "\n")
+	return b
+}
+
+// Quote 引用，多行文本的每一行都会作为引用
+func (b *MarkdownBuilder) Quote(text string) *MarkdownBuilder {
+	b.block()
+	for _, line := range strings.Split(text, "\n") {
+		b.builder.WriteString("> " + EscapeMarkdown(line) + "\n")
+	}
+	return b
+}
+
+// Text 普通文本
+func (b *MarkdownBuilder) Text(text string) *MarkdownBuilder {
+	b.builder.WriteString(EscapeMarkdown(text))
+	return b
+}
+
+// Bold 加粗
+func (b *MarkdownBuilder) Bold(text string) *MarkdownBuilder {
+	b.builder.WriteString("**" + EscapeMarkdown(text) + "**")
+	return b
+}
+
+// Link 链接
+func (b *MarkdownBuilder) Link(text, url string) *MarkdownBuilder {
+	url = strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(url)
+	b.builder.WriteString("[" + EscapeMarkdown(text) + "](" + url + ")")
+	return b
+}
+
+// Code 行内代码
+func (b *MarkdownBuilder) Code(text string) *MarkdownBuilder {
+	b.builder.WriteString("`" + strings.ReplaceAll(text, "`", "'") + "`")
+	return b
+}
+
+// Color 指定颜色的文本
+func (b *MarkdownBuilder) Color(color FontColor, text string) *MarkdownBuilder {
+	b.builder.WriteString(`<font color="` + string(color) + `">` + EscapeMarkdown(text) + "</font>")
+	return b
+}
+
+// Newline 换行
+func (b *MarkdownBuilder) Newline() *MarkdownBuilder {
+	b.builder.WriteString("\n")
+	return b
+}
+
+// Raw 追加未经转义的内容，调用方需保证内容为企业微信支持的markdown
+func (b *MarkdownBuilder) Raw(content string) *MarkdownBuilder {
+	b.builder.WriteString(content)
+	return b
+}
+
+// Len 当前内容的字节数
+func (b *MarkdownBuilder) Len() int {
+	return b.builder.Len()
+}
+
+func (b *MarkdownBuilder) String() string {
+	return strings.TrimRight(b.builder.String(), "\n")
+}
+
+// Message 生成markdown消息
+func (b *MarkdownBuilder) Message() MarkdownMsg {
+	return MarkdownMsg{Content: b.String()}
+}
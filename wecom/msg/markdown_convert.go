@@ -0,0 +1,252 @@
+package msg
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	reFence         = regexp.MustCompile("^\\s{0,3}(```+|~~~+)")
+	reHeading       = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	reSetextH1      = regexp.MustCompile(`^\s{0,3}=+\s*$`)
+	reSetextH2      = regexp.MustCompile(`^\s{0,3}-+\s*$`)
+	reThematic      = regexp.MustCompile(`^\s{0,3}(-(\s*-){2,}|\*(\s*\*){2,}|_(\s*_){2,})\s*$`)
+	reQuote         = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	reBullet        = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	reOrdered       = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	reTask          = regexp.MustCompile(`^\[([ xX])\]\s+`)
+	reTableSep      = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	reLinkDef       = regexp.MustCompile(`^\s{0,3}\[([^\]]+)\]:\s*<?(\S+?)>?(\s+.*)?$`)
+	reCodeSpan      = regexp.MustCompile("`+[^`]*`+")
+	reImage         = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	reLink          = regexp.MustCompile(`\[([^\]]+)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	reRefLink       = regexp.MustCompile(`!?\[([^\]]+)\]\[([^\]]*)\]`)
+	reAutoLink      = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	reBoldStar      = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	reBoldUnder     = regexp.MustCompile(`__(.+?)__`)
+	reItalicStar    = regexp.MustCompile(`\*([^*\s][^*]*?)\*`)
+	reItalicUnder   = regexp.MustCompile(`(^|[^\w\\])_([^_\s][^_]*?)_($|[^\w])`)
+	reStrike        = regexp.MustCompile(`~~(.+?)~~`)
+	reHTMLBreak     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	reHTMLTag       = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	reFontTag       = regexp.MustCompile(`(?i)^(<font\s+color="(info|comment|warning)"\s*>|</font>)$`)
+	boldPlaceholder = "\x00"
+)
+
+// ConvertCommonMark 将CommonMark格式的markdown降级为企业微信支持的markdown子集
+//
+// 标题、加粗、链接、行内代码、引用及<font color>保持不变；表格转换为列表，图片转换为链接，
+// 代码块转换为引用，斜体、删除线及其他HTML标签只保留文本。转换结果超过2048字节时，
+// 在行边界截断并以省略号结尾。
+func ConvertCommonMark(src string) string {
+	converter := newMarkdownConverter(src)
+	return truncateLines(converter.convert(), MaxTextBytes)
+}
+
+type markdownConverter struct {
+	lines []string
+	refs  map[string]string // 链接引用定义
+	out   []string
+}
+
+func newMarkdownConverter(src string) *markdownConverter {
+	src = strings.ReplaceAll(strings.ReplaceAll(src, "\r\n", "\n"), "\t", "    ")
+	c := &markdownConverter{refs: map[string]string{}}
+
+	inFence := false
+	for _, line := range strings.Split(src, "\n") {
+		if reFence.MatchString(line) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := reLinkDef.FindStringSubmatch(line); m != nil {
+				c.refs[strings.ToLower(m[1])] = m[2]
+				continue
+			}
+		}
+		c.lines = append(c.lines, line)
+	}
+	return c
+}
+
+func (c *markdownConverter) emit(line string) {
+	c.out = append(c.out, line)
+}
+
+func (c *markdownConverter) convert() string {
+	for i := 0; i < len(c.lines); i++ {
+		line := c.lines[i]
+		next := ""
+		if i+1 < len(c.lines) {
+			next = c.lines[i+1]
+		}
+
+		switch {
+		case reFence.MatchString(line):
+			i = c.convertCodeBlock(i)
+		case strings.Contains(line, "|") && reTableSep.MatchString(next) && strings.Contains(next, "-"):
+			i = c.convertTable(i)
+		case reHeading.MatchString(line):
+			m := reHeading.FindStringSubmatch(line)
+			c.emit(m[1] + " " + c.inline(m[2]))
+		case strings.TrimSpace(line) != "" && reSetextH1.MatchString(next):
+			c.emit("# " + c.inline(strings.TrimSpace(line)))
+			i++
+		case strings.TrimSpace(line) != "" && reSetextH2.MatchString(next) && !reBullet.MatchString(line):
+			c.emit("## " + c.inline(strings.TrimSpace(line)))
+			i++
+		case reThematic.MatchString(line):
+			c.emit("")
+		case reQuote.MatchString(line):
+			c.emit("> " + c.inline(reQuote.ReplaceAllString(line, "")))
+		case reBullet.MatchString(line):
+			m := reBullet.FindStringSubmatch(line)
+			c.emit(m[1] + "- " + c.inline(c.task(m[2])))
+		case reOrdered.MatchString(line):
+			m := reOrdered.FindStringSubmatch(line)
+			c.emit(m[1] + m[2] + ". " + c.inline(c.task(m[3])))
+		default:
+			c.emit(c.inline(strings.TrimRight(line, " ")))
+		}
+	}
+	return c.result()
+}
+
+// 合并连续的空行
+func (c *markdownConverter) result() string {
+	var lines []string
+	for _, line := range c.out {
+		if strings.TrimSpace(line) == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// 代码块转换为引用，返回代码块最后一行的位置
+func (c *markdownConverter) convertCodeBlock(start int) int {
+	fence := strings.TrimSpace(reFence.FindString(c.lines[start]))
+	i := start + 1
+	for ; i < len(c.lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(c.lines[i]), fence) {
+			break
+		}
+		c.emit("> " + EscapeMarkdown(c.lines[i]))
+	}
+	return i
+}
+
+// 表格转换为列表，每行转换为“表头: 单元格”形式的列表项，返回表格最后一行的位置
+func (c *markdownConverter) convertTable(start int) int {
+	headers := splitTableRow(c.lines[start])
+	i := start + 2
+	for ; i < len(c.lines); i++ {
+		line := c.lines[i]
+		if strings.TrimSpace(line) == "" || !strings.Contains(line, "|") {
+			break
+		}
+
+		var items []string
+		for j, cell := range splitTableRow(line) {
+			if cell == "" {
+				continue
+			}
+			if j < len(headers) && headers[j] != "" {
+				items = append(items, c.inline(headers[j])+": "+c.inline(cell))
+			} else {
+				items = append(items, c.inline(cell))
+			}
+		}
+		c.emit("- " + strings.Join(items, ", "))
+	}
+	return i - 1
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(strings.TrimSuffix(line, "|"), "|")
+
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+func (c *markdownConverter) task(text string) string {
+	if m := reTask.FindStringSubmatch(text); m != nil {
+		mark := "☐ "
+		if m[1] != " " {
+			mark = "☑ "
+		}
+		return mark + text[len(m[0]):]
+	}
+	return text
+}
+
+// 转换行内元素，行内代码保持不变
+func (c *markdownConverter) inline(text string) string {
+	var builder strings.Builder
+	last := 0
+	for _, loc := range reCodeSpan.FindAllStringIndex(text, -1) {
+		builder.WriteString(c.inlineText(text[last:loc[0]]))
+		builder.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	builder.WriteString(c.inlineText(text[last:]))
+	return builder.String()
+}
+
+func (c *markdownConverter) inlineText(text string) string {
+	text = reImage.ReplaceAllStringFunc(text, func(s string) string {
+		m := reImage.FindStringSubmatch(s)
+		alt := m[1]
+		if alt == "" {
+			alt = m[2]
+		}
+		return "[" + alt + "](" + m[2] + ")"
+	})
+	text = reLink.ReplaceAllString(text, "[$1]($2)")
+	text = reRefLink.ReplaceAllStringFunc(text, func(s string) string {
+		m := reRefLink.FindStringSubmatch(s)
+		ref := m[2]
+		if ref == "" {
+			ref = m[1]
+		}
+		if url, ok := c.refs[strings.ToLower(ref)]; ok {
+			return "[" + m[1] + "](" + url + ")"
+		}
+		return m[1]
+	})
+	text = reAutoLink.ReplaceAllString(text, "[$1]($1)")
+	text = reHTMLBreak.ReplaceAllString(text, " ")
+	text = reHTMLTag.ReplaceAllStringFunc(text, func(s string) string {
+		if reFontTag.MatchString(s) {
+			return s
+		}
+		return ""
+	})
+
+	// 加粗使用占位符保护，避免被当作斜体处理
+	text = reBoldUnder.ReplaceAllString(text, "**$1**")
+	text = reBoldStar.ReplaceAllString(text, boldPlaceholder+"$1"+boldPlaceholder)
+	text = reItalicStar.ReplaceAllString(text, "$1")
+	text = reItalicUnder.ReplaceAllString(text, "$1$2$3")
+	text = reStrike.ReplaceAllString(text, "$1")
+	return strings.ReplaceAll(text, boldPlaceholder, "**")
+}
+
+// 截断超长的内容，优先在行边界截断，并以省略号结尾
+func truncateLines(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+
+	suffix := "\n" + Ellipsis
+	cut := cutBytes(s, maxBytes-len(suffix))
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 {
+		return cut[:i] + suffix
+	}
+	return TruncateBytes(s, maxBytes)
+}
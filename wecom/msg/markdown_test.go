@@ -0,0 +1,73 @@
+package msg
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type markdownTestSuite struct {
+	suite.Suite
+}
+
+func (s *markdownTestSuite) TestShouldEscapeText() {
+	s.Equal(`\# 1 \*a\* \_b\_ \[c\](d) &lt;font>`, EscapeMarkdown("# 1 *a* _b_ [c](d) <font>"))
+}
+
+func (s *markdownTestSuite) TestShouldBuildMarkdown() {
+	content := NewMarkdownBuilder().
+		Heading(2, "告警 *P1*").
+		Text("服务: ").Bold("api").Newline().
+		Text("状态: ").Color(FontColorWarning, "firing").Newline().
+		Quote("第一行\n第二行").
+		Link("详情", "https://example.com/a b").Text(" ").Code("a`b").
+		String()
+
+	s.Equal("## 告警 \\*P1\\*\n"+
+		"服务: **api**\n"+
+		`状态: <font color="warning">firing</font>`+"\n"+
+		"> 第一行\n> 第二行\n"+
+		"[详情](https://example.com/a%20b) `a'b`", content)
+}
+
+func (s *markdownTestSuite) TestShouldConvertBlocks() {
+	src := "Title\n=====\n\n" +
+		"Some *italic* and **bold** and __strong__ ~~gone~~ text.\n\n" +
+		"---\n\n" +
+		"```go\nfmt.Println(\"*x*\")\n```\n\n" +
+		"| Name | Value |\n|------|:-----:|\n| cpu | 90% |\n| mem | 80% |\n\n" +
+		"- [x] done\n- [ ] todo\n\n" +
+		"> > nested quote"
+
+	s.Equal("# Title\n\n"+
+		"Some italic and **bold** and **strong** gone text.\n\n"+
+		"> fmt.Println(\"\\*x\\*\")\n\n"+
+		"- Name: cpu, Value: 90%\n- Name: mem, Value: 80%\n\n"+
+		"- ☑ done\n- ☐ todo\n\n"+
+		"> nested quote", ConvertCommonMark(src))
+}
+
+func (s *markdownTestSuite) TestShouldConvertInlines() {
+	src := `![logo](https://example.com/a.png "Logo") [site](https://example.com "title") ` +
+		`<https://example.com/x> [ref][r] <b>html</b> <font color="info">ok</font> ` + "`*code*`\n\n" +
+		"[r]: https://example.com/ref"
+
+	s.Equal(`[logo](https://example.com/a.png) [site](https://example.com) `+
+		`[https://example.com/x](https://example.com/x) [ref](https://example.com/ref) html <font color="info">ok</font> `+"`*code*`",
+		ConvertCommonMark(src))
+}
+
+func (s *markdownTestSuite) TestShouldTruncateConvertedContent() {
+	content := ConvertCommonMark(strings.Repeat("- 列表项内容\n", 300))
+
+	s.LessOrEqual(len(content), MaxTextBytes)
+	s.True(utf8.ValidString(content))
+	s.True(strings.HasSuffix(content, "\n"+Ellipsis))
+	s.NoError(Validate(MarkdownMsg{Content: content}))
+}
+
+func TestMarkdownTestSuite(t *testing.T) {
+	suite.Run(t, new(markdownTestSuite))
+}
@@ -5,11 +5,13 @@ import (
 	"context"
 	"strings"
 
+	"github.com/huimingz/wechatgo"
 	"github.com/huimingz/wechatgo/wecom"
 )
 
 const (
-	urlSend = "/cgi-bin/message/send"
+	urlSend   = "/cgi-bin/message/send"
+	urlRecall = "/cgi-bin/message/recall"
 )
 
 type sendData struct {
@@ -43,24 +45,44 @@ type TextMsg struct {
 	Content string `json:"content"` // 消息内容，最长不超过2048个字节，超过将截断
 }
 
+func (TextMsg) MsgType() string {
+	return "text"
+}
+
 type ImageMsg struct {
 	MediaId string `json:"media_id"` // 图片媒体文件id，可以调用上传临时素材接口获取
 }
 
+func (ImageMsg) MsgType() string {
+	return "image"
+}
+
 type VoiceMsg struct {
 	MediaId string `json:"media_id"` // 语音文件id，可以调用上传临时素材接口获取
 }
 
+func (VoiceMsg) MsgType() string {
+	return "voice"
+}
+
 type VideoMsg struct {
 	MediaId     string `json:"media_id"`              // 视频媒体文件id，可以调用上传临时素材接口获取
 	Title       string `json:"title,omitempty"`       // 视频消息的标题，不超过128个字节，超过会自动截断
 	Description string `json:"description,omitempty"` // 视频消息的描述，不超过512个字节，超过会自动截断
 }
 
+func (VideoMsg) MsgType() string {
+	return "video"
+}
+
 type FileMsg struct {
 	MediaId string `json:"media_id"` // 文件id，可以调用上传临时素材接口获取
 }
 
+func (FileMsg) MsgType() string {
+	return "file"
+}
+
 type TextCardMsg struct {
 	Title       string `json:"title"`             // 标题，不超过128个字节，超过会自动截断
 	Description string `json:"description"`       // 描述，不超过512个字节，超过会自动截断
@@ -68,6 +90,10 @@ type TextCardMsg struct {
 	BtnText     string `json:"btntext,omitempty"` // 按钮文字。 默认为“详情”， 不超过4个文字，超过自动截断
 }
 
+func (TextCardMsg) MsgType() string {
+	return "textcard"
+}
+
 type Article struct {
 	Title       string `json:"title"`                 // 标题，不超过128个字节，超过会自动截断
 	Description string `json:"description,omitempty"` // 描述，不超过512个字节，超过会自动截断
@@ -79,6 +105,10 @@ type NewsMsg struct {
 	Articles []Article `json:"articles"` // 图文消息，一个图文消息支持1到8条图文
 }
 
+func (NewsMsg) MsgType() string {
+	return "news"
+}
+
 type MPArticle struct {
 	// 标题，不超过128个字节，超过会自动截断
 	Title string `json:"title"`
@@ -104,10 +134,18 @@ type MPNewsMsg struct {
 	Articles []MPArticle `json:"articles"` // 图文消息，一个图文消息支持1到8条图文
 }
 
+func (MPNewsMsg) MsgType() string {
+	return "mpnews"
+}
+
 type MarkdownMsg struct {
 	Content string `json:"content"` // markdown内容，最长不超过2048个字节，必须是utf8编码
 }
 
+func (MarkdownMsg) MsgType() string {
+	return "markdown"
+}
+
 type NoticeContentItem struct {
 	Key   string `json:"key"`   // 长度10个汉字以内
 	Value string `json:"value"` // 长度30个汉字以内
@@ -134,6 +172,10 @@ type MiniProgramNoticeMsg struct {
 	ContentItem []NoticeContentItem `json:"content_item,omitempty"`
 }
 
+func (MiniProgramNoticeMsg) MsgType() string {
+	return "miniprogram_notice"
+}
+
 type TaskCardBtn struct {
 	// 按钮key值，用户点击后，会产生任务卡片回调事件，回调事件会带上该key值，
 	// 只能由数字、字母和“_-@.”组成，最长支持128字节
@@ -170,24 +212,38 @@ type TaskCardMsg struct {
 	Btn []TaskCardBtn `json:"btn"`
 }
 
+func (TaskCardMsg) MsgType() string {
+	return "taskcard"
+}
+
 type WechatMsg struct {
-	Client *wecom.Client
+	Client  *wecom.Client
+	sentLog SentLog              // 已发送消息记录
+	limiter wechatgo.RateLimiter // 发送消息的限流器
 }
 
-func NewWechatMsg(client *wecom.Client) *WechatMsg {
-	return &WechatMsg{client}
+type WechatMsgOptionFn func(w *WechatMsg)
+
+// WechatMsgWithSentLog 设置已发送消息记录，用于通过业务key撤回消息
+func WechatMsgWithSentLog(sentLog SentLog) WechatMsgOptionFn {
+	return func(w *WechatMsg) {
+		w.sentLog = sentLog
+	}
 }
 
-// 发送消息
-func (w WechatMsg) send(ctx context.Context, data interface{}) error {
-	errmsg := MsgError{}
-	err := w.Client.Post(ctx, urlSend, nil, data, &errmsg, nil)
+// WechatMsgWithRateLimiter 设置限流器，每次调用发送接口前等待限流器放行
+func WechatMsgWithRateLimiter(limiter wechatgo.RateLimiter) WechatMsgOptionFn {
+	return func(w *WechatMsg) {
+		w.limiter = limiter
+	}
+}
 
-	if errmsg.InvalidUser != "" || errmsg.InvalidTag != "" || errmsg.InvalidParty != "" {
-		return &errmsg
-	} else {
-		return err
+func NewWechatMsg(client *wecom.Client, options ...WechatMsgOptionFn) *WechatMsg {
+	w := &WechatMsg{Client: client}
+	for _, opt := range options {
+		opt(w)
 	}
+	return w
 }
 
 // 文本消息
@@ -199,115 +255,49 @@ func (w WechatMsg) send(ctx context.Context, data interface{}) error {
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E6%96%87%E6%9C%AC%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendText(ctx context.Context, toUser []string, toParty, toTag []int, text TextMsg, safe bool) error {
-	data := struct {
-		sendData
-		Text TextMsg `json:"text"`
-		Safe int     `json:"safe,omitempty"`
-	}{}
-	data.Init(toUser, toParty, toTag, "text", w.Client.AgentId)
-	data.Text = text
-	if safe {
-		data.Safe = 1
-	} else {
-		data.Safe = 0
-	}
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), text, SendWithSafe(safeValue(safe)))
 }
 
 // 图片消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E5%9B%BE%E7%89%87%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendImage(ctx context.Context, toUser []string, toParty, toTag []int, image ImageMsg, safe bool) error {
-	data := struct {
-		sendData
-		Image ImageMsg `json:"image"`
-		Safe  int      `json:"safe,omitempty"`
-	}{}
-	data.Init(toUser, toParty, toTag, "image", w.Client.AgentId)
-	data.Image = image
-	if safe {
-		data.Safe = 1
-	} else {
-		data.Safe = 0
-	}
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), image, SendWithSafe(safeValue(safe)))
 }
 
 // 语音消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E8%AF%AD%E9%9F%B3%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendVoice(ctx context.Context, toUser []string, toParty, toTag []int, voice VoiceMsg) error {
-	data := struct {
-		sendData
-		Voice VoiceMsg `json:"voice"`
-	}{}
-	data.Init(toUser, toParty, toTag, "voice", w.Client.AgentId)
-	data.Voice = voice
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), voice)
 }
 
 // 视频消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E8%A7%86%E9%A2%91%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendVideo(ctx context.Context, toUser []string, toParty, toTag []int, video VideoMsg, safe bool) error {
-	data := struct {
-		sendData
-		Video VideoMsg `json:"video"`
-		Safe  int      `json:"safe,omitempty"`
-	}{}
-	data.Init(toUser, toParty, toTag, "video", w.Client.AgentId)
-	data.Video = video
-	if safe {
-		data.Safe = 1
-	} else {
-		data.Safe = 0
-	}
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), video, SendWithSafe(safeValue(safe)))
 }
 
 // 文件消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E6%96%87%E4%BB%B6%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendFile(ctx context.Context, toUser []string, toParty, toTag []int, file FileMsg, safe bool) error {
-	data := struct {
-		sendData
-		File FileMsg `json:"file"`
-		Safe int     `json:"safe,omitempty"`
-	}{}
-	data.Init(toUser, toParty, toTag, "file", w.Client.AgentId)
-	data.File = file
-	if safe {
-		data.Safe = 1
-	} else {
-		data.Safe = 0
-	}
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), file, SendWithSafe(safeValue(safe)))
 }
 
 // 文本卡片消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E6%96%87%E6%9C%AC%E5%8D%A1%E7%89%87%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendTextCard(ctx context.Context, toUser []string, toParty, toTag []int, textCard TextCardMsg) error {
-	data := struct {
-		sendData
-		TextCard TextCardMsg `json:"textcard"`
-	}{}
-	data.Init(toUser, toParty, toTag, "textcard", w.Client.AgentId)
-	data.TextCard = textCard
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), textCard)
 }
 
 // 图文消息
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E5%9B%BE%E6%96%87%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendNews(ctx context.Context, toUser []string, toParty, toTag []int, news NewsMsg) error {
-	data := struct {
-		sendData
-		News NewsMsg `json:"news"`
-	}{}
-	data.Init(toUser, toParty, toTag, "news", w.Client.AgentId)
-	data.News = news
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), news)
 }
 
 // 图文消息（mpnews）
@@ -317,24 +307,7 @@ func (w WechatMsg) SendNews(ctx context.Context, toUser []string, toParty, toTag
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E5%9B%BE%E6%96%87%E6%B6%88%E6%81%AF%EF%BC%88mpnews%EF%BC%89
 func (w WechatMsg) SendMPNews(ctx context.Context, toUser []string, toParty, toTag []int, mpNews MPNewsMsg, safe bool) error {
-	data := struct {
-		sendData
-		MPNews MPNewsMsg `json:"mpnews"`
-
-		// 表示是否是保密消息，0表示可对外分享，1表示不能分享且内容显示水印，
-		// 2表示仅限在企业内分享，默认为0；
-		//
-		// 注意仅mpnews类型的消息支持safe值为2，其他消息类型不支持
-		Safe int `json:"safe,omitempty"`
-	}{}
-	data.Init(toUser, toParty, toTag, "mpnews", w.Client.AgentId)
-	data.MPNews = mpNews
-	if safe {
-		data.Safe = 1
-	} else {
-		data.Safe = 0
-	}
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), mpNews, SendWithSafe(safeValue(safe)))
 }
 
 // markdown消息
@@ -344,13 +317,7 @@ func (w WechatMsg) SendMPNews(ctx context.Context, toUser []string, toParty, toT
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/markdown%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendMarkdown(ctx context.Context, toUser []string, toParty, toTag []int, md MarkdownMsg) error {
-	data := struct {
-		sendData
-		Markdown MarkdownMsg `json:"markdown"`
-	}{}
-	data.Init(toUser, toParty, toTag, "markdown", w.Client.AgentId)
-	data.Markdown = md
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), md)
 }
 
 // 小程序通知消息
@@ -361,13 +328,7 @@ func (w WechatMsg) SendMarkdown(ctx context.Context, toUser []string, toParty, t
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E5%B0%8F%E7%A8%8B%E5%BA%8F%E9%80%9A%E7%9F%A5%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendMiniProgramNotice(ctx context.Context, toUser []string, toParty, toTag []int, mpn MiniProgramNoticeMsg) error {
-	data := struct {
-		sendData
-		MiniProgramNotice MiniProgramNoticeMsg `json:"miniprogram_notice"`
-	}{}
-	data.Init(toUser, toParty, toTag, "miniprogram_notice", w.Client.AgentId)
-	data.MiniProgramNotice = mpn
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), mpn)
 }
 
 // SendTaskCard 任务卡片消息
@@ -376,11 +337,5 @@ func (w WechatMsg) SendMiniProgramNotice(ctx context.Context, toUser []string, t
 //
 // 参考文档：https://work.weixin.qq.com/api/doc#90000/90135/90236/%E4%BB%BB%E5%8A%A1%E5%8D%A1%E7%89%87%E6%B6%88%E6%81%AF
 func (w WechatMsg) SendTaskCard(ctx context.Context, toUser []string, toParty, toTag []int, taskCard TaskCardMsg) error {
-	data := struct {
-		sendData
-		TaskCard TaskCardMsg `json:"taskcard"`
-	}{}
-	data.Init(toUser, toParty, toTag, "taskcard", w.Client.AgentId)
-	data.TaskCard = taskCard
-	return w.send(ctx, data)
+	return w.sendLegacy(ctx, NewRecipients(toUser, toParty, toTag), taskCard)
 }
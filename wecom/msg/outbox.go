@@ -0,0 +1,500 @@
+package msg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+)
+
+// 发件箱的默认配置
+const (
+	DefaultOutboxConcurrency  = 4
+	DefaultOutboxMaxAttempts  = 5
+	DefaultOutboxPollInterval = time.Second
+)
+
+// 可重试的错误码
+var retryableErrCodes = map[int]bool{
+	-1:    true, // 系统繁忙
+	6000:  true, // 数据版本冲突
+	40014: true, // 不合法的access_token
+	42001: true, // access_token已过期
+	45009: true, // 接口调用超过限制
+	45033: true, // 接口并发调用超过限制
+}
+
+// IsRetryableError 发送失败的错误是否可以重试
+//
+// 网络错误、系统繁忙、频率限制及access_token失效等临时错误可以重试；
+// 消息内容校验失败及其他企业微信返回的错误不可重试。
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return false
+	}
+	var msgErr wechatgo.WechatMsgInterface
+	if errors.As(err, &msgErr) {
+		return retryableErrCodes[msgErr.GetErrCode()]
+	}
+	return true
+}
+
+// ExponentialBackoff 指数退避，第n次重试前等待base*2^(n-1)，最长不超过max
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// QueuedMessage 发件箱中的消息
+type QueuedMessage struct {
+	Id            string       // 消息在发件箱中的id
+	To            Recipients   // 接收者
+	Message       Message      // 消息内容
+	Progress      SendProgress // 发送进度，重试时从失败的部分继续，只发送给未收到该部分的接收者
+	Attempts      int          // 已尝试发送的次数
+	NextAttemptAt time.Time    // 下次尝试发送的时间
+	LastError     string       // 最近一次发送失败的错误
+	EnqueuedAt    time.Time    // 加入发件箱的时间
+
+	option sendOption
+}
+
+// 可持久化的发送选项
+type storedOption struct {
+	AgentId                int    `json:"agentid"`
+	Safe                   int    `json:"safe,omitempty"`
+	EnableIdTrans          bool   `json:"enable_id_trans,omitempty"`
+	EnableDuplicateCheck   bool   `json:"enable_duplicate_check,omitempty"`
+	DuplicateCheckInterval int    `json:"duplicate_check_interval,omitempty"`
+	Key                    string `json:"key,omitempty"`
+	Concurrency            int    `json:"concurrency,omitempty"`
+	Truncate               bool   `json:"truncate,omitempty"`
+	Split                  bool   `json:"split,omitempty"`
+	SkipValidation         bool   `json:"skip_validation,omitempty"`
+}
+
+func newStoredOption(o sendOption) storedOption {
+	return storedOption{
+		AgentId:                o.agentId,
+		Safe:                   o.safe,
+		EnableIdTrans:          o.enableIdTrans,
+		EnableDuplicateCheck:   o.enableDuplicateCheck,
+		DuplicateCheckInterval: o.duplicateCheckInterval,
+		Key:                    o.key,
+		Concurrency:            o.concurrency,
+		Truncate:               o.truncate,
+		Split:                  o.split,
+		SkipValidation:         o.skipValidation,
+	}
+}
+
+func (o storedOption) sendOption() sendOption {
+	return sendOption{
+		agentId:                o.AgentId,
+		safe:                   o.Safe,
+		enableIdTrans:          o.EnableIdTrans,
+		enableDuplicateCheck:   o.EnableDuplicateCheck,
+		duplicateCheckInterval: o.DuplicateCheckInterval,
+		key:                    o.Key,
+		concurrency:            o.Concurrency,
+		truncate:               o.Truncate,
+		split:                  o.Split,
+		skipValidation:         o.SkipValidation,
+	}
+}
+
+// 可持久化的发送进度
+type storedProgress struct {
+	Part    int               `json:"part,omitempty"`
+	Pending *storedRecipients `json:"pending,omitempty"`
+}
+
+type storedRecipients struct {
+	Users   []string `json:"users,omitempty"`
+	Parties []int    `json:"parties,omitempty"`
+	Tags    []int    `json:"tags,omitempty"`
+}
+
+func newStoredProgress(p SendProgress) storedProgress {
+	progress := storedProgress{Part: p.Part}
+	if p.Pending != nil {
+		progress.Pending = &storedRecipients{Users: p.Pending.Users, Parties: p.Pending.Parties, Tags: p.Pending.Tags}
+	}
+	return progress
+}
+
+func (p storedProgress) sendProgress() SendProgress {
+	progress := SendProgress{Part: p.Part}
+	if p.Pending != nil {
+		progress.Pending = &Recipients{Users: p.Pending.Users, Parties: p.Pending.Parties, Tags: p.Pending.Tags}
+	}
+	return progress
+}
+
+type queuedMessageJSON struct {
+	Id            string         `json:"id"`
+	Users         []string       `json:"users,omitempty"`
+	Parties       []int          `json:"parties,omitempty"`
+	Tags          []int          `json:"tags,omitempty"`
+	All           bool           `json:"all,omitempty"`
+	Message       EncodedMessage `json:"message"`
+	Option        storedOption   `json:"option"`
+	Progress      storedProgress `json:"progress"`
+	Attempts      int            `json:"attempts"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	LastError     string         `json:"last_error,omitempty"`
+	EnqueuedAt    time.Time      `json:"enqueued_at"`
+}
+
+func (m QueuedMessage) MarshalJSON() ([]byte, error) {
+	encoded, err := EncodeMessage(m.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(queuedMessageJSON{
+		Id:            m.Id,
+		Users:         m.To.Users,
+		Parties:       m.To.Parties,
+		Tags:          m.To.Tags,
+		All:           m.To.All,
+		Message:       encoded,
+		Option:        newStoredOption(m.option),
+		Progress:      newStoredProgress(m.Progress),
+		Attempts:      m.Attempts,
+		NextAttemptAt: m.NextAttemptAt,
+		LastError:     m.LastError,
+		EnqueuedAt:    m.EnqueuedAt,
+	})
+}
+
+func (m *QueuedMessage) UnmarshalJSON(data []byte) error {
+	v := queuedMessageJSON{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	message, err := DecodeMessage(v.Message)
+	if err != nil {
+		return err
+	}
+
+	*m = QueuedMessage{
+		Id:            v.Id,
+		To:            Recipients{Users: v.Users, Parties: v.Parties, Tags: v.Tags, All: v.All},
+		Message:       message,
+		Progress:      v.Progress.sendProgress(),
+		Attempts:      v.Attempts,
+		NextAttemptAt: v.NextAttemptAt,
+		LastError:     v.LastError,
+		EnqueuedAt:    v.EnqueuedAt,
+		option:        v.Option.sendOption(),
+	}
+	return nil
+}
+
+// OutboxStatus 消息的投递状态
+type OutboxStatus int
+
+const (
+	OutboxDelivered    OutboxStatus = iota // 发送成功
+	OutboxRetrying                         // 发送失败，等待重试
+	OutboxDeadLettered                     // 发送失败且不再重试，已移入死信队列
+)
+
+func (s OutboxStatus) String() string {
+	switch s {
+	case OutboxDelivered:
+		return "delivered"
+	case OutboxRetrying:
+		return "retrying"
+	case OutboxDeadLettered:
+		return "dead_lettered"
+	}
+	return "unknown"
+}
+
+// OutboxOutcome 一次投递的结果
+type OutboxOutcome struct {
+	Item   *QueuedMessage // 投递的消息
+	Status OutboxStatus   // 投递状态
+	Result *SendResult    // 发送结果
+	Err    error          // 发送失败的错误
+}
+
+type OutboxOptionFn func(outbox *Outbox)
+
+// OutboxWithStore 指定持久化存储，默认为内存存储
+func OutboxWithStore(store OutboxStore) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.store = store
+	}
+}
+
+// OutboxWithConcurrency 最大并发发送数，默认为4
+func OutboxWithConcurrency(concurrency int) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.concurrency = concurrency
+	}
+}
+
+// OutboxWithMaxAttempts 最多尝试发送的次数，超过后移入死信队列，默认为5
+func OutboxWithMaxAttempts(attempts int) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.maxAttempts = attempts
+	}
+}
+
+// OutboxWithBackoff 重试前的等待时间，attempt为已尝试的次数，默认为ExponentialBackoff(time.Second, time.Minute*5)
+func OutboxWithBackoff(backoff func(attempt int) time.Duration) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.backoff = backoff
+	}
+}
+
+// OutboxWithRetryable 判断错误是否可以重试，默认为IsRetryableError
+func OutboxWithRetryable(retryable func(err error) bool) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.retryable = retryable
+	}
+}
+
+// OutboxWithPollInterval 检查到期消息的时间间隔，默认为1秒
+func OutboxWithPollInterval(interval time.Duration) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.pollInterval = interval
+	}
+}
+
+// OutboxWithCallback 每次投递后的回调，在发送消息的goroutine中同步调用
+func OutboxWithCallback(callback func(ctx context.Context, outcome OutboxOutcome)) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.callback = callback
+	}
+}
+
+func OutboxWithLogger(logger wechatgo.Logger) OutboxOptionFn {
+	return func(outbox *Outbox) {
+		outbox.log = logger
+	}
+}
+
+// Outbox 发件箱，消息先持久化再由后台worker异步发送
+//
+// 发送失败时按照退避策略重试，不可重试的错误或超过最大尝试次数时移入死信队列。
+// 拆分或分批发送时部分失败，重试时从失败的部分继续，只发送给未收到该部分的接收者。
+type Outbox struct {
+	msg          *WechatMsg
+	store        OutboxStore
+	concurrency  int
+	maxAttempts  int
+	backoff      func(attempt int) time.Duration
+	retryable    func(err error) bool
+	pollInterval time.Duration
+	callback     func(ctx context.Context, outcome OutboxOutcome)
+	log          wechatgo.Logger
+
+	notify   chan struct{}
+	mutex    *sync.Mutex
+	inflight map[string]bool
+}
+
+func NewOutbox(msg *WechatMsg, options ...OutboxOptionFn) *Outbox {
+	outbox := &Outbox{
+		msg:      msg,
+		notify:   make(chan struct{}, 1),
+		mutex:    &sync.Mutex{},
+		inflight: map[string]bool{},
+	}
+
+	for _, opt := range options {
+		opt(outbox)
+	}
+
+	if outbox.store == nil {
+		outbox.store = NewMemoryOutboxStore()
+	}
+	if outbox.concurrency <= 0 {
+		outbox.concurrency = DefaultOutboxConcurrency
+	}
+	if outbox.maxAttempts <= 0 {
+		outbox.maxAttempts = DefaultOutboxMaxAttempts
+	}
+	if outbox.backoff == nil {
+		outbox.backoff = ExponentialBackoff(time.Second, time.Minute*5)
+	}
+	if outbox.retryable == nil {
+		outbox.retryable = IsRetryableError
+	}
+	if outbox.pollInterval <= 0 {
+		outbox.pollInterval = DefaultOutboxPollInterval
+	}
+	if outbox.log == nil {
+		outbox.log = wechatgo.DefaultLogger()
+	}
+	return outbox
+}
+
+// Enqueue 将消息加入发件箱，返回消息在发件箱中的id
+//
+// 加入前会校验消息内容，options与Send相同，保存后由Run启动的worker发送
+func (o *Outbox) Enqueue(ctx context.Context, to Recipients, message Message, options ...SendOption) (string, error) {
+	option := o.msg.newSendOption(options...)
+	if _, err := o.msg.prepareMessages(message, option); err != nil {
+		return "", err
+	}
+
+	id, err := newOutboxId()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	item := &QueuedMessage{
+		Id:            id,
+		To:            to,
+		Message:       message,
+		NextAttemptAt: now,
+		EnqueuedAt:    now,
+		option:        option,
+	}
+	if err = o.store.Save(ctx, item); err != nil {
+		return "", err
+	}
+
+	o.wakeup()
+	return id, nil
+}
+
+// DeadLetters 返回死信队列中的消息
+func (o *Outbox) DeadLetters(ctx context.Context) ([]*QueuedMessage, error) {
+	return o.store.DeadLetters(ctx)
+}
+
+// Run 启动worker发送到期的消息，阻塞直到ctx被取消，返回前等待正在发送的消息完成
+func (o *Outbox) Run(ctx context.Context) error {
+	sem := make(chan struct{}, o.concurrency)
+	wg := sync.WaitGroup{}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		o.dispatch(ctx, sem, &wg)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-o.notify:
+		}
+	}
+}
+
+func (o *Outbox) wakeup() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 将到期的消息分配给空闲的worker
+func (o *Outbox) dispatch(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup) {
+	items, err := o.store.Due(ctx, time.Now(), o.concurrency*2)
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("Outbox: failed to load due messages, Error: %s", err))
+		return
+	}
+
+	for _, item := range items {
+		o.mutex.Lock()
+		busy := o.inflight[item.Id]
+		o.mutex.Unlock()
+		if busy {
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			return
+		}
+
+		o.mutex.Lock()
+		o.inflight[item.Id] = true
+		o.mutex.Unlock()
+
+		wg.Add(1)
+		go func(item *QueuedMessage) {
+			defer func() {
+				o.mutex.Lock()
+				delete(o.inflight, item.Id)
+				o.mutex.Unlock()
+				<-sem
+				wg.Done()
+				o.wakeup()
+			}()
+
+			o.deliver(ctx, item)
+		}(item)
+	}
+}
+
+func (o *Outbox) deliver(ctx context.Context, item *QueuedMessage) {
+	result, progress, err := o.msg.sendWithProgress(ctx, item.To, item.Message, item.option, item.Progress)
+	if err != nil && ctx.Err() != nil {
+		// 停止时中断的发送不计入尝试次数，下次启动后重新发送
+		return
+	}
+
+	outcome := OutboxOutcome{Item: item, Result: result, Err: err}
+	item.Attempts++
+	item.Progress = progress
+	switch {
+	case err == nil:
+		outcome.Status = OutboxDelivered
+		err = o.store.Delete(ctx, item.Id)
+	case o.retryable(err) && item.Attempts < o.maxAttempts:
+		outcome.Status = OutboxRetrying
+		item.LastError = err.Error()
+		item.NextAttemptAt = time.Now().Add(o.backoff(item.Attempts))
+		err = o.store.Save(ctx, item)
+	default:
+		outcome.Status = OutboxDeadLettered
+		item.LastError = err.Error()
+		err = o.store.DeadLetter(ctx, item)
+	}
+	if err != nil {
+		o.log.Error(ctx, fmt.Sprintf("Outbox: failed to update message %s, Error: %s", item.Id, err))
+	}
+
+	if o.callback != nil {
+		o.callback(ctx, outcome)
+	}
+}
+
+func newOutboxId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
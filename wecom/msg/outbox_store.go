@@ -0,0 +1,262 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+)
+
+// OutboxStore 发件箱的持久化存储
+//
+// 实现需要保证并发安全，返回的QueuedMessage可以被调用方修改
+type OutboxStore interface {
+	// Save 保存或更新待发送的消息
+	Save(ctx context.Context, item *QueuedMessage) error
+
+	// Due 返回最多limit条NextAttemptAt不晚于now的消息，按NextAttemptAt升序排列
+	Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error)
+
+	// Delete 删除待发送的消息，消息不存在时不返回错误
+	Delete(ctx context.Context, id string) error
+
+	// DeadLetter 将消息从待发送队列移入死信队列
+	DeadLetter(ctx context.Context, item *QueuedMessage) error
+
+	// DeadLetters 返回死信队列中的所有消息
+	DeadLetters(ctx context.Context) ([]*QueuedMessage, error)
+}
+
+type memoryOutboxStore struct {
+	mutex *sync.Mutex
+	queue map[string]QueuedMessage
+	dead  map[string]QueuedMessage
+}
+
+// NewMemoryOutboxStore 内存存储，进程退出后消息丢失
+func NewMemoryOutboxStore() OutboxStore {
+	return &memoryOutboxStore{
+		mutex: &sync.Mutex{},
+		queue: map[string]QueuedMessage{},
+		dead:  map[string]QueuedMessage{},
+	}
+}
+
+func (s *memoryOutboxStore) Save(ctx context.Context, item *QueuedMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queue[item.Id] = *item
+	return nil
+}
+
+func (s *memoryOutboxStore) Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var items []*QueuedMessage
+	for _, item := range s.queue {
+		if !item.NextAttemptAt.After(now) {
+			item := item
+			items = append(items, &item)
+		}
+	}
+	return limitDue(items, limit), nil
+}
+
+func (s *memoryOutboxStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.queue, id)
+	return nil
+}
+
+func (s *memoryOutboxStore) DeadLetter(ctx context.Context, item *QueuedMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.queue, item.Id)
+	s.dead[item.Id] = *item
+	return nil
+}
+
+func (s *memoryOutboxStore) DeadLetters(ctx context.Context) ([]*QueuedMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items := make([]*QueuedMessage, 0, len(s.dead))
+	for _, item := range s.dead {
+		item := item
+		items = append(items, &item)
+	}
+	return limitDue(items, 0), nil
+}
+
+// 按NextAttemptAt升序排列并截取前limit条，limit不大于0时不截取
+func limitDue(items []*QueuedMessage, limit int) []*QueuedMessage {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].NextAttemptAt.Equal(items[j].NextAttemptAt) {
+			return items[i].EnqueuedAt.Before(items[j].EnqueuedAt)
+		}
+		return items[i].NextAttemptAt.Before(items[j].NextAttemptAt)
+	})
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items
+}
+
+type fileOutboxStore struct {
+	mutex    *sync.Mutex
+	queueDir string
+	deadDir  string
+}
+
+// NewFileOutboxStore 文件存储，每条消息保存为dir/queue下的一个JSON文件，死信保存在dir/dead下
+//
+// 仅适用于单个进程使用同一目录的场景
+func NewFileOutboxStore(dir string) (OutboxStore, error) {
+	s := &fileOutboxStore{
+		mutex:    &sync.Mutex{},
+		queueDir: filepath.Join(dir, "queue"),
+		deadDir:  filepath.Join(dir, "dead"),
+	}
+	for _, d := range []string{s.queueDir, s.deadDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *fileOutboxStore) Save(ctx context.Context, item *QueuedMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeJSONFile(s.queueDir, item.Id, item)
+}
+
+func (s *fileOutboxStore) Due(ctx context.Context, now time.Time, limit int) ([]*QueuedMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items, err := readJSONFiles[QueuedMessage](s.queueDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*QueuedMessage
+	for _, item := range items {
+		if !item.NextAttemptAt.After(now) {
+			due = append(due, item)
+		}
+	}
+	return limitDue(due, limit), nil
+}
+
+func (s *fileOutboxStore) Delete(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return removeJSONFile(s.queueDir, id)
+}
+
+func (s *fileOutboxStore) DeadLetter(ctx context.Context, item *QueuedMessage) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := writeJSONFile(s.deadDir, item.Id, item); err != nil {
+		return err
+	}
+	return removeJSONFile(s.queueDir, item.Id)
+}
+
+func (s *fileOutboxStore) DeadLetters(ctx context.Context) ([]*QueuedMessage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	items, err := readJSONFiles[QueuedMessage](s.deadDir)
+	if err != nil {
+		return nil, err
+	}
+	return limitDue(items, 0), nil
+}
+
+// 将v保存为dir/name.json，先写入临时文件再重命名，避免进程退出时留下不完整的文件
+func writeJSONFile(dir, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name+".json"))
+}
+
+// 删除dir/name.json，文件不存在时不返回错误
+func removeJSONFile(dir, name string) error {
+	err := os.Remove(filepath.Join(dir, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// 读取dir/name.json
+func readJSONFile(dir, name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 读取dir下的所有JSON文件
+//
+// 无法解析的文件（如写入时被截断）重命名为*.json.corrupt并记录警告后跳过，避免一个损坏的文件导致整个存储无法读取
+func readJSONFiles[T any](dir string) ([]*T, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*T
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		item := new(T)
+		if err = json.Unmarshal(data, item); err != nil {
+			path := filepath.Join(dir, entry.Name())
+			wechatgo.DefaultLogger().Warn(context.Background(), fmt.Sprintf("failed to decode %s, moved to %s.corrupt, Error: %s", path, path, err))
+			if err = os.Rename(path, path+".corrupt"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,297 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/mock"
+)
+
+type outboxTestSuite struct {
+	mockTestSuite
+	outcomes chan OutboxOutcome
+}
+
+func (s *outboxTestSuite) SetupTest() {
+	s.mockTestSuite.SetupTest()
+	s.outcomes = make(chan OutboxOutcome, 10)
+}
+
+func (s *outboxTestSuite) newOutbox(options ...OutboxOptionFn) *Outbox {
+	options = append([]OutboxOptionFn{
+		OutboxWithBackoff(func(int) time.Duration { return time.Millisecond }),
+		OutboxWithPollInterval(time.Millisecond * 5),
+		OutboxWithCallback(func(ctx context.Context, outcome OutboxOutcome) {
+			s.outcomes <- outcome
+		}),
+	}, options...)
+	return NewOutbox(s.msg, options...)
+}
+
+// 启动发件箱，测试结束时停止
+func (s *outboxTestSuite) run(outbox *Outbox) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		outbox.Run(ctx)
+		close(done)
+	}()
+	s.T().Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func (s *outboxTestSuite) nextOutcome() OutboxOutcome {
+	select {
+	case outcome := <-s.outcomes:
+		return outcome
+	case <-time.After(time.Second):
+		s.FailNow("timeout waiting for outbox outcome")
+	}
+	return OutboxOutcome{}
+}
+
+func (s *outboxTestSuite) TestShouldDeliverEnqueuedMessage() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+	store := NewMemoryOutboxStore()
+	outbox := s.newOutbox(OutboxWithStore(store))
+	s.run(outbox)
+
+	id, err := outbox.Enqueue(context.Background(), ToUsers("zhangsan"), TextMsg{Content: "hello"}, SendWithSafe(1))
+	s.Require().NoError(err)
+
+	outcome := s.nextOutcome()
+	s.Equal(OutboxDelivered, outcome.Status)
+	s.Equal(id, outcome.Item.Id)
+	s.Equal("msgid1", outcome.Result.MsgId)
+	s.Equal(float64(1), s.transport.Requests()[0]["safe"])
+
+	due, err := store.Due(context.Background(), time.Now(), 0)
+	s.NoError(err)
+	s.Empty(due)
+}
+
+func (s *outboxTestSuite) TestShouldRetryRetryableError() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend,
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":45009,"errmsg":"api freq out of limit"}`).
+			Then(httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)))
+	outbox := s.newOutbox()
+	s.run(outbox)
+
+	_, err := outbox.Enqueue(context.Background(), ToUsers("zhangsan"), TextMsg{Content: "hello"})
+	s.Require().NoError(err)
+
+	retrying := s.nextOutcome()
+	s.Equal(OutboxRetrying, retrying.Status)
+	s.Contains(retrying.Item.LastError, "45009")
+
+	delivered := s.nextOutcome()
+	s.Equal(OutboxDelivered, delivered.Status)
+	s.Equal(2, delivered.Item.Attempts)
+}
+
+func (s *outboxTestSuite) TestShouldDeadLetterPermanentError() {
+	s.transport.RegisterPost(urlSend, `{"errcode":81013,"errmsg":"user & party & tag all invalid"}`)
+	outbox := s.newOutbox()
+	s.run(outbox)
+
+	_, err := outbox.Enqueue(context.Background(), ToUsers("nobody"), TextMsg{Content: "hello"})
+	s.Require().NoError(err)
+
+	outcome := s.nextOutcome()
+	s.Equal(OutboxDeadLettered, outcome.Status)
+	s.Equal(1, outcome.Item.Attempts)
+
+	dead, err := outbox.DeadLetters(context.Background())
+	s.Require().NoError(err)
+	s.Len(dead, 1)
+	s.Equal(TextMsg{Content: "hello"}, dead[0].Message)
+}
+
+func (s *outboxTestSuite) TestShouldDeadLetterAfterMaxAttempts() {
+	s.transport.RegisterPost(urlSend, `{"errcode":-1,"errmsg":"system busy"}`)
+	outbox := s.newOutbox(OutboxWithMaxAttempts(2))
+	s.run(outbox)
+
+	_, err := outbox.Enqueue(context.Background(), ToUsers("zhangsan"), TextMsg{Content: "hello"})
+	s.Require().NoError(err)
+
+	s.Equal(OutboxRetrying, s.nextOutcome().Status)
+	s.Equal(OutboxDeadLettered, s.nextOutcome().Status)
+	s.Len(s.transport.Requests(), 2)
+}
+
+func (s *outboxTestSuite) TestShouldRejectInvalidMessageOnEnqueue() {
+	outbox := s.newOutbox()
+
+	_, err := outbox.Enqueue(context.Background(), ToUsers("zhangsan"), TextMsg{})
+
+	var validationErr *ValidationError
+	s.True(errors.As(err, &validationErr))
+}
+
+func (s *outboxTestSuite) TestShouldRetryFromFailedPart() {
+	s.transport.RegisterPost(urlSend,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`,
+		`{"errcode":45009,"errmsg":"api freq out of limit"}`,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid2"}`)
+	outbox := s.newOutbox()
+	s.run(outbox)
+
+	_, err := outbox.Enqueue(context.Background(), ToUsers("zhangsan"), TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}, SendWithSplit())
+	s.Require().NoError(err)
+
+	retrying := s.nextOutcome()
+	s.Equal(OutboxRetrying, retrying.Status)
+	s.Equal(SendProgress{Part: 1}, retrying.Item.Progress)
+	s.Equal(OutboxDelivered, s.nextOutcome().Status)
+
+	requests := s.transport.Requests()
+	s.Require().Len(requests, 3)
+	s.Equal(requests[1]["text"], requests[2]["text"])
+	s.Equal("zhangsan", requests[2]["touser"])
+}
+
+func (s *outboxTestSuite) TestShouldRetryFailedPartOnlyForFailedBatches() {
+	s.transport.RegisterPost(urlSend,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid2"}`,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid3"}`,
+		`{"errcode":45009,"errmsg":"api freq out of limit"}`,
+		`{"errcode":0,"errmsg":"ok","msgid":"msgid4"}`)
+	outbox := s.newOutbox()
+	s.run(outbox)
+
+	users := make([]string, MaxUsersPerSend+1)
+	for i := range users {
+		users[i] = fmt.Sprintf("u%d", i)
+	}
+	_, err := outbox.Enqueue(context.Background(), ToUsers(users...), TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}, SendWithSplit())
+	s.Require().NoError(err)
+
+	retrying := s.nextOutcome()
+	s.Equal(OutboxRetrying, retrying.Status)
+	s.Equal(SendProgress{Part: 1, Pending: &Recipients{Users: []string{"u1000"}}}, retrying.Item.Progress)
+	s.Equal(OutboxDelivered, s.nextOutcome().Status)
+
+	requests := s.transport.Requests()
+	s.Require().Len(requests, 5)
+	s.Equal(requests[3]["text"], requests[4]["text"])
+	s.Equal("u1000", requests[4]["touser"])
+}
+
+func (s *outboxTestSuite) TestShouldPersistToFileStore() {
+	dir := s.T().TempDir()
+	store, err := NewFileOutboxStore(dir)
+	s.Require().NoError(err)
+
+	item := &QueuedMessage{
+		Id:            "item1",
+		To:            NewRecipients([]string{"zhangsan"}, []int{1}, nil),
+		Message:       NewsMsg{Articles: []Article{{Title: "标题", Url: "https://example.com"}}},
+		Progress:      SendProgress{Part: 1, Pending: &Recipients{Users: []string{"zhangsan"}}},
+		NextAttemptAt: time.Now().Add(-time.Second).Truncate(time.Second),
+		option:        sendOption{agentId: 1000002, safe: 1, key: "alert-1"},
+	}
+	s.Require().NoError(store.Save(context.Background(), item))
+
+	reopened, err := NewFileOutboxStore(dir)
+	s.Require().NoError(err)
+	due, err := reopened.Due(context.Background(), time.Now(), 10)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.Equal(item.To, due[0].To)
+	s.Equal(item.Message, due[0].Message)
+	s.Equal(item.option, due[0].option)
+	s.Equal(item.Progress, due[0].Progress)
+	s.True(item.NextAttemptAt.Equal(due[0].NextAttemptAt))
+
+	s.Require().NoError(reopened.DeadLetter(context.Background(), due[0]))
+	due, err = reopened.Due(context.Background(), time.Now(), 10)
+	s.NoError(err)
+	s.Empty(due)
+	dead, err := reopened.DeadLetters(context.Background())
+	s.NoError(err)
+	s.Len(dead, 1)
+}
+
+func (s *outboxTestSuite) TestShouldSkipCorruptFileInFileStore() {
+	dir := s.T().TempDir()
+	store, err := NewFileOutboxStore(dir)
+	s.Require().NoError(err)
+	s.Require().NoError(store.Save(context.Background(), &QueuedMessage{Id: "item1", To: ToUsers("zhangsan"), Message: TextMsg{Content: "hello"}}))
+	corrupt := filepath.Join(dir, "queue", "item2.json")
+	s.Require().NoError(os.WriteFile(corrupt, []byte(`{"id":"item2","mess`), 0o644))
+
+	due, err := store.Due(context.Background(), time.Now(), 10)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.Equal("item1", due[0].Id)
+	s.FileExists(corrupt + ".corrupt")
+	s.NoFileExists(corrupt)
+}
+
+func (s *outboxTestSuite) TestShouldClassifyErrors() {
+	s.True(IsRetryableError(errors.New("connection reset")))
+	s.True(IsRetryableError(&MsgError{WechatMessageError: wechatgo.WechatMessageError{ErrCode: 45009}}))
+	s.False(IsRetryableError(&MsgError{WechatMessageError: wechatgo.WechatMessageError{ErrCode: 81013}}))
+	s.False(IsRetryableError(&ValidationError{MsgType: "text"}))
+
+	backoff := ExponentialBackoff(time.Second, time.Second*5)
+	s.Equal([]time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5},
+		[]time.Duration{backoff(1), backoff(2), backoff(3), backoff(4)})
+}
+
+func TestOutboxTestSuite(t *testing.T) {
+	suite.Run(t, new(outboxTestSuite))
+}
+
+func TestDecodeMessage(t *testing.T) {
+	encoded, err := EncodeMessage(MarkdownMsg{Content: "**hello**"})
+	if err != nil {
+		t.Fatalf("EncodeMessage() error = '%s'", err)
+	}
+	message, err := DecodeMessage(encoded)
+	if err != nil || message != (MarkdownMsg{Content: "**hello**"}) {
+		t.Errorf("DecodeMessage() = %v, error = '%v'", message, err)
+	}
+
+	if _, err = DecodeMessage(EncodedMessage{MsgType: "unknown"}); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("DecodeMessage() error = '%v', want unregistered message type", err)
+	}
+}
+
+type webhookTextMsg struct {
+	Content string `json:"content"`
+}
+
+func (webhookTextMsg) MsgType() string {
+	return "text"
+}
+
+func TestRegisterMessageType(t *testing.T) {
+	if err := RegisterMessageType[webhookTextMsg](); err == nil {
+		t.Error("RegisterMessageType() error = nil, want message type already registered")
+	}
+	if err := RegisterMessageType[TextMsg](); err != nil {
+		t.Errorf("RegisterMessageType() error = '%s'", err)
+	}
+
+	message, err := DecodeMessage(EncodedMessage{MsgType: "text", Content: json.RawMessage(`{"content":"hello"}`)})
+	if err != nil || message != (TextMsg{Content: "hello"}) {
+		t.Errorf("DecodeMessage() = %v, error = '%v'", message, err)
+	}
+}
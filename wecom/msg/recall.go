@@ -0,0 +1,165 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo/storage"
+)
+
+// RecallTimeLimit 应用消息可撤回的时间范围
+const RecallTimeLimit = time.Hour * 24
+
+// ErrSentRecordNotFound 未找到业务key对应的已发送消息
+var ErrSentRecordNotFound = errors.New("sent message record not found")
+
+// SentLog 已发送消息记录，保存业务key与消息id的对应关系
+type SentLog interface {
+	// Save 保存业务key对应的消息id列表
+	Save(ctx context.Context, key string, msgIds []string) error
+
+	// Load 获取业务key对应的消息id列表，不存在时返回空列表
+	Load(ctx context.Context, key string) ([]string, error)
+}
+
+type storageSentLog struct {
+	storage storage.Storage
+	ttl     time.Duration
+
+	mutex *sync.Mutex
+	locks map[string]*keyLock // 正在保存的业务key
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// 已发送消息记录的存储格式
+type sentRecord struct {
+	MsgIds   []string  `json:"msgids"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// NewStorageSentLog 基于storage.Storage的已发送消息记录
+//
+// 记录的有效时间与消息可撤回的时间范围一致，从业务key第一次发送消息开始计算，追加消息id时不会延长。
+// 同一进程内对同一业务key的保存是串行的，多个进程共享storage时需要避免并发发送同一业务key的消息。
+func NewStorageSentLog(s storage.Storage) SentLog {
+	return &storageSentLog{storage: s, ttl: RecallTimeLimit, mutex: &sync.Mutex{}, locks: map[string]*keyLock{}}
+}
+
+func (l *storageSentLog) storageKey(key string) string {
+	return "sentmsg_" + key
+}
+
+// 锁定业务key，返回解锁函数
+func (l *storageSentLog) lock(key string) func() {
+	l.mutex.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mutex.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		l.mutex.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mutex.Unlock()
+	}
+}
+
+func (l *storageSentLog) Save(ctx context.Context, key string, msgIds []string) error {
+	defer l.lock(key)()
+
+	record, err := l.load(ctx, key)
+	if err != nil {
+		return err
+	}
+	// 第一次保存时从现在开始计算有效期
+	ttl := time.Until(record.ExpireAt)
+	if ttl <= 0 {
+		record.ExpireAt = time.Now().Add(l.ttl)
+		ttl = l.ttl
+	}
+	record.MsgIds = append(record.MsgIds, msgIds...)
+
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return l.storage.Set(ctx, l.storageKey(key), string(content), ttl)
+}
+
+func (l *storageSentLog) Load(ctx context.Context, key string) ([]string, error) {
+	record, err := l.load(ctx, key)
+	return record.MsgIds, err
+}
+
+func (l *storageSentLog) load(ctx context.Context, key string) (sentRecord, error) {
+	content := l.storage.Get(ctx, l.storageKey(key))
+	if content == "" {
+		return sentRecord{}, nil
+	}
+
+	record := sentRecord{}
+	err := json.Unmarshal([]byte(content), &record)
+	return record, err
+}
+
+// Recall 撤回应用消息
+//
+// 仅可撤回24小时内通过发送应用消息接口推送的消息
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/94867
+func (w WechatMsg) Recall(ctx context.Context, msgId string) error {
+	data := struct {
+		MsgId string `json:"msgid"` // 消息ID。从应用发送消息接口处获得
+	}{
+		MsgId: msgId,
+	}
+
+	return w.Client.Post(ctx, urlRecall, nil, data, nil, nil)
+}
+
+// RecallByKey 根据发送时通过SendWithKey指定的业务key撤回消息
+//
+// 业务key对应多条消息时会逐一撤回，返回遇到的第一个错误
+func (w WechatMsg) RecallByKey(ctx context.Context, key string) error {
+	if w.sentLog == nil {
+		return errors.New("sent log is not configured")
+	}
+
+	msgIds, err := w.sentLog.Load(ctx, key)
+	if err != nil {
+		return err
+	}
+	if len(msgIds) == 0 {
+		return ErrSentRecordNotFound
+	}
+
+	var firstErr error
+	for _, msgId := range msgIds {
+		if err := w.Recall(ctx, msgId); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// 记录已发送的消息
+func (w WechatMsg) logSent(ctx context.Context, key string, msgIds []string) error {
+	if key == "" || w.sentLog == nil || len(msgIds) == 0 {
+		return nil
+	}
+	return w.sentLog.Save(ctx, key, msgIds)
+}
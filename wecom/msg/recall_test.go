@@ -0,0 +1,104 @@
+package msg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/storage"
+)
+
+type recallTestSuite struct {
+	mockTestSuite
+}
+
+func (s *recallTestSuite) SetupTest() {
+	s.mockTestSuite.SetupTest()
+	s.msg = NewWechatMsg(s.msg.Client, WechatMsgWithSentLog(NewStorageSentLog(storage.NewMemoryStorage())))
+}
+
+func (s *recallTestSuite) TestShouldRecallByMsgId() {
+	s.transport.RegisterPost(urlRecall, `{"errcode":0,"errmsg":"ok"}`)
+
+	err := s.msg.Recall(context.Background(), "msgid1")
+
+	s.NoError(err)
+	s.Equal(1, s.transport.GetCallCountInfo()["POST "+mock.BaseURL+urlRecall])
+}
+
+func (s *recallTestSuite) TestShouldRecallByKey() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+	s.transport.RegisterPost(urlRecall, `{"errcode":0,"errmsg":"ok"}`)
+
+	_, err := s.msg.Send(context.Background(), ToUsers("u1"), TextMsg{Content: "hello"}, SendWithKey("order-1"))
+	s.NoError(err)
+
+	err = s.msg.RecallByKey(context.Background(), "order-1")
+
+	s.NoError(err)
+	s.Equal(1, s.transport.GetCallCountInfo()["POST "+mock.BaseURL+urlRecall])
+}
+
+func (s *recallTestSuite) TestShouldReturnNotFoundForUnknownKey() {
+	err := s.msg.RecallByKey(context.Background(), "unknown")
+
+	s.True(errors.Is(err, ErrSentRecordNotFound))
+}
+
+func (s *recallTestSuite) TestShouldReturnRecallError() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+	s.transport.RegisterPost(urlRecall, `{"errcode":40001,"errmsg":"invalid msgid"}`)
+
+	_, err := s.msg.Send(context.Background(), ToUsers("u1"), TextMsg{Content: "hello"}, SendWithKey("order-1"))
+	s.NoError(err)
+
+	err = s.msg.RecallByKey(context.Background(), "order-1")
+
+	s.Error(err)
+}
+
+func (s *recallTestSuite) TestShouldSaveConcurrentlyWithoutLosingMsgIds() {
+	sentLog := NewStorageSentLog(storage.NewMemoryStorage())
+	ctx := context.Background()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.NoError(sentLog.Save(ctx, "order-1", []string{fmt.Sprintf("msgid%d", i)}))
+		}(i)
+	}
+	wg.Wait()
+
+	msgIds, err := sentLog.Load(ctx, "order-1")
+	s.NoError(err)
+	s.Len(msgIds, 20)
+}
+
+func (s *recallTestSuite) TestShouldKeepOriginalExpiry() {
+	sentLog := &storageSentLog{storage: storage.NewMemoryStorage(), ttl: time.Millisecond * 100, mutex: &sync.Mutex{}, locks: map[string]*keyLock{}}
+	ctx := context.Background()
+
+	s.Require().NoError(sentLog.Save(ctx, "order-1", []string{"msgid1"}))
+	time.Sleep(time.Millisecond * 60)
+	s.Require().NoError(sentLog.Save(ctx, "order-1", []string{"msgid2"}))
+	msgIds, err := sentLog.Load(ctx, "order-1")
+	s.NoError(err)
+	s.Equal([]string{"msgid1", "msgid2"}, msgIds)
+
+	time.Sleep(time.Millisecond * 60)
+	msgIds, err = sentLog.Load(ctx, "order-1")
+	s.NoError(err)
+	s.Empty(msgIds, "appending does not extend the record")
+}
+
+func TestRecallTestSuite(t *testing.T) {
+	suite.Run(t, new(recallTestSuite))
+}
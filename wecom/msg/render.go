@@ -0,0 +1,356 @@
+package msg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// ErrTemplateNotFound 未找到指定名称的消息模板
+var ErrTemplateNotFound = errors.New("message template not found")
+
+// RawContent 不需要转义的内容，在模板中通过raw函数生成，如{{.Content | raw}}
+type RawContent string
+
+const escapeFuncName = "_wecom_escape"
+
+// 模板字段的输出格式，决定插入的数据如何转义
+type templateFormat int
+
+const (
+	formatText     templateFormat = iota // 纯文本，不转义
+	formatMarkdown                       // markdown，转义markdown标记
+	formatHTML                           // 文本卡片描述等支持部分html标签的字段，转义html
+	formatJSON                           // JSON字符串，转义为JSON字符串内容
+)
+
+func (f templateFormat) escape(s string) string {
+	switch f {
+	case formatMarkdown:
+		return EscapeMarkdown(s)
+	case formatHTML:
+		return html.EscapeString(s)
+	case formatJSON:
+		data, _ := json.Marshal(s)
+		return string(data[1 : len(data)-1])
+	}
+	return s
+}
+
+func (f templateFormat) escaper() func(v interface{}) string {
+	return func(v interface{}) string {
+		switch v := v.(type) {
+		case nil:
+			return ""
+		case RawContent:
+			return string(v)
+		default:
+			return f.escape(fmt.Sprint(v))
+		}
+	}
+}
+
+// 在所有输出数据的动作末尾追加转义函数，类似html/template的处理方式
+func addEscaping(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addEscaping(child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escapeFuncName).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		addEscaping(n.List)
+		addEscaping(n.ElseList)
+	case *parse.RangeNode:
+		addEscaping(n.List)
+		addEscaping(n.ElseList)
+	case *parse.WithNode:
+		addEscaping(n.List)
+		addEscaping(n.ElseList)
+	}
+}
+
+// 模板中的一个字段
+type templateField struct {
+	tmpl *template.Template
+}
+
+func compileField(name, text string, format templateFormat, funcs template.FuncMap) (templateField, error) {
+	tmpl, err := template.New(name).
+		Funcs(template.FuncMap{
+			"raw":          func(v interface{}) RawContent { return RawContent(fmt.Sprint(v)) },
+			escapeFuncName: format.escaper(),
+		}).
+		Funcs(funcs).
+		Parse(text)
+	if err != nil {
+		return templateField{}, err
+	}
+
+	if format != formatText {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				addEscaping(t.Tree.Root)
+			}
+		}
+	}
+	return templateField{tmpl: tmpl}, nil
+}
+
+func (f templateField) execute(data interface{}) (string, error) {
+	if f.tmpl == nil {
+		return "", nil
+	}
+	buf := bytes.Buffer{}
+	if err := f.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type compiledTemplate func(data interface{}) (Message, error)
+
+// MessageTemplate 消息模板，通过TextTemplate、MarkdownTemplate、TextCardTemplate及JSONTemplate创建
+type MessageTemplate interface {
+	compile(name string, funcs template.FuncMap) (compiledTemplate, error)
+}
+
+type textTemplate struct {
+	content string
+}
+
+// TextTemplate 文本消息模板，数据不转义
+func TextTemplate(content string) MessageTemplate {
+	return textTemplate{content: content}
+}
+
+func (t textTemplate) compile(name string, funcs template.FuncMap) (compiledTemplate, error) {
+	field, err := compileField(name, t.content, formatText, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return func(data interface{}) (Message, error) {
+		content, err := field.execute(data)
+		return TextMsg{Content: content}, err
+	}, nil
+}
+
+type markdownTemplate struct {
+	content string
+}
+
+// MarkdownTemplate markdown消息模板，插入的数据会转义markdown标记，可以通过raw函数插入markdown内容
+func MarkdownTemplate(content string) MessageTemplate {
+	return markdownTemplate{content: content}
+}
+
+func (t markdownTemplate) compile(name string, funcs template.FuncMap) (compiledTemplate, error) {
+	field, err := compileField(name, t.content, formatMarkdown, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return func(data interface{}) (Message, error) {
+		content, err := field.execute(data)
+		return MarkdownMsg{Content: content}, err
+	}, nil
+}
+
+type textCardTemplate struct {
+	card TextCardMsg
+}
+
+// TextCardTemplate 文本卡片消息模板，card的各字段均为模板
+//
+// 描述支持部分html标签，插入的数据会转义html；其他字段不转义，链接中的参数可以使用urlquery函数转义
+func TextCardTemplate(card TextCardMsg) MessageTemplate {
+	return textCardTemplate{card: card}
+}
+
+func (t textCardTemplate) compile(name string, funcs template.FuncMap) (compiledTemplate, error) {
+	specs := []struct {
+		text   string
+		format templateFormat
+	}{
+		{t.card.Title, formatText},
+		{t.card.Description, formatHTML},
+		{t.card.Url, formatText},
+		{t.card.BtnText, formatText},
+	}
+
+	fields := make([]templateField, len(specs))
+	for i, spec := range specs {
+		field, err := compileField(name, spec.text, spec.format, funcs)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = field
+	}
+
+	return func(data interface{}) (Message, error) {
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			value, err := field.execute(data)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return TextCardMsg{Title: values[0], Description: values[1], Url: values[2], BtnText: values[3]}, nil
+	}, nil
+}
+
+type jsonTemplate struct {
+	msgType string
+	body    string
+}
+
+// JSONTemplate 任意已注册类型的消息模板，body为消息内容的JSON模板
+//
+// 插入的数据会转义为JSON字符串的内容，因此动作应位于JSON字符串中，如{"content": "{{.Name}}"}
+func JSONTemplate(msgType, body string) MessageTemplate {
+	return jsonTemplate{msgType: msgType, body: body}
+}
+
+func (t jsonTemplate) compile(name string, funcs template.FuncMap) (compiledTemplate, error) {
+	field, err := compileField(name, t.body, formatJSON, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return func(data interface{}) (Message, error) {
+		content, err := field.execute(data)
+		if err != nil {
+			return nil, err
+		}
+		return DecodeMessage(EncodedMessage{MsgType: t.msgType, Content: json.RawMessage(content)})
+	}, nil
+}
+
+type RendererOptionFn func(renderer *Renderer)
+
+// RendererWithDefaultLocale 未找到指定语言的模板时使用的语言
+func RendererWithDefaultLocale(locale string) RendererOptionFn {
+	return func(renderer *Renderer) {
+		renderer.defaultLocale = locale
+	}
+}
+
+// RendererWithFuncs 模板中可以使用的函数，需在注册模板前指定
+func RendererWithFuncs(funcs template.FuncMap) RendererOptionFn {
+	return func(renderer *Renderer) {
+		renderer.funcs = funcs
+	}
+}
+
+// RendererWithTruncate 渲染后截断超长的字段，而不是返回*ValidationError
+func RendererWithTruncate() RendererOptionFn {
+	return func(renderer *Renderer) {
+		renderer.truncate = true
+	}
+}
+
+// Renderer 消息模板渲染器，按名称及语言管理模板
+//
+// 渲染时依次查找指定语言（如zh-CN）、语言的主标签（如zh）、默认语言以及未指定语言的模板。
+// 渲染结果会按照消息的长度限制进行校验。
+type Renderer struct {
+	mutex         *sync.RWMutex
+	templates     map[string]map[string]compiledTemplate // 模板名称 -> 语言 -> 模板
+	defaultLocale string
+	funcs         template.FuncMap
+	truncate      bool
+}
+
+func NewRenderer(options ...RendererOptionFn) *Renderer {
+	renderer := &Renderer{
+		mutex:     &sync.RWMutex{},
+		templates: map[string]map[string]compiledTemplate{},
+	}
+	for _, opt := range options {
+		opt(renderer)
+	}
+	return renderer
+}
+
+// Register 注册未指定语言的模板，已存在时替换
+func (r *Renderer) Register(name string, tmpl MessageTemplate) error {
+	return r.RegisterLocale(name, "", tmpl)
+}
+
+// RegisterLocale 注册指定语言的模板，已存在时替换
+func (r *Renderer) RegisterLocale(name, locale string, tmpl MessageTemplate) error {
+	compiled, err := tmpl.compile(name, r.funcs)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.templates[name] == nil {
+		r.templates[name] = map[string]compiledTemplate{}
+	}
+	r.templates[name][normalizeLocale(locale)] = compiled
+	return nil
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+func (r *Renderer) lookup(name, locale string) (compiledTemplate, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	variants := r.templates[name]
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, normalizeLocale(r.defaultLocale), "")
+
+	for _, candidate := range candidates {
+		if tmpl, ok := variants[candidate]; ok {
+			return tmpl, true
+		}
+	}
+	return nil, false
+}
+
+// Render 使用指定名称及语言的模板渲染消息
+//
+// 渲染结果违反消息的长度限制时返回*ValidationError，指定RendererWithTruncate时截断超长的字段
+func (r *Renderer) Render(name, locale string, data interface{}) (Message, error) {
+	tmpl, ok := r.lookup(name, locale)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+
+	message, err := tmpl(data)
+	if err != nil {
+		return nil, err
+	}
+	if r.truncate {
+		message = Truncate(message)
+	}
+	if err = Validate(message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,107 @@
+package msg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type renderTestSuite struct {
+	suite.Suite
+	renderer *Renderer
+}
+
+func (s *renderTestSuite) SetupTest() {
+	s.renderer = NewRenderer(
+		RendererWithDefaultLocale("en"),
+		RendererWithFuncs(template.FuncMap{"upper": strings.ToUpper}),
+	)
+}
+
+func (s *renderTestSuite) TestShouldEscapeMarkdownData() {
+	s.Require().NoError(s.renderer.Register("alert",
+		MarkdownTemplate("## {{.Title}}\n{{range .Items}}> {{.}}\n{{end}}{{.Link | raw}}")))
+
+	message, err := s.renderer.Render("alert", "", map[string]any{
+		"Title": "CPU *high*",
+		"Items": []string{"host_1", "[x]"},
+		"Link":  "[详情](https://example.com)",
+	})
+
+	s.Require().NoError(err)
+	s.Equal(MarkdownMsg{Content: "## CPU \\*high\\*\n> host\\_1\n> \\[x\\]\n[详情](https://example.com)"}, message)
+}
+
+func (s *renderTestSuite) TestShouldEscapeTextCardDescription() {
+	s.Require().NoError(s.renderer.Register("approval", TextCardTemplate(TextCardMsg{
+		Title:       "{{.Name | upper}}的申请",
+		Description: `<div class="gray">{{.Date}}</div><div class="normal">{{.Reason}}</div>`,
+		Url:         "https://example.com/approval?id={{.Id | urlquery}}",
+	})))
+
+	message, err := s.renderer.Render("approval", "", map[string]any{
+		"Name": "alice", "Date": "2024-03-01", "Reason": "<b>急</b>", "Id": "a&b",
+	})
+
+	s.Require().NoError(err)
+	s.Equal(TextCardMsg{
+		Title:       "ALICE的申请",
+		Description: `<div class="gray">2024-03-01</div><div class="normal">&lt;b&gt;急&lt;/b&gt;</div>`,
+		Url:         "https://example.com/approval?id=a%26b",
+	}, message)
+}
+
+func (s *renderTestSuite) TestShouldRenderAnyRegisteredTypeFromJSON() {
+	s.Require().NoError(s.renderer.Register("news", JSONTemplate("news",
+		`{"articles":[{"title":"{{.Title}}","url":"https://example.com/{{.Id}}"}]}`)))
+
+	message, err := s.renderer.Render("news", "", map[string]any{"Title": `say "hi"`, "Id": 42})
+
+	s.Require().NoError(err)
+	s.Equal(NewsMsg{Articles: []Article{{Title: `say "hi"`, Url: "https://example.com/42"}}}, message)
+}
+
+func (s *renderTestSuite) TestShouldSelectLocaleVariant() {
+	s.Require().NoError(s.renderer.Register("greeting", TextTemplate("hi {{.}}")))
+	s.Require().NoError(s.renderer.RegisterLocale("greeting", "en", TextTemplate("hello {{.}}")))
+	s.Require().NoError(s.renderer.RegisterLocale("greeting", "zh", TextTemplate("你好 {{.}}")))
+	s.Require().NoError(s.renderer.RegisterLocale("greeting", "zh-TW", TextTemplate("妳好 {{.}}")))
+
+	render := func(locale string) Message {
+		message, err := s.renderer.Render("greeting", locale, "bob")
+		s.Require().NoError(err)
+		return message
+	}
+	s.Equal(TextMsg{Content: "你好 bob"}, render("zh_CN"))
+	s.Equal(TextMsg{Content: "妳好 bob"}, render("zh-tw"))
+	s.Equal(TextMsg{Content: "hello bob"}, render("fr"))
+
+	_, err := s.renderer.Render("missing", "en", nil)
+	s.True(errors.Is(err, ErrTemplateNotFound))
+}
+
+func (s *renderTestSuite) TestShouldValidateRenderedMessage() {
+	s.Require().NoError(s.renderer.Register("long", TextTemplate("{{.}}")))
+
+	_, err := s.renderer.Render("long", "", strings.Repeat("a", MaxTextBytes+1))
+	var validationErr *ValidationError
+	s.True(errors.As(err, &validationErr))
+
+	truncating := NewRenderer(RendererWithTruncate())
+	s.Require().NoError(truncating.Register("long", TextTemplate("{{.}}")))
+	message, err := truncating.Render("long", "", strings.Repeat("a", MaxTextBytes+1))
+	s.Require().NoError(err)
+	s.LessOrEqual(len(message.(TextMsg).Content), MaxTextBytes)
+}
+
+func (s *renderTestSuite) TestShouldRejectInvalidTemplate() {
+	s.Error(s.renderer.Register("broken", MarkdownTemplate("{{.Title")))
+	s.Error(s.renderer.Register("unknown", MarkdownTemplate("{{missing .Title}}")))
+}
+
+func TestRenderTestSuite(t *testing.T) {
+	suite.Run(t, new(renderTestSuite))
+}
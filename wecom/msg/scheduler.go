@@ -0,0 +1,407 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/storage"
+)
+
+// 定时发送的默认配置
+const (
+	DefaultIdempotencyWindow     = time.Hour * 24
+	DefaultSchedulerMaxAttempts  = 3
+	DefaultSchedulerPollInterval = time.Second
+)
+
+// ErrJobNotFound 未找到业务key对应的定时任务
+var ErrJobNotFound = errors.New("scheduled job not found")
+
+// ErrDuplicateMessage 幂等窗口内已发送或正在发送相同id的消息
+var ErrDuplicateMessage = errors.New("message with the same idempotency id has already been sent")
+
+// ScheduledJob 定时发送的消息任务
+type ScheduledJob struct {
+	Key           string       // 业务key
+	IdempotencyId string       // 发送时的幂等id，每次安排时生成，重试时保持不变
+	To            Recipients   // 接收者
+	Message       Message      // 消息内容
+	Progress      SendProgress // 发送进度，重试时从失败的部分继续，只发送给未收到该部分的接收者
+	SendAt        time.Time    // 发送时间
+	Attempts      int          // 已尝试发送的次数
+	LastError     string       // 最近一次发送失败的错误
+	CreatedAt     time.Time    // 创建时间
+
+	option sendOption
+}
+
+type scheduledJobJSON struct {
+	Key           string         `json:"key"`
+	IdempotencyId string         `json:"idempotency_id,omitempty"`
+	Users         []string       `json:"users,omitempty"`
+	Parties       []int          `json:"parties,omitempty"`
+	Tags          []int          `json:"tags,omitempty"`
+	All           bool           `json:"all,omitempty"`
+	Message       EncodedMessage `json:"message"`
+	Option        storedOption   `json:"option"`
+	Progress      storedProgress `json:"progress"`
+	SendAt        time.Time      `json:"send_at"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+func (j ScheduledJob) MarshalJSON() ([]byte, error) {
+	encoded, err := EncodeMessage(j.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(scheduledJobJSON{
+		Key:           j.Key,
+		IdempotencyId: j.IdempotencyId,
+		Users:         j.To.Users,
+		Parties:       j.To.Parties,
+		Tags:          j.To.Tags,
+		All:           j.To.All,
+		Message:       encoded,
+		Option:        newStoredOption(j.option),
+		Progress:      newStoredProgress(j.Progress),
+		SendAt:        j.SendAt,
+		Attempts:      j.Attempts,
+		LastError:     j.LastError,
+		CreatedAt:     j.CreatedAt,
+	})
+}
+
+func (j *ScheduledJob) UnmarshalJSON(data []byte) error {
+	v := scheduledJobJSON{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	message, err := DecodeMessage(v.Message)
+	if err != nil {
+		return err
+	}
+
+	*j = ScheduledJob{
+		Key:           v.Key,
+		IdempotencyId: v.IdempotencyId,
+		To:            Recipients{Users: v.Users, Parties: v.Parties, Tags: v.Tags, All: v.All},
+		Message:       message,
+		Progress:      v.Progress.sendProgress(),
+		SendAt:        v.SendAt,
+		Attempts:      v.Attempts,
+		LastError:     v.LastError,
+		CreatedAt:     v.CreatedAt,
+		option:        v.Option.sendOption(),
+	}
+	return nil
+}
+
+// JobOutcome 定时任务的执行结果
+type JobOutcome struct {
+	Job         *ScheduledJob // 执行的任务
+	Result      *SendResult   // 发送结果
+	Err         error         // 发送失败的错误，幂等窗口内重复时为ErrDuplicateMessage
+	Rescheduled bool          // 发送失败后是否已重新安排发送
+}
+
+type SchedulerOptionFn func(scheduler *Scheduler)
+
+// SchedulerWithStore 指定任务存储，默认为内存存储
+func SchedulerWithStore(store JobStore) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.store = store
+	}
+}
+
+// SchedulerWithIdempotency 指定保存幂等id的存储及幂等窗口，默认为24小时
+//
+// 未指定存储时，任务存储实现了IdempotencyProvider（如NewFileJobStore）则使用其提供的持久化存储，
+// 否则使用内存存储。需要在进程重启后保证不重复发送时，应使用持久化的存储
+func SchedulerWithIdempotency(s storage.Storage, window time.Duration) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.idempotency = s
+		scheduler.window = window
+	}
+}
+
+// SchedulerWithMaxAttempts 可重试的错误最多尝试发送的次数，默认为3
+func SchedulerWithMaxAttempts(attempts int) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.maxAttempts = attempts
+	}
+}
+
+// SchedulerWithBackoff 重试前的等待时间，attempt为已尝试的次数，默认为ExponentialBackoff(time.Second*10, time.Minute*5)
+func SchedulerWithBackoff(backoff func(attempt int) time.Duration) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.backoff = backoff
+	}
+}
+
+// SchedulerWithPollInterval 检查到期任务的时间间隔，默认为1秒
+func SchedulerWithPollInterval(interval time.Duration) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.pollInterval = interval
+	}
+}
+
+// SchedulerWithCallback 每个任务执行后的回调
+func SchedulerWithCallback(callback func(ctx context.Context, outcome JobOutcome)) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.callback = callback
+	}
+}
+
+func SchedulerWithLogger(logger wechatgo.Logger) SchedulerOptionFn {
+	return func(scheduler *Scheduler) {
+		scheduler.log = logger
+	}
+}
+
+// Scheduler 定时发送及幂等发送
+//
+// 定时任务以业务key标识，同一key只保留最后一次安排的任务，可以通过key取消。
+// 每次安排任务时生成新的幂等id，任务到期后以该id发送：幂等窗口内已发送过相同id的消息时不再发送，
+// 避免进程在发送后、删除任务前退出导致重复发送。拆分或分批发送部分失败时，重试时从失败的部分继续，
+// 只发送给未收到该部分的接收者，发送完所有部分后才记录幂等id。
+type Scheduler struct {
+	msg          *WechatMsg
+	store        JobStore
+	idempotency  storage.Storage
+	window       time.Duration
+	maxAttempts  int
+	backoff      func(attempt int) time.Duration
+	pollInterval time.Duration
+	callback     func(ctx context.Context, outcome JobOutcome)
+	log          wechatgo.Logger
+
+	notify   chan struct{}
+	mutex    *sync.Mutex
+	sending  map[string]bool // 正在发送的幂等id
+	jobMutex *sync.Mutex     // 保证任务的读取、比较及更新是原子的
+}
+
+func NewScheduler(msg *WechatMsg, options ...SchedulerOptionFn) *Scheduler {
+	scheduler := &Scheduler{
+		msg:      msg,
+		notify:   make(chan struct{}, 1),
+		mutex:    &sync.Mutex{},
+		sending:  map[string]bool{},
+		jobMutex: &sync.Mutex{},
+	}
+
+	for _, opt := range options {
+		opt(scheduler)
+	}
+
+	if scheduler.store == nil {
+		scheduler.store = NewMemoryJobStore()
+	}
+	if scheduler.idempotency == nil {
+		if provider, ok := scheduler.store.(IdempotencyProvider); ok {
+			scheduler.idempotency = provider.IdempotencyStorage()
+		} else {
+			scheduler.idempotency = storage.NewMemoryStorage()
+		}
+	}
+	if scheduler.window <= 0 {
+		scheduler.window = DefaultIdempotencyWindow
+	}
+	if scheduler.maxAttempts <= 0 {
+		scheduler.maxAttempts = DefaultSchedulerMaxAttempts
+	}
+	if scheduler.backoff == nil {
+		scheduler.backoff = ExponentialBackoff(time.Second*10, time.Minute*5)
+	}
+	if scheduler.pollInterval <= 0 {
+		scheduler.pollInterval = DefaultSchedulerPollInterval
+	}
+	if scheduler.log == nil {
+		scheduler.log = wechatgo.DefaultLogger()
+	}
+	return scheduler
+}
+
+// Schedule 安排在sendAt发送消息，已存在相同key的任务时替换
+//
+// 安排前会校验消息内容，options与Send相同；sendAt早于当前时间时尽快发送
+func (s *Scheduler) Schedule(ctx context.Context, key string, sendAt time.Time, to Recipients, message Message, options ...SendOption) error {
+	option := s.msg.newSendOption(options...)
+	if _, err := s.msg.prepareMessages(message, option); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	job := &ScheduledJob{
+		Key:           key,
+		IdempotencyId: key + "@" + now.Format(time.RFC3339Nano),
+		To:            to,
+		Message:       message,
+		SendAt:        sendAt,
+		CreatedAt:     now,
+		option:        option,
+	}
+	s.jobMutex.Lock()
+	err := s.store.Save(ctx, job)
+	s.jobMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Cancel 取消业务key对应的任务，返回任务是否存在
+func (s *Scheduler) Cancel(ctx context.Context, key string) (bool, error) {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+	return s.store.Delete(ctx, key)
+}
+
+// Get 获取业务key对应的任务，不存在时返回ErrJobNotFound
+func (s *Scheduler) Get(ctx context.Context, key string) (*ScheduledJob, error) {
+	return s.store.Get(ctx, key)
+}
+
+// SendOnce 立即发送消息，幂等窗口内已发送或正在发送相同id的消息时返回ErrDuplicateMessage
+//
+// 发送失败时不记录id，之后可以使用相同的id重新发送；部分内容发送成功时同样记录id并返回错误，
+// 避免重新发送已成功的部分，失败的批次需要根据SendResult.Batches自行处理
+func (s *Scheduler) SendOnce(ctx context.Context, id string, to Recipients, message Message, options ...SendOption) (*SendResult, error) {
+	result, _, err := s.sendOnce(ctx, id, to, message, s.msg.newSendOption(options...), SendProgress{}, true)
+	return result, err
+}
+
+func (s *Scheduler) idempotencyKey(id string) string {
+	return "msgidem_" + id
+}
+
+// 从progress继续发送，全部发送成功时记录id；markPartial为true时部分内容发送成功也记录id
+func (s *Scheduler) sendOnce(ctx context.Context, id string, to Recipients, message Message, option sendOption, progress SendProgress, markPartial bool) (*SendResult, SendProgress, error) {
+	s.mutex.Lock()
+	if s.sending[id] || s.idempotency.Get(ctx, s.idempotencyKey(id)) != "" {
+		s.mutex.Unlock()
+		return &SendResult{}, progress, ErrDuplicateMessage
+	}
+	s.sending[id] = true
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.sending, id)
+		s.mutex.Unlock()
+	}()
+
+	result, progress, err := s.msg.sendWithProgress(ctx, to, message, option, progress)
+	if err != nil && !(markPartial && progress.Started()) {
+		return result, progress, err
+	}
+	markErr := s.idempotency.Set(ctx, s.idempotencyKey(id), time.Now().Format(time.RFC3339), s.window)
+	if err != nil {
+		return result, progress, err
+	}
+	return result, progress, markErr
+}
+
+// Run 发送到期的任务，阻塞直到ctx被取消
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.notify:
+		}
+	}
+}
+
+func (s *Scheduler) dispatch(ctx context.Context) {
+	jobs, err := s.store.Due(ctx, time.Now(), 100)
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("Scheduler: failed to load due jobs, Error: %s", err))
+		return
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		s.execute(ctx, job)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, job *ScheduledJob) {
+	// 任务在读取后可能已被取消或替换
+	if current, err := s.currentJob(ctx, job); err != nil || current == nil {
+		if err != nil {
+			s.log.Error(ctx, fmt.Sprintf("Scheduler: failed to load job %s, Error: %s", job.Key, err))
+		}
+		return
+	}
+
+	result, progress, err := s.sendOnce(ctx, job.IdempotencyId, job.To, job.Message, job.option, job.Progress, false)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	outcome := JobOutcome{Job: job, Result: result, Err: err}
+	job.Attempts++
+	job.Progress = progress
+	retry := err != nil && !errors.Is(err, ErrDuplicateMessage) && IsRetryableError(err) && job.Attempts < s.maxAttempts
+	if retry {
+		job.LastError = err.Error()
+		job.SendAt = time.Now().Add(s.backoff(job.Attempts))
+	}
+
+	// 发送期间任务被取消或替换时不再更新，避免恢复已取消的任务或删除新安排的任务
+	s.jobMutex.Lock()
+	current, err := s.currentJob(ctx, job)
+	if err == nil && current != nil {
+		if retry {
+			outcome.Rescheduled = true
+			err = s.store.Save(ctx, job)
+		} else {
+			_, err = s.store.Delete(ctx, job.Key)
+		}
+	}
+	s.jobMutex.Unlock()
+	if err != nil {
+		s.log.Error(ctx, fmt.Sprintf("Scheduler: failed to update job %s, Error: %s", job.Key, err))
+	}
+
+	if s.callback != nil {
+		s.callback(ctx, outcome)
+	}
+}
+
+// 返回存储中与job为同一次安排的任务，任务已被取消或替换时返回nil
+func (s *Scheduler) currentJob(ctx context.Context, job *ScheduledJob) (*ScheduledJob, error) {
+	current, err := s.store.Get(ctx, job.Key)
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !current.CreatedAt.Equal(job.CreatedAt) {
+		return nil, nil
+	}
+	return current, nil
+}
@@ -0,0 +1,240 @@
+package msg
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/huimingz/wechatgo/storage"
+)
+
+// JobStore 定时消息任务的持久化存储
+//
+// 每个业务key最多只有一个任务，实现需要保证并发安全
+type JobStore interface {
+	// Save 保存任务，已存在相同key的任务时替换
+	Save(ctx context.Context, job *ScheduledJob) error
+
+	// Get 获取业务key对应的任务，不存在时返回ErrJobNotFound
+	Get(ctx context.Context, key string) (*ScheduledJob, error)
+
+	// Due 返回最多limit个SendAt不晚于now的任务，按SendAt升序排列
+	Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledJob, error)
+
+	// Delete 删除任务，返回任务是否存在
+	Delete(ctx context.Context, key string) (bool, error)
+}
+
+type memoryJobStore struct {
+	mutex *sync.Mutex
+	jobs  map[string]ScheduledJob
+}
+
+// NewMemoryJobStore 内存存储，进程退出后任务丢失
+func NewMemoryJobStore() JobStore {
+	return &memoryJobStore{mutex: &sync.Mutex{}, jobs: map[string]ScheduledJob{}}
+}
+
+func (s *memoryJobStore) Save(ctx context.Context, job *ScheduledJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.jobs[job.Key] = *job
+	return nil
+}
+
+func (s *memoryJobStore) Get(ctx context.Context, key string) (*ScheduledJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[key]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *memoryJobStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var jobs []*ScheduledJob
+	for _, job := range s.jobs {
+		if !job.SendAt.After(now) {
+			job := job
+			jobs = append(jobs, &job)
+		}
+	}
+	return limitDueJobs(jobs, limit), nil
+}
+
+func (s *memoryJobStore) Delete(ctx context.Context, key string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.jobs[key]
+	delete(s.jobs, key)
+	return ok, nil
+}
+
+// 按SendAt升序排列并截取前limit个，limit不大于0时不截取
+func limitDueJobs(jobs []*ScheduledJob, limit int) []*ScheduledJob {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].SendAt.Before(jobs[j].SendAt)
+	})
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs
+}
+
+type fileJobStore struct {
+	mutex *sync.Mutex
+	dir   string
+}
+
+// NewFileJobStore 文件存储，每个任务保存为dir下的一个JSON文件
+//
+// 仅适用于单个进程使用同一目录的场景
+func NewFileJobStore(dir string) (JobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileJobStore{mutex: &sync.Mutex{}, dir: dir}, nil
+}
+
+// 业务key可能包含不能用作文件名的字符，使用key的摘要作为文件名
+func (s *fileJobStore) filename(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *fileJobStore) Save(ctx context.Context, job *ScheduledJob) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeJSONFile(s.dir, s.filename(job.Key), job)
+}
+
+func (s *fileJobStore) Get(ctx context.Context, key string) (*ScheduledJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job := &ScheduledJob{}
+	err := readJSONFile(s.dir, s.filename(key), job)
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+func (s *fileJobStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledJob, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs, err := readJSONFiles[ScheduledJob](s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []*ScheduledJob
+	for _, job := range jobs {
+		if !job.SendAt.After(now) {
+			due = append(due, job)
+		}
+	}
+	return limitDueJobs(due, limit), nil
+}
+
+func (s *fileJobStore) Delete(ctx context.Context, key string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := os.Remove(filepath.Join(s.dir, s.filename(key)+".json"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// IdempotencyProvider 可以提供幂等存储的JobStore
+//
+// 未通过SchedulerWithIdempotency指定幂等存储时，Scheduler优先使用任务存储提供的幂等存储
+type IdempotencyProvider interface {
+	IdempotencyStorage() storage.Storage
+}
+
+// IdempotencyStorage 与任务保存在同一目录下的文件幂等存储，进程重启后仍然有效
+func (s *fileJobStore) IdempotencyStorage() storage.Storage {
+	return &fileIdempotencyStore{mutex: &sync.Mutex{}, dir: filepath.Join(s.dir, "idempotency")}
+}
+
+type idempotencyRecord struct {
+	Value    string    `json:"value"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+type fileIdempotencyStore struct {
+	mutex *sync.Mutex
+	dir   string
+}
+
+// NewFileIdempotencyStore 文件幂等存储，每个幂等id保存为dir下的一个JSON文件
+//
+// 仅适用于单个进程使用同一目录的场景，过期的记录在读取时删除
+func NewFileIdempotencyStore(dir string) (storage.Storage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fileIdempotencyStore{mutex: &sync.Mutex{}, dir: dir}, nil
+}
+
+func (s *fileIdempotencyStore) filename(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// 读取未过期的记录，过期时删除记录
+func (s *fileIdempotencyStore) get(key string) (idempotencyRecord, bool) {
+	record := idempotencyRecord{}
+	if err := readJSONFile(s.dir, s.filename(key), &record); err != nil {
+		return record, false
+	}
+	if !record.ExpireAt.After(time.Now()) {
+		removeJSONFile(s.dir, s.filename(key))
+		return record, false
+	}
+	return record, true
+}
+
+func (s *fileIdempotencyStore) Get(ctx context.Context, key string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, ok := s.get(key)
+	if !ok {
+		return ""
+	}
+	return record.Value
+}
+
+func (s *fileIdempotencyStore) Set(ctx context.Context, key, val string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	return writeJSONFile(s.dir, s.filename(key), idempotencyRecord{Value: val, ExpireAt: time.Now().Add(ttl)})
+}
+
+func (s *fileIdempotencyStore) HasExpired(ctx context.Context, key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.get(key)
+	return !ok
+}
@@ -0,0 +1,349 @@
+package msg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/storage"
+)
+
+type schedulerTestSuite struct {
+	mockTestSuite
+	outcomes chan JobOutcome
+}
+
+func (s *schedulerTestSuite) SetupTest() {
+	s.mockTestSuite.SetupTest()
+	s.outcomes = make(chan JobOutcome, 10)
+}
+
+func (s *schedulerTestSuite) newScheduler(options ...SchedulerOptionFn) *Scheduler {
+	options = append([]SchedulerOptionFn{
+		SchedulerWithBackoff(func(int) time.Duration { return time.Millisecond }),
+		SchedulerWithPollInterval(time.Millisecond * 5),
+		SchedulerWithCallback(func(ctx context.Context, outcome JobOutcome) {
+			s.outcomes <- outcome
+		}),
+	}, options...)
+	return NewScheduler(s.msg, options...)
+}
+
+// 启动调度器，测试结束时停止
+func (s *schedulerTestSuite) run(scheduler *Scheduler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(ctx)
+		close(done)
+	}()
+	s.T().Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func (s *schedulerTestSuite) nextOutcome() JobOutcome {
+	select {
+	case outcome := <-s.outcomes:
+		return outcome
+	case <-time.After(time.Second):
+		s.FailNow("timeout waiting for job outcome")
+	}
+	return JobOutcome{}
+}
+
+func (s *schedulerTestSuite) TestShouldSendWhenDue() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+
+	sendAt := time.Now().Add(time.Millisecond * 30)
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", sendAt, ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+
+	outcome := s.nextOutcome()
+	s.False(time.Now().Before(sendAt))
+	s.NoError(outcome.Err)
+	s.Equal("msgid1", outcome.Result.MsgId)
+	s.Len(s.transport.Requests(), 1)
+
+	_, err := scheduler.Get(context.Background(), "remind-1")
+	s.True(errors.Is(err, ErrJobNotFound))
+}
+
+func (s *schedulerTestSuite) TestShouldCancelByKey() {
+	scheduler := s.newScheduler()
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now().Add(time.Hour),
+		ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+
+	job, err := scheduler.Get(context.Background(), "remind-1")
+	s.Require().NoError(err)
+	s.Equal(TextMsg{Content: "开会"}, job.Message)
+
+	canceled, err := scheduler.Cancel(context.Background(), "remind-1")
+	s.NoError(err)
+	s.True(canceled)
+	canceled, err = scheduler.Cancel(context.Background(), "remind-1")
+	s.NoError(err)
+	s.False(canceled)
+}
+
+func (s *schedulerTestSuite) TestShouldReplaceJobWithSameKey() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok"}`)
+	scheduler := s.newScheduler()
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now().Add(time.Hour),
+		ToUsers("zhangsan"), TextMsg{Content: "old"}))
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(),
+		ToUsers("zhangsan"), TextMsg{Content: "new"}))
+	s.run(scheduler)
+
+	s.NoError(s.nextOutcome().Err)
+	s.Equal(map[string]any{"content": "new"}, s.transport.Requests()[0]["text"])
+}
+
+func (s *schedulerTestSuite) TestShouldNotSendDuplicateWithinWindow() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok"}`)
+	idempotency := storage.NewMemoryStorage()
+	scheduler := s.newScheduler(SchedulerWithIdempotency(idempotency, time.Hour))
+
+	_, err := scheduler.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.Require().NoError(err)
+
+	// 模拟进程重启：新的调度器使用相同的幂等存储
+	restarted := s.newScheduler(SchedulerWithIdempotency(idempotency, time.Hour))
+	_, err = restarted.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.True(errors.Is(err, ErrDuplicateMessage))
+	s.Len(s.transport.Requests(), 1)
+}
+
+func (s *schedulerTestSuite) TestShouldPersistIdempotencyWithFileStore() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok"}`)
+	dir := s.T().TempDir()
+	store, err := NewFileJobStore(dir)
+	s.Require().NoError(err)
+
+	_, err = s.newScheduler(SchedulerWithStore(store)).SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.Require().NoError(err)
+
+	// 模拟进程重启：重新打开同一目录的任务存储
+	reopened, err := NewFileJobStore(dir)
+	s.Require().NoError(err)
+	_, err = s.newScheduler(SchedulerWithStore(reopened)).SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.True(errors.Is(err, ErrDuplicateMessage))
+	s.Len(s.transport.Requests(), 1)
+
+	due, err := reopened.Due(context.Background(), time.Now(), 10)
+	s.NoError(err)
+	s.Empty(due, "idempotency records are not loaded as jobs")
+}
+
+func (s *schedulerTestSuite) TestShouldExpireFileIdempotencyRecord() {
+	idempotency, err := NewFileIdempotencyStore(s.T().TempDir())
+	s.Require().NoError(err)
+	ctx := context.Background()
+
+	s.Require().NoError(idempotency.Set(ctx, "a", "sent", time.Hour))
+	s.Require().NoError(idempotency.Set(ctx, "b", "sent", -time.Second))
+	s.Equal("sent", idempotency.Get(ctx, "a"))
+	s.False(idempotency.HasExpired(ctx, "a"))
+	s.Equal("", idempotency.Get(ctx, "b"))
+	s.True(idempotency.HasExpired(ctx, "b"))
+}
+
+func (s *schedulerTestSuite) TestShouldAllowResendAfterFailure() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend,
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":81013,"errmsg":"user & party & tag all invalid"}`).
+			Then(httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)))
+	scheduler := s.newScheduler()
+
+	_, err := scheduler.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.Error(err)
+	_, err = scheduler.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), TextMsg{Content: "告警"})
+	s.NoError(err)
+}
+
+func (s *schedulerTestSuite) TestShouldRescheduleRetryableError() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend,
+		httpmock.NewStringResponder(http.StatusOK, `{"errcode":45009,"errmsg":"api freq out of limit"}`).
+			Then(httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok"}`)))
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+
+	first := s.nextOutcome()
+	s.Error(first.Err)
+	s.True(first.Rescheduled)
+	second := s.nextOutcome()
+	s.NoError(second.Err)
+	s.Equal(2, second.Job.Attempts)
+}
+
+func (s *schedulerTestSuite) TestShouldRescheduleOnlyFailedBatches() {
+	s.transport.RegisterPost(urlSend,
+		`{"errcode":0,"errmsg":"ok"}`,
+		`{"errcode":45009,"errmsg":"api freq out of limit"}`,
+		`{"errcode":0,"errmsg":"ok"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+
+	users := make([]string, MaxUsersPerSend+1)
+	for i := range users {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers(users...), TextMsg{Content: "开会"}))
+
+	first := s.nextOutcome()
+	var batchErr *BatchError
+	s.Require().True(errors.As(first.Err, &batchErr))
+	s.True(first.Rescheduled)
+	s.Equal(SendProgress{Pending: &Recipients{Users: []string{"user1000"}}}, first.Job.Progress)
+	s.NoError(s.nextOutcome().Err)
+
+	requests := s.transport.Requests()
+	s.Require().Len(requests, 3)
+	s.Equal("user1000", requests[2]["touser"])
+}
+
+func (s *schedulerTestSuite) TestShouldRescheduleFromFailedPart() {
+	s.transport.RegisterPost(urlSend,
+		`{"errcode":0,"errmsg":"ok"}`,
+		`{"errcode":45009,"errmsg":"api freq out of limit"}`,
+		`{"errcode":0,"errmsg":"ok"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"),
+		TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}, SendWithSplit()))
+
+	first := s.nextOutcome()
+	s.Error(first.Err)
+	s.True(first.Rescheduled)
+	s.Equal(SendProgress{Part: 1}, first.Job.Progress)
+	s.NoError(s.nextOutcome().Err)
+
+	requests := s.transport.Requests()
+	s.Require().Len(requests, 3)
+	s.Equal(requests[1]["text"], requests[2]["text"])
+}
+
+func (s *schedulerTestSuite) TestShouldMarkPartiallySentMessage() {
+	s.transport.RegisterPost(urlSend,
+		`{"errcode":0,"errmsg":"ok"}`,
+		`{"errcode":45009,"errmsg":"api freq out of limit"}`)
+	scheduler := s.newScheduler()
+	message := TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}
+
+	_, err := scheduler.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), message, SendWithSplit())
+	s.Error(err)
+	_, err = scheduler.SendOnce(context.Background(), "alert-1", ToUsers("zhangsan"), message, SendWithSplit())
+	s.True(errors.Is(err, ErrDuplicateMessage))
+	s.Len(s.transport.Requests(), 2)
+}
+
+func (s *schedulerTestSuite) TestShouldSendAgainWhenRescheduledWithSameKey() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+	first := s.nextOutcome()
+	s.Require().NoError(first.Err)
+
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"), TextMsg{Content: "再次提醒"}))
+	second := s.nextOutcome()
+	s.NoError(second.Err)
+	s.NotEqual(first.Job.IdempotencyId, second.Job.IdempotencyId)
+	s.Len(s.transport.Requests(), 2)
+}
+
+// 注册在release关闭前阻塞的发送接口，started在收到请求时关闭
+func (s *schedulerTestSuite) registerBlockingSendResponder(body string) (started, release chan struct{}) {
+	started, release = make(chan struct{}), make(chan struct{})
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+urlSend, func(req *http.Request) (*http.Response, error) {
+		close(started)
+		<-release
+		return httpmock.NewStringResponse(http.StatusOK, body), nil
+	})
+	return started, release
+}
+
+func (s *schedulerTestSuite) TestShouldNotRestoreJobCanceledDuringSend() {
+	started, release := s.registerBlockingSendResponder(`{"errcode":45009,"errmsg":"api freq out of limit"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+
+	<-started
+	canceled, err := scheduler.Cancel(context.Background(), "remind-1")
+	s.Require().NoError(err)
+	s.True(canceled)
+	close(release)
+
+	outcome := s.nextOutcome()
+	s.Error(outcome.Err)
+	s.False(outcome.Rescheduled)
+	_, err = scheduler.Get(context.Background(), "remind-1")
+	s.True(errors.Is(err, ErrJobNotFound))
+}
+
+func (s *schedulerTestSuite) TestShouldKeepJobRescheduledDuringSend() {
+	started, release := s.registerBlockingSendResponder(`{"errcode":0,"errmsg":"ok"}`)
+	scheduler := s.newScheduler()
+	s.run(scheduler)
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now(), ToUsers("zhangsan"), TextMsg{Content: "开会"}))
+
+	<-started
+	s.Require().NoError(scheduler.Schedule(context.Background(), "remind-1", time.Now().Add(time.Hour), ToUsers("zhangsan"), TextMsg{Content: "再次提醒"}))
+	close(release)
+
+	s.NoError(s.nextOutcome().Err)
+	job, err := scheduler.Get(context.Background(), "remind-1")
+	s.Require().NoError(err)
+	s.Equal(TextMsg{Content: "再次提醒"}, job.Message)
+}
+
+func (s *schedulerTestSuite) TestShouldPersistToFileStore() {
+	dir := s.T().TempDir()
+	store, err := NewFileJobStore(dir)
+	s.Require().NoError(err)
+	sendAt := time.Now().Add(-time.Second).Truncate(time.Second)
+	job := &ScheduledJob{
+		Key:      "task/2024:01",
+		To:       ToUsers("zhangsan"),
+		Message:  TaskCardMsg{Title: "审批", Description: "请审批", TaskId: "task1", Btn: []TaskCardBtn{{Key: "ok", Name: "同意"}}},
+		SendAt:   sendAt,
+		Progress: SendProgress{Part: 1},
+		option:   sendOption{agentId: 1000002, enableIdTrans: true},
+	}
+	s.Require().NoError(store.Save(context.Background(), job))
+
+	reopened, err := NewFileJobStore(dir)
+	s.Require().NoError(err)
+	due, err := reopened.Due(context.Background(), time.Now(), 10)
+	s.Require().NoError(err)
+	s.Require().Len(due, 1)
+	s.Equal(job.Key, due[0].Key)
+	s.Equal(job.Message, due[0].Message)
+	s.Equal(job.option, due[0].option)
+	s.Equal(job.Progress, due[0].Progress)
+	s.True(sendAt.Equal(due[0].SendAt))
+
+	deleted, err := reopened.Delete(context.Background(), job.Key)
+	s.NoError(err)
+	s.True(deleted)
+	_, err = reopened.Get(context.Background(), job.Key)
+	s.True(errors.Is(err, ErrJobNotFound))
+}
+
+func TestSchedulerTestSuite(t *testing.T) {
+	suite.Run(t, new(schedulerTestSuite))
+}
@@ -0,0 +1,342 @@
+package msg
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Message 应用消息
+//
+// MsgType返回消息类型，同时也是消息内容在请求数据中的字段名
+type Message interface {
+	MsgType() string
+}
+
+// Recipients 消息接收者，Users、Parties、Tags不能同时为空
+type Recipients struct {
+	Users   []string // 成员ID列表，最多支持1000个
+	Parties []int    // 部门ID列表，最多支持100个
+	Tags    []int    // 标签ID列表，最多支持100个
+	All     bool     // 向该企业应用的全部成员发送，为true时忽略其他字段
+}
+
+func NewRecipients(users []string, parties, tags []int) Recipients {
+	return Recipients{Users: users, Parties: parties, Tags: tags}
+}
+
+// ToUsers 发送给指定成员
+func ToUsers(users ...string) Recipients {
+	return Recipients{Users: users}
+}
+
+// ToAll 发送给应用可见范围内的全部成员
+func ToAll() Recipients {
+	return Recipients{All: true}
+}
+
+// IsEmpty 是否未指定任何接收者
+func (r Recipients) IsEmpty() bool {
+	return !r.All && len(r.Users) == 0 && len(r.Parties) == 0 && len(r.Tags) == 0
+}
+
+type SendOption func(option *sendOption)
+
+type sendOption struct {
+	agentId                int
+	safe                   int
+	enableIdTrans          bool
+	enableDuplicateCheck   bool
+	duplicateCheckInterval int
+	key                    string
+	concurrency            int
+	truncate               bool
+	split                  bool
+	skipValidation         bool
+}
+
+// SendWithAgentId 指定发送消息的应用id，默认使用Client的AgentId
+func SendWithAgentId(agentId int) SendOption {
+	return func(option *sendOption) {
+		option.agentId = agentId
+	}
+}
+
+// SendWithSafe 设置是否是保密消息
+//
+// 0表示可对外分享，1表示不能分享且内容显示水印，2表示仅限在企业内分享（仅mpnews支持）
+func SendWithSafe(safe int) SendOption {
+	return func(option *sendOption) {
+		option.safe = safe
+	}
+}
+
+// SendWithIdTrans 开启id转译
+func SendWithIdTrans() SendOption {
+	return func(option *sendOption) {
+		option.enableIdTrans = true
+	}
+}
+
+// SendWithDuplicateCheck 开启重复消息检查，interval为检查的时间间隔，单位为秒，默认1800秒，最大不超过4小时
+func SendWithDuplicateCheck(interval int) SendOption {
+	return func(option *sendOption) {
+		option.enableDuplicateCheck = true
+		option.duplicateCheckInterval = interval
+	}
+}
+
+// SendWithKey 指定消息的业务key，发送成功后记录到WechatMsg的SentLog中，
+// 之后可以通过RecallByKey撤回消息。未设置SentLog时忽略
+func SendWithKey(key string) SendOption {
+	return func(option *sendOption) {
+		option.key = key
+	}
+}
+
+// SendWithConcurrency 接收者超出单次发送上限而分批发送时，设置最大并发数，默认为1
+func SendWithConcurrency(concurrency int) SendOption {
+	return func(option *sendOption) {
+		option.concurrency = concurrency
+	}
+}
+
+// SendWithTruncate 发送前按照长度限制在字符边界截断超长的字段，被截断的字段以省略号结尾
+func SendWithTruncate() SendOption {
+	return func(option *sendOption) {
+		option.truncate = true
+	}
+}
+
+// SendWithSplit 将超长的文本消息、markdown消息拆分为多条消息依次发送
+//
+// 某条消息发送失败时不再发送后续的消息
+func SendWithSplit() SendOption {
+	return func(option *sendOption) {
+		option.split = true
+	}
+}
+
+// SendWithoutValidation 发送前不校验消息内容
+func SendWithoutValidation() SendOption {
+	return func(option *sendOption) {
+		option.skipValidation = true
+	}
+}
+
+// SendResult 消息发送结果
+type SendResult struct {
+	ErrCode        int      // 返回码
+	ErrMsg         string   // 对返回码的文本描述内容
+	MsgId          string   // 消息id，用于撤回应用消息
+	InvalidUser    []string // 不合法的userid
+	InvalidParty   []int    // 不合法的partyid
+	InvalidTag     []int    // 不合法的标签id
+	UnlicensedUser []string // 没有基础接口许可（包含已过期）的userid
+	ResponseCode   string   // 仅交互型的模板卡片消息返回，用于更新卡片，72小时内有效，且只能使用一次
+
+	MsgIds  []string      // 所有批次的消息id
+	Batches []BatchResult // 每个批次的发送结果
+}
+
+// HasInvalid 是否存在不合法的接收者
+func (r SendResult) HasInvalid() bool {
+	return len(r.InvalidUser) > 0 || len(r.InvalidParty) > 0 || len(r.InvalidTag) > 0
+}
+
+type sendResponse struct {
+	MsgError
+	UnlicensedUser string `json:"unlicenseduser"`
+	MsgId          string `json:"msgid"`
+	ResponseCode   string `json:"response_code"`
+}
+
+func (resp sendResponse) result() *SendResult {
+	return &SendResult{
+		ErrCode:        resp.ErrCode,
+		ErrMsg:         resp.ErrMsg,
+		MsgId:          resp.MsgId,
+		InvalidUser:    splitIds(resp.InvalidUser),
+		InvalidParty:   splitIntIds(resp.InvalidParty),
+		InvalidTag:     splitIntIds(resp.InvalidTag),
+		UnlicensedUser: splitIds(resp.UnlicensedUser),
+		ResponseCode:   resp.ResponseCode,
+	}
+}
+
+type sendPayload struct {
+	sendData
+	Safe                   int `json:"safe,omitempty"`
+	EnableIdTrans          int `json:"enable_id_trans,omitempty"`
+	EnableDuplicateCheck   int `json:"enable_duplicate_check,omitempty"`
+	DuplicateCheckInterval int `json:"duplicate_check_interval,omitempty"`
+
+	message Message
+}
+
+func (p sendPayload) MarshalJSON() ([]byte, error) {
+	type plain sendPayload
+	return marshalWithMessage(plain(p), p.message)
+}
+
+// 将消息内容以消息类型为字段名合并到请求数据中
+func marshalWithMessage(v interface{}, message Message) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields[message.MsgType()], err = json.Marshal(message); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+func (w WechatMsg) newSendOption(options ...SendOption) sendOption {
+	option := sendOption{agentId: w.Client.AgentId}
+	for _, opt := range options {
+		opt(&option)
+	}
+	return option
+}
+
+func (w WechatMsg) newSendPayload(to Recipients, message Message, option sendOption) sendPayload {
+	payload := sendPayload{message: message, Safe: option.safe}
+	if to.All {
+		payload.Init([]string{"@all"}, nil, nil, message.MsgType(), option.agentId)
+	} else {
+		payload.Init(to.Users, to.Parties, to.Tags, message.MsgType(), option.agentId)
+	}
+	if option.enableIdTrans {
+		payload.EnableIdTrans = 1
+	}
+	if option.enableDuplicateCheck {
+		payload.EnableDuplicateCheck = 1
+		payload.DuplicateCheckInterval = option.duplicateCheckInterval
+	}
+	return payload
+}
+
+// 发送消息
+func (w WechatMsg) send(ctx context.Context, data interface{}) (*sendResponse, error) {
+	resp := sendResponse{}
+	err := w.Client.Post(ctx, urlSend, nil, data, &resp, nil)
+	if _, ok := err.(*sendResponse); ok {
+		err = &resp.MsgError
+	}
+	return &resp, err
+}
+
+// 发送前对消息进行截断、拆分和校验
+func (w WechatMsg) prepareMessages(message Message, option sendOption) ([]Message, error) {
+	if option.truncate {
+		message = Truncate(message)
+	}
+
+	messages := []Message{message}
+	if option.split {
+		messages = Split(message)
+	}
+
+	if !option.skipValidation {
+		for _, message := range messages {
+			if err := Validate(message); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return messages, nil
+}
+
+// Send 发送应用消息
+//
+// 部分接收者不合法时企业微信仍会发送给其余接收者，此时不返回错误，不合法的接收者列表
+// 通过SendResult返回。
+//
+// 接收者超出单次发送上限（成员1000个，部门、标签各100个）时自动分批发送，
+// SendResult为所有批次合并后的结果；存在失败的批次时返回*BatchError。
+//
+// 发送前会校验消息内容，违反长度等限制时返回*ValidationError，其中包含所有违反的限制；
+// 可以通过SendWithTruncate截断超长字段，或通过SendWithSplit拆分超长的文本。
+//
+// 通过SendWithKey指定业务key时，发送成功后会记录所有批次的消息id；记录失败时返回错误，
+// 此时消息已发送，SendResult中包含消息id。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90236
+func (w WechatMsg) Send(ctx context.Context, to Recipients, message Message, options ...SendOption) (*SendResult, error) {
+	return w.sendWithOption(ctx, to, message, w.newSendOption(options...))
+}
+
+func (w WechatMsg) sendWithOption(ctx context.Context, to Recipients, message Message, option sendOption) (*SendResult, error) {
+	result, _, err := w.sendWithProgress(ctx, to, message, option, SendProgress{})
+	return result, err
+}
+
+// 从progress继续发送，返回本次发送的结果及更新后的进度
+//
+// 某一部分只有部分批次失败时，进度中记录失败批次的接收者，下次只向其发送该部分；
+// 该部分的批次全部失败时进度不变
+func (w WechatMsg) sendWithProgress(ctx context.Context, to Recipients, message Message, option sendOption, progress SendProgress) (*SendResult, SendProgress, error) {
+	messages, err := w.prepareMessages(message, option)
+	if err != nil {
+		return &SendResult{}, progress, err
+	}
+
+	var batches []BatchResult
+	for progress.Part < len(messages) {
+		target := to
+		if progress.Pending != nil {
+			target = *progress.Pending
+		}
+		results := w.sendBatches(ctx, SplitRecipients(target), messages[progress.Part], option)
+		batches = append(batches, results...)
+		if failed := failedBatches(results); len(failed) > 0 {
+			if len(failed) < len(results) {
+				pending := batchRecipients(failed)
+				progress.Pending = &pending
+			}
+			break
+		}
+		progress = SendProgress{Part: progress.Part + 1}
+	}
+	result, err := mergeBatchResults(batches)
+
+	if logErr := w.logSent(ctx, option.key, result.MsgIds); logErr != nil && err == nil {
+		err = logErr
+	}
+	return result, progress, err
+}
+
+// 兼容旧接口，发送成功但存在不合法的接收者时返回MsgError
+//
+// 旧接口发送前不校验消息内容，超出限制时由服务端处理，与校验引入前的行为一致；
+// 发送失败时直接返回Send的错误，分批发送时为*BatchError，其中包含各批次的结果
+func (w WechatMsg) sendLegacy(ctx context.Context, to Recipients, message Message, options ...SendOption) error {
+	options = append([]SendOption{SendWithoutValidation()}, options...)
+	result, err := w.Send(ctx, to, message, options...)
+	if err != nil {
+		return err
+	}
+	if result.HasInvalid() {
+		msgErr := MsgError{
+			InvalidUser:  strings.Join(result.InvalidUser, "|"),
+			InvalidParty: intSliceToString(result.InvalidParty, "|"),
+			InvalidTag:   intSliceToString(result.InvalidTag, "|"),
+		}
+		msgErr.ErrCode = result.ErrCode
+		msgErr.ErrMsg = result.ErrMsg
+		return &msgErr
+	}
+	return nil
+}
+
+func safeValue(safe bool) int {
+	if safe {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package msg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/testdata"
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+// 使用模拟接口的测试，每个测试前重新创建Transport
+type mockTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	msg       *WechatMsg
+}
+
+func (s *mockTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+	conf := testdata.TestConf
+	client := wecom.NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+	s.msg = NewWechatMsg(client)
+}
+
+type sendTestSuite struct {
+	mockTestSuite
+}
+
+func (s *sendTestSuite) TestShouldBuildPayloadByMessageType() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	result, err := s.msg.Send(context.Background(), NewRecipients([]string{"u1", "u2"}, []int{1}, nil),
+		TextMsg{Content: "hello"}, SendWithSafe(1), SendWithDuplicateCheck(600))
+
+	s.NoError(err)
+	s.Equal("msgid1", result.MsgId)
+	s.Require().Len(s.transport.Requests(), 1)
+	payload := s.transport.Requests()[0]
+	s.Equal("text", payload["msgtype"])
+	s.Equal("u1|u2", payload["touser"])
+	s.Equal("1", payload["toparty"])
+	s.Equal(map[string]any{"content": "hello"}, payload["text"])
+	s.Equal(float64(1), payload["safe"])
+	s.Equal(float64(1), payload["enable_duplicate_check"])
+	s.Equal(float64(600), payload["duplicate_check_interval"])
+	s.Equal(float64(testdata.TestConf.AgentId), payload["agentid"])
+	s.NotContains(payload, "totag")
+}
+
+func (s *sendTestSuite) TestShouldSendToAll() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	_, err := s.msg.Send(context.Background(), ToAll(), MarkdownMsg{Content: "**hi**"})
+
+	s.NoError(err)
+	s.Equal("@all", s.transport.Requests()[0]["touser"])
+	s.Equal("markdown", s.transport.Requests()[0]["msgtype"])
+}
+
+func (s *sendTestSuite) TestShouldParseInvalidRecipients() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","invaliduser":"u1|u2","invalidparty":"2|3","invalidtag":"4","unlicenseduser":"u3","msgid":"msgid2","response_code":"code"}`)
+
+	result, err := s.msg.Send(context.Background(), ToUsers("u1", "u2", "u3"), TextMsg{Content: "hello"})
+
+	s.NoError(err)
+	s.True(result.HasInvalid())
+	s.Equal([]string{"u1", "u2"}, result.InvalidUser)
+	s.Equal([]int{2, 3}, result.InvalidParty)
+	s.Equal([]int{4}, result.InvalidTag)
+	s.Equal([]string{"u3"}, result.UnlicensedUser)
+	s.Equal("msgid2", result.MsgId)
+	s.Equal("code", result.ResponseCode)
+}
+
+func (s *sendTestSuite) TestShouldReturnMsgErrorWhenFailed() {
+	s.transport.RegisterPost(urlSend, `{"errcode":81013,"errmsg":"user & party & tag all invalid","invaliduser":"u1"}`)
+
+	result, err := s.msg.Send(context.Background(), ToUsers("u1"), TextMsg{Content: "hello"})
+
+	s.Error(err)
+	msgErr, ok := err.(*MsgError)
+	s.Require().True(ok)
+	s.Equal(81013, msgErr.GetErrCode())
+	s.Equal(81013, result.ErrCode)
+	s.Equal([]string{"u1"}, result.InvalidUser)
+}
+
+func (s *sendTestSuite) TestShouldReturnMsgErrorFromLegacyMethodWhenInvalid() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","invaliduser":"u1"}`)
+
+	err := s.msg.SendTaskCard(context.Background(), []string{"u1"}, nil, nil, TaskCardMsg{
+		Title:       "title",
+		Description: "description",
+		TaskId:      "task-1",
+		Btn:         []TaskCardBtn{{Key: "ok", Name: "OK"}},
+	})
+
+	msgErr, ok := err.(*MsgError)
+	s.Require().True(ok)
+	s.Equal("u1", msgErr.InvalidUser)
+	s.Equal("task-1", s.transport.Requests()[0]["taskcard"].(map[string]any)["task_id"])
+}
+
+func (s *sendTestSuite) TestShouldReturnNilFromLegacyMethodWhenSucceeded() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	err := s.msg.SendVoice(context.Background(), []string{"u1"}, nil, nil, VoiceMsg{MediaId: "media"})
+
+	s.NoError(err)
+	s.NotContains(s.transport.Requests()[0], "safe")
+}
+
+func TestSendTestSuite(t *testing.T) {
+	suite.Run(t, new(sendTestSuite))
+}
@@ -0,0 +1,110 @@
+package msg
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+const urlGetStatistics = "/cgi-bin/message/get_statistics"
+
+// StatisticsRange 消息统计的时间范围
+type StatisticsRange int
+
+const (
+	StatisticsToday     StatisticsRange = 0 // 当天
+	StatisticsYesterday StatisticsRange = 1 // 昨天
+)
+
+func (r StatisticsRange) String() string {
+	switch r {
+	case StatisticsToday:
+		return "today"
+	case StatisticsYesterday:
+		return "yesterday"
+	}
+	return "unknown"
+}
+
+// Date 统计范围对应的日期（当天零点），now为当前时间
+func (r StatisticsRange) Date(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day-int(r), 0, 0, 0, 0, now.Location())
+}
+
+// MessageStatistics 应用的消息发送量
+type MessageStatistics struct {
+	AgentId int    `json:"agentid"`  // 应用id
+	AppName string `json:"app_name"` // 应用名
+	Count   int    `json:"count"`    // 发消息成功人次
+}
+
+// StatisticsReport 多个应用的消息发送量汇总
+type StatisticsReport struct {
+	Range StatisticsRange     // 统计的时间范围
+	Apps  []MessageStatistics // 各应用的消息发送量，按发送量降序排列
+	Total int                 // 所有应用的消息发送量之和
+}
+
+// Count 指定应用的消息发送量，应用不在报告中时返回0
+func (r StatisticsReport) Count(agentId int) int {
+	for _, app := range r.Apps {
+		if app.AgentId == agentId {
+			return app.Count
+		}
+	}
+	return 0
+}
+
+// GetStatistics 查询应用消息发送统计
+//
+// 返回企业所有应用在指定时间范围内的发消息成功人次
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/92369
+func (w WechatMsg) GetStatistics(ctx context.Context, timeRange StatisticsRange) ([]MessageStatistics, error) {
+	data := struct {
+		TimeType StatisticsRange `json:"time_type"`
+	}{
+		TimeType: timeRange,
+	}
+
+	out := struct {
+		Statistics []MessageStatistics `json:"statistics"`
+	}{}
+	err := w.Client.Post(ctx, urlGetStatistics, nil, data, nil, &out)
+	return out.Statistics, err
+}
+
+// GetStatisticsReport 查询指定应用的消息发送量并汇总
+//
+// apps通常为Wecom.App.GetAllApp的返回值；没有发送记录的应用发送量为0，
+// 不在apps中的应用不计入汇总。
+func (w WechatMsg) GetStatisticsReport(ctx context.Context, timeRange StatisticsRange, apps []wecom.AppIntro) (*StatisticsReport, error) {
+	statistics, err := w.GetStatistics(ctx, timeRange)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatisticsReport(timeRange, apps, statistics), nil
+}
+
+// NewStatisticsReport 按照应用列表汇总消息发送统计
+func NewStatisticsReport(timeRange StatisticsRange, apps []wecom.AppIntro, statistics []MessageStatistics) *StatisticsReport {
+	counts := make(map[int]int, len(statistics))
+	for _, item := range statistics {
+		counts[item.AgentId] += item.Count
+	}
+
+	report := &StatisticsReport{Range: timeRange, Apps: make([]MessageStatistics, 0, len(apps))}
+	for _, app := range apps {
+		count := counts[app.AgentId]
+		report.Apps = append(report.Apps, MessageStatistics{AgentId: app.AgentId, AppName: app.Name, Count: count})
+		report.Total += count
+	}
+
+	sort.SliceStable(report.Apps, func(i, j int) bool {
+		return report.Apps[i].Count > report.Apps[j].Count
+	})
+	return report
+}
@@ -0,0 +1,57 @@
+package msg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/wecom"
+)
+
+type statisticsTestSuite struct {
+	mockTestSuite
+}
+
+func (s *statisticsTestSuite) TestShouldGetStatistics() {
+	s.transport.RegisterPost(urlGetStatistics,
+		`{"errcode":0,"errmsg":"ok","statistics":[{"agentid":1000002,"app_name":"应用1","count":101}]}`)
+
+	statistics, err := s.msg.GetStatistics(context.Background(), StatisticsYesterday)
+
+	s.Require().NoError(err)
+	s.Equal([]MessageStatistics{{AgentId: 1000002, AppName: "应用1", Count: 101}}, statistics)
+	s.Equal(map[string]any{"time_type": float64(1)}, s.transport.Requests()[0])
+}
+
+func (s *statisticsTestSuite) TestShouldAggregateByApps() {
+	s.transport.RegisterPost(urlGetStatistics, `{"errcode":0,"errmsg":"ok","statistics":[`+
+		`{"agentid":1000002,"app_name":"应用1","count":101},{"agentid":1000003,"app_name":"应用2","count":7},`+
+		`{"agentid":1000009,"app_name":"其他","count":50}]}`)
+	apps := []wecom.AppIntro{{AgentId: 1000003, Name: "应用2"}, {AgentId: 1000002, Name: "应用1"}, {AgentId: 1000004, Name: "应用3"}}
+
+	report, err := s.msg.GetStatisticsReport(context.Background(), StatisticsToday, apps)
+
+	s.Require().NoError(err)
+	s.Equal(108, report.Total)
+	s.Equal([]MessageStatistics{
+		{AgentId: 1000002, AppName: "应用1", Count: 101},
+		{AgentId: 1000003, AppName: "应用2", Count: 7},
+		{AgentId: 1000004, AppName: "应用3", Count: 0},
+	}, report.Apps)
+	s.Equal(7, report.Count(1000003))
+	s.Equal(0, report.Count(1000009))
+}
+
+func (s *statisticsTestSuite) TestShouldResolveRangeDate() {
+	now := time.Date(2024, 3, 1, 15, 4, 5, 0, time.UTC)
+
+	s.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), StatisticsToday.Date(now))
+	s.Equal(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), StatisticsYesterday.Date(now))
+	s.Equal("yesterday", StatisticsYesterday.String())
+}
+
+func TestStatisticsTestSuite(t *testing.T) {
+	suite.Run(t, new(statisticsTestSuite))
+}
@@ -0,0 +1,367 @@
+package msg
+
+import (
+	"context"
+)
+
+const (
+	urlUpdateTemplateCard = "/cgi-bin/message/update_template_card"
+	urlUpdateTaskCard     = "/cgi-bin/message/update_taskcard"
+)
+
+// 模板卡片类型
+const (
+	CardTypeTextNotice          = "text_notice"          // 文本通知型
+	CardTypeNewsNotice          = "news_notice"          // 图文展示型
+	CardTypeButtonInteraction   = "button_interaction"   // 按钮交互型
+	CardTypeVoteInteraction     = "vote_interaction"     // 投票选择型
+	CardTypeMultipleInteraction = "multiple_interaction" // 多项选择型
+)
+
+type CardSource struct {
+	IconUrl   string `json:"icon_url,omitempty"`   // 来源图片的url
+	Desc      string `json:"desc,omitempty"`       // 来源图片的描述，建议不超过20个字
+	DescColor int    `json:"desc_color,omitempty"` // 来源文字的颜色，目前支持：0(默认) 灰色，1 黑色，2 红色，3 绿色
+}
+
+type CardActionMenuItem struct {
+	Text string `json:"text"` // 操作的描述文案
+	Key  string `json:"key"`  // 操作key值，用户点击后，会产生回调事件将本参数作为EventKey返回，最长支持1024字节，不可重复
+}
+
+type CardActionMenu struct {
+	Desc       string               `json:"desc,omitempty"` // 更多操作界面的描述
+	ActionList []CardActionMenuItem `json:"action_list"`    // 操作列表，列表长度取值范围为 [1, 3]
+}
+
+type CardMainTitle struct {
+	Title string `json:"title,omitempty"` // 一级标题，建议不超过36个字
+	Desc  string `json:"desc,omitempty"`  // 标题辅助信息，建议不超过44个字
+}
+
+type CardQuoteArea struct {
+	// 引用文献样式区域点击事件，0或不填代表没有点击事件，1 代表跳转url，2 代表跳转小程序
+	Type int `json:"type,omitempty"`
+
+	// 点击跳转的url，type是1时必填
+	Url string `json:"url,omitempty"`
+
+	// 点击跳转的小程序的appid，必须是与当前应用关联的小程序，type是2时必填
+	AppId string `json:"appid,omitempty"`
+
+	// 点击跳转的小程序的pagepath，type是2时选填
+	PagePath string `json:"pagepath,omitempty"`
+
+	// 引用文献样式的标题
+	Title string `json:"title,omitempty"`
+
+	// 引用文献样式的引用文案
+	QuoteText string `json:"quote_text,omitempty"`
+}
+
+type CardEmphasisContent struct {
+	Title string `json:"title,omitempty"` // 关键数据样式的数据内容，建议不超过14个字
+	Desc  string `json:"desc,omitempty"`  // 关键数据样式的数据描述内容，建议不超过22个字
+}
+
+type CardHorizontalContent struct {
+	// 链接类型，0或不填代表不是链接，1 代表跳转url，2 代表下载附件，3 代表点击跳转成员详情
+	Type int `json:"type,omitempty"`
+
+	// 二级标题，建议不超过5个字
+	KeyName string `json:"keyname"`
+
+	// 二级文本，如果horizontal_content_list.type是2，该字段代表文件名称（要包含文件类型），建议不超过30个字
+	Value string `json:"value,omitempty"`
+
+	// 链接跳转的url，type是1时必填
+	Url string `json:"url,omitempty"`
+
+	// 附件的media_id，type是2时必填
+	MediaId string `json:"media_id,omitempty"`
+
+	// 成员详情的userid，type是3时必填
+	UserId string `json:"userid,omitempty"`
+}
+
+type CardJump struct {
+	// 跳转链接类型，0或不填代表不是链接，1 代表跳转url，2 代表跳转小程序
+	Type int `json:"type,omitempty"`
+
+	// 跳转链接样式的文案内容，建议不超过18个字
+	Title string `json:"title"`
+
+	// 跳转链接的url，type是1时必填
+	Url string `json:"url,omitempty"`
+
+	// 跳转链接的小程序的appid，必须是与当前应用关联的小程序，type是2时必填
+	AppId string `json:"appid,omitempty"`
+
+	// 跳转链接的小程序的pagepath，type是2时选填
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+type CardAction struct {
+	// 卡片跳转类型，0或不填代表不是链接，1 代表跳转url，2 代表打开小程序。
+	// text_notice必须填写1或2
+	Type int `json:"type,omitempty"`
+
+	// 跳转事件的url，type是1时必填
+	Url string `json:"url,omitempty"`
+
+	// 跳转事件的小程序的appid，必须是与当前应用关联的小程序，type是2时必填
+	AppId string `json:"appid,omitempty"`
+
+	// 跳转事件的小程序的pagepath，type是2时选填
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+type CardImage struct {
+	Url         string  `json:"url"`                    // 图片的url
+	AspectRatio float64 `json:"aspect_ratio,omitempty"` // 图片的宽高比，宽高比要小于2.25，大于1.3，不填该参数默认1.3
+}
+
+type CardImageTextArea struct {
+	// 左图右文样式区域点击事件，0或不填代表没有点击事件，1 代表跳转url，2 代表跳转小程序
+	Type int `json:"type,omitempty"`
+
+	// 点击跳转的url，type是1时必填
+	Url string `json:"url,omitempty"`
+
+	// 点击跳转的小程序的appid，type是2时必填
+	AppId string `json:"appid,omitempty"`
+
+	// 点击跳转的小程序的pagepath，type是2时选填
+	PagePath string `json:"pagepath,omitempty"`
+
+	// 左图右文样式的标题
+	Title string `json:"title,omitempty"`
+
+	// 左图右文样式的描述
+	Desc string `json:"desc,omitempty"`
+
+	// 左图右文样式的图片url
+	ImageUrl string `json:"image_url"`
+}
+
+type CardVerticalContent struct {
+	Title string `json:"title"`          // 卡片二级标题，建议不超过38个字
+	Desc  string `json:"desc,omitempty"` // 二级普通文本，建议不超过160个字
+}
+
+type CardOption struct {
+	Id        string `json:"id"`                   // 选项id，用户提交选项后，会产生回调事件，回调事件会带上该id值表示该选项，最长支持128字节，不可重复
+	Text      string `json:"text"`                 // 选项文案描述，建议不超过17个字
+	IsChecked bool   `json:"is_checked,omitempty"` // 该选项是否要默认选中，仅投票选择型使用
+}
+
+type CardButtonSelection struct {
+	QuestionKey string       `json:"question_key"`          // 下拉式的选择器的key，用户提交选项后，会产生回调事件，回调事件会带上该key值表示该题，最长支持1024字节
+	Title       string       `json:"title,omitempty"`       // 下拉式的选择器左边的标题
+	OptionList  []CardOption `json:"option_list"`           // 选项列表，下拉选项不超过 10 个，最少1个
+	SelectedId  string       `json:"selected_id,omitempty"` // 默认选定的id，不填或错填默认第一个
+}
+
+type CardButton struct {
+	// 按钮点击事件类型，0 或不填代表回调点击事件，1 代表跳转url
+	Type int `json:"type,omitempty"`
+
+	// 按钮文案，建议不超过10个字
+	Text string `json:"text"`
+
+	// 按钮样式，目前可填1~4，不填或错填默认1
+	Style int `json:"style,omitempty"`
+
+	// 按钮key值，用户点击后，会产生回调事件将本参数作为EventKey返回，回调事件会带上该key值，
+	// 最长支持1024字节，不可重复，type是0时必填
+	Key string `json:"key,omitempty"`
+
+	// 跳转事件的url，type是1时必填
+	Url string `json:"url,omitempty"`
+}
+
+type CardCheckbox struct {
+	QuestionKey string       `json:"question_key"`   // 选择题key值，用户提交选项后，会产生回调事件，回调事件会带上该key值表示该题，最长支持1024字节
+	OptionList  []CardOption `json:"option_list"`    // 选项list，选项个数不超过 20 个，最少1个
+	Mode        int          `json:"mode,omitempty"` // 选择题模式，单选：0，多选：1，不填默认0
+}
+
+type CardSelect struct {
+	QuestionKey string       `json:"question_key"`          // 下拉式的选择器题目的key，用户提交选项后，会产生回调事件，回调事件会带上该key值表示该题，最长支持1024字节，不可重复
+	Title       string       `json:"title,omitempty"`       // 下拉式的选择器上面的title
+	SelectedId  string       `json:"selected_id,omitempty"` // 默认选定的id，不填或错填默认第一个
+	OptionList  []CardOption `json:"option_list"`           // 选项列表，下拉选项不超过 10 个，最少1个
+}
+
+type CardSubmitButton struct {
+	Text string `json:"text"` // 按钮文案，建议不超过10个字
+	Key  string `json:"key"`  // 提交按钮的key，会产生回调事件将本参数作为EventKey返回，最长支持1024字节
+}
+
+// TemplateCardMsg 模板卡片消息
+//
+// 不同类型的卡片支持的字段不同，详见各字段说明。
+// 交互型卡片（按钮交互型、投票选择型、多项选择型）必须填写TaskId。
+type TemplateCardMsg struct {
+	// 模板卡片类型，取值见CardType常量
+	CardType string `json:"card_type"`
+
+	// 卡片来源样式信息，不需要来源样式可不填写
+	Source *CardSource `json:"source,omitempty"`
+
+	// 卡片右上角更多操作按钮
+	ActionMenu *CardActionMenu `json:"action_menu,omitempty"`
+
+	// 任务id，同一个应用任务id不能重复，只能由数字、字母和“_-@”组成，最长128字节。
+	// 填写了action_menu时或交互型卡片必填
+	TaskId string `json:"task_id,omitempty"`
+
+	// 一级标题
+	MainTitle *CardMainTitle `json:"main_title,omitempty"`
+
+	// 引用文献样式
+	QuoteArea *CardQuoteArea `json:"quote_area,omitempty"`
+
+	// 关键数据样式，仅文本通知型
+	EmphasisContent *CardEmphasisContent `json:"emphasis_content,omitempty"`
+
+	// 二级普通文本，建议不超过160个字，仅文本通知型、按钮交互型
+	SubTitleText string `json:"sub_title_text,omitempty"`
+
+	// 二级标题+文本列表，列表长度不超过6
+	HorizontalContentList []CardHorizontalContent `json:"horizontal_content_list,omitempty"`
+
+	// 跳转指引样式的列表，列表长度不超过3，仅通知型
+	JumpList []CardJump `json:"jump_list,omitempty"`
+
+	// 整体卡片的点击跳转事件，通知型必填
+	CardAction *CardAction `json:"card_action,omitempty"`
+
+	// 图片样式，仅图文展示型
+	CardImage *CardImage `json:"card_image,omitempty"`
+
+	// 左图右文样式，仅图文展示型
+	ImageTextArea *CardImageTextArea `json:"image_text_area,omitempty"`
+
+	// 卡片二级垂直内容，列表长度不超过4，仅图文展示型
+	VerticalContentList []CardVerticalContent `json:"vertical_content_list,omitempty"`
+
+	// 下拉式的选择器，仅按钮交互型
+	ButtonSelection *CardButtonSelection `json:"button_selection,omitempty"`
+
+	// 按钮列表，列表长度不超过6，仅按钮交互型
+	ButtonList []CardButton `json:"button_list,omitempty"`
+
+	// 选择题样式，仅投票选择型
+	Checkbox *CardCheckbox `json:"checkbox,omitempty"`
+
+	// 下拉式的选择器列表，列表长度不超过3，仅多项选择型
+	SelectList []CardSelect `json:"select_list,omitempty"`
+
+	// 提交按钮样式，投票选择型、多项选择型必填
+	SubmitButton *CardSubmitButton `json:"submit_button,omitempty"`
+
+	// 按钮替换文案，仅更新卡片时使用，填写后卡片的按钮区域将替换为该文案
+	ReplaceText string `json:"replace_text,omitempty"`
+}
+
+func (TemplateCardMsg) MsgType() string {
+	return "template_card"
+}
+
+// SendTemplateCard 模板卡片消息
+//
+// 交互型卡片发送成功后SendResult中包含ResponseCode，用于更新卡片
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/90236
+func (w WechatMsg) SendTemplateCard(ctx context.Context, to Recipients, card TemplateCardMsg, options ...SendOption) (*SendResult, error) {
+	return w.Send(ctx, to, card, options...)
+}
+
+type updateCardData struct {
+	UserIds       []string `json:"userids,omitempty"`         // 企业的成员ID列表，最多支持1000个
+	PartyIds      []int    `json:"partyids,omitempty"`        // 企业的部门ID列表，最多支持100个
+	TagIds        []int    `json:"tagids,omitempty"`          // 企业的标签ID列表，最多支持100个
+	AtAll         int      `json:"atall,omitempty"`           // 更新整个任务接收人员
+	AgentId       int      `json:"agentid"`                   // 应用的agentid
+	ResponseCode  string   `json:"response_code"`             // 更新卡片所需要消费的code，可通过发消息接口和回调接口返回值获取，一个code只能调用一次该接口，且只能在72小时内调用
+	EnableIdTrans int      `json:"enable_id_trans,omitempty"` // 是否开启id转译
+}
+
+func (data *updateCardData) Init(to Recipients, agentId int, responseCode string) {
+	if to.All {
+		data.AtAll = 1
+	} else {
+		data.UserIds = to.Users
+		data.PartyIds = to.Parties
+		data.TagIds = to.Tags
+	}
+	data.AgentId = agentId
+	data.ResponseCode = responseCode
+}
+
+// UpdateTemplateCardButton 更新模版卡片消息的按钮为不可点击状态
+//
+// 返回不合法的userid列表
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/94888
+func (w WechatMsg) UpdateTemplateCardButton(ctx context.Context, to Recipients, responseCode, replaceName string) (invalidUser []string, err error) {
+	data := struct {
+		updateCardData
+		Button struct {
+			ReplaceName string `json:"replace_name"` // 需要更新的按钮的文案
+		} `json:"button"`
+	}{}
+	data.Init(to, w.Client.AgentId, responseCode)
+	data.Button.ReplaceName = replaceName
+
+	return w.updateCard(ctx, urlUpdateTemplateCard, data)
+}
+
+// UpdateTemplateCard 更新为新的模版卡片消息
+//
+// 返回不合法的userid列表
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/94888
+func (w WechatMsg) UpdateTemplateCard(ctx context.Context, to Recipients, responseCode string, card TemplateCardMsg) (invalidUser []string, err error) {
+	data := struct {
+		updateCardData
+		TemplateCard TemplateCardMsg `json:"template_card"`
+	}{}
+	data.Init(to, w.Client.AgentId, responseCode)
+	data.TemplateCard = card
+
+	return w.updateCard(ctx, urlUpdateTemplateCard, data)
+}
+
+// UpdateTaskCard 更新任务卡片消息状态
+//
+// 将指定成员的任务卡片按钮更新为已处理状态，clickedKey为已点击按钮的key，可为空。
+// 返回不合法的userid列表
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/91579
+func (w WechatMsg) UpdateTaskCard(ctx context.Context, userIds []string, taskId, replaceName, clickedKey string) (invalidUser []string, err error) {
+	data := struct {
+		UserIds     []string `json:"userids"`               // 企业的成员ID列表，最多支持1000个
+		AgentId     int      `json:"agentid"`               // 应用的agentid
+		TaskId      string   `json:"task_id"`               // 发送任务卡片消息时指定的task_id
+		ReplaceName string   `json:"replace_name"`          // 设置指定的按钮为已点击状态，按钮名称替换为该值
+		ClickedKey  string   `json:"clicked_key,omitempty"` // 已点击按钮的key
+	}{
+		UserIds:     userIds,
+		AgentId:     w.Client.AgentId,
+		TaskId:      taskId,
+		ReplaceName: replaceName,
+		ClickedKey:  clickedKey,
+	}
+
+	return w.updateCard(ctx, urlUpdateTaskCard, data)
+}
+
+func (w WechatMsg) updateCard(ctx context.Context, url string, data interface{}) ([]string, error) {
+	out := struct {
+		InvalidUser []string `json:"invaliduser"`
+	}{}
+	err := w.Client.Post(ctx, url, nil, data, nil, &out)
+	return out.InvalidUser, err
+}
@@ -0,0 +1,78 @@
+package msg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type templateCardTestSuite struct {
+	mockTestSuite
+}
+
+func (s *templateCardTestSuite) TestShouldSendButtonInteractionCard() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1","response_code":"code1"}`)
+
+	result, err := s.msg.SendTemplateCard(context.Background(), ToUsers("u1"), TemplateCardMsg{
+		CardType:  CardTypeButtonInteraction,
+		TaskId:    "task-1",
+		MainTitle: &CardMainTitle{Title: "请审批"},
+		ButtonList: []CardButton{
+			{Text: "同意", Key: "approve"},
+			{Text: "拒绝", Key: "reject", Style: 2},
+		},
+	})
+
+	s.NoError(err)
+	s.Equal("code1", result.ResponseCode)
+	s.Equal("template_card", s.transport.Requests()[0]["msgtype"])
+	card := s.transport.Requests()[0]["template_card"].(map[string]any)
+	s.Equal("button_interaction", card["card_type"])
+	s.Equal("task-1", card["task_id"])
+	s.Len(card["button_list"], 2)
+	s.NotContains(card, "checkbox")
+}
+
+func (s *templateCardTestSuite) TestShouldUpdateTemplateCardButton() {
+	s.transport.RegisterPost(urlUpdateTemplateCard, `{"errcode":0,"errmsg":"ok","invaliduser":["u2"]}`)
+
+	invalidUser, err := s.msg.UpdateTemplateCardButton(context.Background(), ToUsers("u1", "u2"), "code1", "已处理")
+
+	s.NoError(err)
+	s.Equal([]string{"u2"}, invalidUser)
+	s.Equal("code1", s.transport.Requests()[0]["response_code"])
+	s.Equal(map[string]any{"replace_name": "已处理"}, s.transport.Requests()[0]["button"])
+	s.Equal([]any{"u1", "u2"}, s.transport.Requests()[0]["userids"])
+}
+
+func (s *templateCardTestSuite) TestShouldUpdateTemplateCardForAll() {
+	s.transport.RegisterPost(urlUpdateTemplateCard, `{"errcode":0,"errmsg":"ok"}`)
+
+	_, err := s.msg.UpdateTemplateCard(context.Background(), ToAll(), "code1", TemplateCardMsg{
+		CardType:    CardTypeTextNotice,
+		MainTitle:   &CardMainTitle{Title: "已完成"},
+		CardAction:  &CardAction{Type: 1, Url: "https://work.weixin.qq.com"},
+		ReplaceText: "已处理",
+	})
+
+	s.NoError(err)
+	s.Equal(float64(1), s.transport.Requests()[0]["atall"])
+	card := s.transport.Requests()[0]["template_card"].(map[string]any)
+	s.Equal("已处理", card["replace_text"])
+}
+
+func (s *templateCardTestSuite) TestShouldUpdateTaskCard() {
+	s.transport.RegisterPost(urlUpdateTaskCard, `{"errcode":0,"errmsg":"ok","invaliduser":[]}`)
+
+	invalidUser, err := s.msg.UpdateTaskCard(context.Background(), []string{"u1"}, "task-1", "已同意", "approve")
+
+	s.NoError(err)
+	s.Empty(invalidUser)
+	s.Equal("task-1", s.transport.Requests()[0]["task_id"])
+	s.Equal("approve", s.transport.Requests()[0]["clicked_key"])
+}
+
+func TestTemplateCardTestSuite(t *testing.T) {
+	suite.Run(t, new(templateCardTestSuite))
+}
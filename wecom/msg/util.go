@@ -3,6 +3,7 @@ package msg
 import (
 	"bytes"
 	"strconv"
+	"strings"
 )
 
 func intSliceToString(v []int, step string) string {
@@ -17,3 +18,20 @@ func intSliceToString(v []int, step string) string {
 
 	return buffer.String()
 }
+
+func splitIds(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
+
+func splitIntIds(s string) []int {
+	var ids []int
+	for _, v := range splitIds(s) {
+		if id, err := strconv.Atoi(v); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,376 @@
+package msg
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 消息内容长度限制
+const (
+	MaxTextBytes        = 2048       // 文本消息、markdown消息内容的最大字节数
+	MaxTitleBytes       = 128        // 标题的最大字节数
+	MaxDescriptionBytes = 512        // 描述的最大字节数
+	MaxUrlBytes         = 2048       // 链接的最大字节数
+	MaxMPContentBytes   = 666 * 1024 // mpnews图文内容的最大字节数
+	MaxArticles         = 8          // 图文消息的最大图文数
+	MaxNoticeItems      = 10         // 小程序通知消息的最大键值对数
+	MaxTaskCardButtons  = 2          // 任务卡片消息的最大按钮数
+	MaxKeyBytes         = 128        // 任务卡片的task_id及按钮key的最大字节数
+)
+
+// Ellipsis 截断时追加的省略号
+const Ellipsis = "…"
+
+var keyPattern = regexp.MustCompile(`^[0-9A-Za-z_\-@.]*$`)
+
+// Violation 违反的消息内容限制
+type Violation struct {
+	Field   string // 字段名，如articles[0].title
+	Message string // 违反的限制
+}
+
+func (v Violation) String() string {
+	return v.Field + " " + v.Message
+}
+
+// ValidationError 消息内容校验失败，包含所有违反的限制
+type ValidationError struct {
+	MsgType    string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	items := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		items[i] = v.String()
+	}
+	return fmt.Sprintf("invalid %s message: %s", e.MsgType, strings.Join(items, "; "))
+}
+
+// Validator 可校验内容的消息
+type Validator interface {
+	Validate() error
+}
+
+// Validate 校验消息内容，消息未实现Validator时不做校验
+func Validate(message Message) error {
+	if v, ok := message.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+type truncater interface {
+	truncated() Message
+}
+
+// Truncate 按照长度限制在字符边界截断消息内容，被截断的字段以省略号结尾
+//
+// 仅处理超长的字段，其他违反的限制（如必填字段为空）保持不变
+func Truncate(message Message) Message {
+	if t, ok := message.(truncater); ok {
+		return t.truncated()
+	}
+	return message
+}
+
+type splitter interface {
+	split() []Message
+}
+
+// Split 将超长的文本消息、markdown消息拆分为多条消息，其他消息原样返回
+func Split(message Message) []Message {
+	if s, ok := message.(splitter); ok {
+		return s.split()
+	}
+	return []Message{message}
+}
+
+// TruncateBytes 在字符边界截断字符串，使其不超过maxBytes字节，被截断时以省略号结尾
+func TruncateBytes(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	return cutBytes(s, maxBytes-len(Ellipsis)) + Ellipsis
+}
+
+// TruncateRunes 截断字符串，使其不超过maxRunes个字符，被截断时以省略号结尾
+func TruncateRunes(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+	if maxRunes <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:maxRunes-1]) + Ellipsis
+}
+
+// SplitText 将文本拆分为不超过maxBytes字节的多段，优先在换行处拆分，其次在字符边界拆分
+func SplitText(s string, maxBytes int) []string {
+	var parts []string
+	for len(s) > maxBytes {
+		part := cutBytes(s, maxBytes)
+		if part == "" {
+			_, size := utf8.DecodeRuneInString(s)
+			part = s[:size]
+		}
+		if i := strings.LastIndexByte(part, '\n'); i > 0 {
+			part = part[:i+1]
+		}
+		parts = append(parts, part)
+		s = s[len(part):]
+	}
+	return append(parts, s)
+}
+
+// 在字符边界截取不超过n字节的前缀
+func cutBytes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+type validator struct {
+	msgType    string
+	violations []Violation
+}
+
+func newValidator(msgType string) *validator {
+	return &validator{msgType: msgType}
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) maxBytes(field, value string, max int) {
+	if len(value) > max {
+		v.add(field, "exceeds %d bytes (got %d)", max, len(value))
+	}
+	if !utf8.ValidString(value) {
+		v.add(field, "is not valid utf-8")
+	}
+}
+
+func (v *validator) runeRange(field, value string, min, max int) {
+	if n := utf8.RuneCountInString(value); n < min || n > max {
+		v.add(field, "must be %d-%d characters (got %d)", min, max, n)
+	}
+}
+
+func (v *validator) countRange(field string, n, min, max int) {
+	if n < min || n > max {
+		v.add(field, "must contain %d-%d items (got %d)", min, max, n)
+	}
+}
+
+func (v *validator) key(field, value string) {
+	v.maxBytes(field, value, MaxKeyBytes)
+	if !keyPattern.MatchString(value) {
+		v.add(field, "may only contain digits, letters and '_-@.'")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{MsgType: v.msgType, Violations: v.violations}
+}
+
+func (m TextMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("content", m.Content)
+	v.maxBytes("content", m.Content, MaxTextBytes)
+	return v.err()
+}
+
+func (m TextMsg) truncated() Message {
+	m.Content = TruncateBytes(m.Content, MaxTextBytes)
+	return m
+}
+
+func (m TextMsg) split() []Message {
+	var messages []Message
+	for _, content := range SplitText(m.Content, MaxTextBytes) {
+		messages = append(messages, TextMsg{Content: content})
+	}
+	return messages
+}
+
+func (m MarkdownMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("content", m.Content)
+	v.maxBytes("content", m.Content, MaxTextBytes)
+	return v.err()
+}
+
+func (m MarkdownMsg) truncated() Message {
+	m.Content = TruncateBytes(m.Content, MaxTextBytes)
+	return m
+}
+
+func (m MarkdownMsg) split() []Message {
+	var messages []Message
+	for _, content := range SplitText(m.Content, MaxTextBytes) {
+		messages = append(messages, MarkdownMsg{Content: content})
+	}
+	return messages
+}
+
+func (m VideoMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("media_id", m.MediaId)
+	v.maxBytes("title", m.Title, MaxTitleBytes)
+	v.maxBytes("description", m.Description, MaxDescriptionBytes)
+	return v.err()
+}
+
+func (m VideoMsg) truncated() Message {
+	m.Title = TruncateBytes(m.Title, MaxTitleBytes)
+	m.Description = TruncateBytes(m.Description, MaxDescriptionBytes)
+	return m
+}
+
+func (m TextCardMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("title", m.Title)
+	v.maxBytes("title", m.Title, MaxTitleBytes)
+	v.required("description", m.Description)
+	v.maxBytes("description", m.Description, MaxDescriptionBytes)
+	v.required("url", m.Url)
+	v.runeRange("btntext", m.BtnText, 0, 4)
+	return v.err()
+}
+
+func (m TextCardMsg) truncated() Message {
+	m.Title = TruncateBytes(m.Title, MaxTitleBytes)
+	m.Description = TruncateBytes(m.Description, MaxDescriptionBytes)
+	m.BtnText = TruncateRunes(m.BtnText, 4)
+	return m
+}
+
+func (m NewsMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.countRange("articles", len(m.Articles), 1, MaxArticles)
+	for i, article := range m.Articles {
+		field := fmt.Sprintf("articles[%d].", i)
+		v.required(field+"title", article.Title)
+		v.maxBytes(field+"title", article.Title, MaxTitleBytes)
+		v.maxBytes(field+"description", article.Description, MaxDescriptionBytes)
+		v.required(field+"url", article.Url)
+	}
+	return v.err()
+}
+
+func (m NewsMsg) truncated() Message {
+	articles := make([]Article, len(m.Articles))
+	for i, article := range m.Articles {
+		article.Title = TruncateBytes(article.Title, MaxTitleBytes)
+		article.Description = TruncateBytes(article.Description, MaxDescriptionBytes)
+		articles[i] = article
+	}
+	m.Articles = articles
+	return m
+}
+
+func (m MPNewsMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.countRange("articles", len(m.Articles), 1, MaxArticles)
+	for i, article := range m.Articles {
+		field := fmt.Sprintf("articles[%d].", i)
+		v.required(field+"title", article.Title)
+		v.maxBytes(field+"title", article.Title, MaxTitleBytes)
+		v.required(field+"thumb_media_id", article.ThumbMediaId)
+		v.maxBytes(field+"author", article.Author, 64)
+		v.required(field+"content", article.Content)
+		v.maxBytes(field+"content", article.Content, MaxMPContentBytes)
+		v.maxBytes(field+"digest", article.Digest, MaxDescriptionBytes)
+	}
+	return v.err()
+}
+
+func (m MPNewsMsg) truncated() Message {
+	articles := make([]MPArticle, len(m.Articles))
+	for i, article := range m.Articles {
+		article.Title = TruncateBytes(article.Title, MaxTitleBytes)
+		article.Author = TruncateBytes(article.Author, 64)
+		article.Digest = TruncateBytes(article.Digest, MaxDescriptionBytes)
+		articles[i] = article
+	}
+	m.Articles = articles
+	return m
+}
+
+func (m MiniProgramNoticeMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("appid", m.AppId)
+	v.runeRange("title", m.Title, 4, 12)
+	if m.Description != "" {
+		v.runeRange("description", m.Description, 4, 12)
+	}
+	v.countRange("content_item", len(m.ContentItem), 0, MaxNoticeItems)
+	for i, item := range m.ContentItem {
+		field := fmt.Sprintf("content_item[%d].", i)
+		v.runeRange(field+"key", item.Key, 1, 10)
+		v.runeRange(field+"value", item.Value, 1, 30)
+	}
+	return v.err()
+}
+
+func (m MiniProgramNoticeMsg) truncated() Message {
+	m.Title = TruncateRunes(m.Title, 12)
+	m.Description = TruncateRunes(m.Description, 12)
+	items := make([]NoticeContentItem, len(m.ContentItem))
+	for i, item := range m.ContentItem {
+		item.Key = TruncateRunes(item.Key, 10)
+		item.Value = TruncateRunes(item.Value, 30)
+		items[i] = item
+	}
+	m.ContentItem = items
+	return m
+}
+
+func (m TaskCardMsg) Validate() error {
+	v := newValidator(m.MsgType())
+	v.required("title", m.Title)
+	v.maxBytes("title", m.Title, MaxTitleBytes)
+	v.required("description", m.Description)
+	v.maxBytes("description", m.Description, MaxDescriptionBytes)
+	v.maxBytes("url", m.Url, MaxUrlBytes)
+	v.required("task_id", m.TaskId)
+	v.key("task_id", m.TaskId)
+	v.countRange("btn", len(m.Btn), 1, MaxTaskCardButtons)
+	for i, btn := range m.Btn {
+		field := fmt.Sprintf("btn[%d].", i)
+		v.required(field+"key", btn.Key)
+		v.key(field+"key", btn.Key)
+		v.required(field+"name", btn.Name)
+		if btn.Color != "" && btn.Color != "red" && btn.Color != "blue" {
+			v.add(field+"color", "must be red or blue")
+		}
+	}
+	return v.err()
+}
+
+func (m TaskCardMsg) truncated() Message {
+	m.Title = TruncateBytes(m.Title, MaxTitleBytes)
+	m.Description = TruncateBytes(m.Description, MaxDescriptionBytes)
+	return m
+}
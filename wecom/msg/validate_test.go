@@ -0,0 +1,103 @@
+package msg
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type validateTestSuite struct {
+	mockTestSuite
+}
+
+func (s *validateTestSuite) TestShouldReportAllViolations() {
+	err := Validate(TaskCardMsg{
+		Title:  strings.Repeat("a", MaxTitleBytes+1),
+		TaskId: "task id",
+		Btn:    []TaskCardBtn{{Key: "a"}, {Key: "b", Name: "b"}, {Key: "c", Name: "c"}},
+	})
+
+	var validationErr *ValidationError
+	s.Require().True(errors.As(err, &validationErr))
+	fields := map[string]bool{}
+	for _, v := range validationErr.Violations {
+		fields[v.Field] = true
+	}
+	s.Equal(map[string]bool{"title": true, "description": true, "task_id": true, "btn": true, "btn[0].name": true}, fields)
+}
+
+func (s *validateTestSuite) TestShouldValidateRuneLength() {
+	s.NoError(Validate(MiniProgramNoticeMsg{AppId: "wx1", Title: "会议室预订成功"}))
+	s.Error(Validate(MiniProgramNoticeMsg{AppId: "wx1", Title: "预订"}))
+	s.Error(Validate(NewsMsg{}))
+}
+
+func (s *validateTestSuite) TestShouldTruncateOnRuneBoundary() {
+	content := strings.Repeat("中", 1000)
+
+	truncated := Truncate(TextMsg{Content: content}).(TextMsg)
+
+	s.LessOrEqual(len(truncated.Content), MaxTextBytes)
+	s.True(utf8.ValidString(truncated.Content))
+	s.True(strings.HasSuffix(truncated.Content, Ellipsis))
+	s.NoError(Validate(truncated))
+}
+
+func (s *validateTestSuite) TestShouldSplitText() {
+	content := strings.Repeat("第一行\n", 200) + strings.Repeat("中", 800)
+
+	parts := SplitText(content, MaxTextBytes)
+
+	s.Equal(content, strings.Join(parts, ""))
+	for _, part := range parts {
+		s.LessOrEqual(len(part), MaxTextBytes)
+		s.True(utf8.ValidString(part))
+	}
+	s.True(strings.HasSuffix(parts[0], "\n"))
+}
+
+func (s *validateTestSuite) TestShouldRejectInvalidMessageBeforeSending() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	_, err := s.msg.Send(context.Background(), ToUsers("u1"), TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)})
+
+	var validationErr *ValidationError
+	s.True(errors.As(err, &validationErr))
+	s.Empty(s.transport.Requests())
+}
+
+func (s *validateTestSuite) TestShouldNotValidateInLegacyMethod() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	err := s.msg.SendText(context.Background(), []string{"u1"}, nil, nil, TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}, false)
+
+	s.NoError(err)
+	s.Len(s.transport.Requests()[0]["text"].(map[string]any)["content"], MaxTextBytes+1)
+}
+
+func (s *validateTestSuite) TestShouldSendTruncatedMessage() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	_, err := s.msg.Send(context.Background(), ToUsers("u1"), TextMsg{Content: strings.Repeat("a", MaxTextBytes+1)}, SendWithTruncate())
+
+	s.NoError(err)
+	s.Len(s.transport.Requests()[0]["text"].(map[string]any)["content"], MaxTextBytes)
+}
+
+func (s *validateTestSuite) TestShouldSendSplitMessages() {
+	s.transport.RegisterPost(urlSend, `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+
+	result, err := s.msg.Send(context.Background(), ToUsers("u1"), MarkdownMsg{Content: strings.Repeat("a", MaxTextBytes*2+1)}, SendWithSplit())
+
+	s.NoError(err)
+	s.Len(s.transport.Requests(), 3)
+	s.Len(result.MsgIds, 3)
+}
+
+func TestValidateTestSuite(t *testing.T) {
+	suite.Run(t, new(validateTestSuite))
+}
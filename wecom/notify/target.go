@@ -0,0 +1,59 @@
+// Package notify 消息的发送目标
+//
+// Target统一了应用消息与群机器人两种发送方式，供告警、日志转发等只关心消息内容的场景使用。
+package notify
+
+import (
+	"context"
+
+	"github.com/huimingz/wechatgo/wecom/msg"
+	"github.com/huimingz/wechatgo/wecom/robot"
+)
+
+// Target 消息的发送目标
+type Target interface {
+	Send(ctx context.Context, message msg.Message) error
+}
+
+// TargetFunc 将函数转换为Target
+type TargetFunc func(ctx context.Context, message msg.Message) error
+
+func (f TargetFunc) Send(ctx context.Context, message msg.Message) error {
+	return f(ctx, message)
+}
+
+type appTarget struct {
+	msg     *msg.WechatMsg
+	to      msg.Recipients
+	options []msg.SendOption
+}
+
+// AppTarget 通过应用消息发送给指定的成员、部门或标签
+//
+// 超长的消息会被拆分为多条发送，options在拆分之后应用，与Send相同
+func AppTarget(w *msg.WechatMsg, to msg.Recipients, options ...msg.SendOption) Target {
+	options = append([]msg.SendOption{msg.SendWithSplit()}, options...)
+	return appTarget{msg: w, to: to, options: options}
+}
+
+func (t appTarget) Send(ctx context.Context, message msg.Message) error {
+	_, err := t.msg.Send(ctx, t.to, message, t.options...)
+	return err
+}
+
+type robotTarget struct {
+	robot *robot.Robot
+}
+
+// RobotTarget 通过群机器人发送，超过群机器人长度限制的markdown消息会被截断
+func RobotTarget(r *robot.Robot) Target {
+	return robotTarget{robot: r}
+}
+
+func (t robotTarget) Send(ctx context.Context, message msg.Message) error {
+	if m, ok := message.(msg.MarkdownMsg); ok {
+		m.Content = msg.TruncateBytes(m.Content, robot.MaxMarkdownBytes)
+		message = m
+	}
+	return t.robot.Send(ctx, message)
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/testdata"
+	"github.com/huimingz/wechatgo/wecom"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+type targetTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	msg       *msg.WechatMsg
+}
+
+func (s *targetTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+	conf := testdata.TestConf
+	client := wecom.NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, wecom.ClientWithHTTPClient(s.transport.HTTPClient()))
+	s.msg = msg.NewWechatMsg(client)
+}
+
+func (s *targetTestSuite) TestShouldSplitAppMessageWithOptions() {
+	s.transport.RegisterPost("/cgi-bin/message/send", `{"errcode":0,"errmsg":"ok","msgid":"msgid1"}`)
+	target := AppTarget(s.msg, msg.ToUsers("zhangsan"), msg.SendWithSafe(1))
+
+	err := target.Send(context.Background(), msg.TextMsg{Content: strings.Repeat("a", msg.MaxTextBytes+1)})
+
+	s.Require().NoError(err)
+	requests := s.transport.Requests()
+	s.Require().Len(requests, 2)
+	for _, request := range requests {
+		s.Equal(float64(1), request["safe"])
+	}
+}
+
+func TestTargetTestSuite(t *testing.T) {
+	suite.Run(t, new(targetTestSuite))
+}
@@ -3,6 +3,7 @@ package oa
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/huimingz/wechatgo/wecom"
 )
@@ -87,3 +88,29 @@ func (w WechatApproval) GetApprovalData(ctx context.Context, startTime, endTime,
 	err := w.Client.Post(ctx, urlGetApprovalData, nil, data, nil, &out)
 	return &out, err
 }
+
+// ApprovalDataPager 审批数据的分页迭代器
+//
+// 接口每次最多返回100条记录，分页大小设置无效。游标为下一次拉取的审批单号，
+// 返回的审批单号为0或与请求的审批单号相同时表示没有更多数据
+func (w WechatApproval) ApprovalDataPager(startTime, endTime int, options ...wecom.PagerOptionFn) *wecom.Pager[ApprovalEntry] {
+	return wecom.NewPager(func(ctx context.Context, cursor string, limit int) ([]ApprovalEntry, string, error) {
+		nextSpNum := 0
+		if cursor != "" {
+			var err error
+			if nextSpNum, err = strconv.Atoi(cursor); err != nil {
+				return nil, "", err
+			}
+		}
+
+		data, err := w.GetApprovalData(ctx, startTime, endTime, nextSpNum)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if data.NextSpnum == 0 || data.NextSpnum == nextSpNum {
+			return data.Data, "", nil
+		}
+		return data.Data, strconv.Itoa(data.NextSpnum), nil
+	}, options...)
+}
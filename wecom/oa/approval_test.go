@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/huimingz/wechatgo/mock"
 	"github.com/huimingz/wechatgo/testdata"
 	"github.com/huimingz/wechatgo/wecom"
 )
@@ -21,6 +22,28 @@ func TestWechatApproval_GetApprovalData(t *testing.T) {
 
 }
 
+func TestWechatApproval_ApprovalDataPagerFromCursor(t *testing.T) {
+	transport := mock.NewTransport()
+	transport.RegisterPost(urlGetApprovalData,
+		`{"errcode":0,"errmsg":"ok","count":1,"total":3,"next_spnum":201905290003,"data":[{"sp_num":201905290002}]}`,
+		`{"errcode":0,"errmsg":"ok","count":1,"total":3,"next_spnum":201905290003,"data":[{"sp_num":201905290003}]}`)
+	conf := testdata.TestConf
+	approval := NewWechatApproval(wecom.NewClient(conf.CorpId, conf.ApprovalSecret, conf.ApprovalAgentId,
+		wecom.ClientWithHTTPClient(transport.HTTPClient())))
+
+	entries, err := approval.ApprovalDataPager(1559048367, 1561640367, wecom.PagerWithCursor("201905290002")).
+		All(context.Background())
+	if err != nil {
+		t.Fatalf("WechatApproval.ApprovalDataPager() error = '%s'", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("WechatApproval.ApprovalDataPager() error = '审批数据条数为%d，应为2'", len(entries))
+	}
+	if requests := transport.Requests(); len(requests) != 2 || requests[0]["next_spnum"] != float64(201905290002) {
+		t.Errorf("WechatApproval.ApprovalDataPager() error = '请求数据错误：%v'", requests)
+	}
+}
+
 func init() {
 	var conf = testdata.TestConf
 	var wechatClient = wecom.NewClient(conf.CorpId, conf.ApprovalSecret, conf.ApprovalAgentId)
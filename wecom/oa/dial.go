@@ -64,3 +64,14 @@ func (w WechatDial) GetRecord(ctx context.Context, startTime, endTime, offset, l
 	err := w.Client.Post(ctx, urlGetDialRecord, nil, data, nil, &out)
 	return out.Record, err
 }
+
+// RecordPager 公费电话拨打记录的分页迭代器
+//
+// 分页大小默认为100
+func (w WechatDial) RecordPager(startTime, endTime int, options ...wecom.PagerOptionFn) *wecom.Pager[DialRecord] {
+	options = append([]wecom.PagerOptionFn{wecom.PagerWithLimit(100)}, options...)
+	return wecom.NewOffsetPager(func(ctx context.Context, offset, limit int) ([]DialRecord, bool, error) {
+		record, err := w.GetRecord(ctx, startTime, endTime, offset, limit)
+		return record, len(record) >= limit, err
+	}, options...)
+}
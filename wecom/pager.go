@@ -0,0 +1,142 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// ErrPagerDone 分页数据已全部拉取完毕
+var ErrPagerDone = errors.New("no more pages")
+
+// PageFetcher 拉取一页数据
+//
+// cursor为本次拉取的游标，首次拉取时为空字符串；limit为分页大小，0表示使用接口默认值。
+// 返回的next为下一页的游标，为空字符串时表示没有更多数据。
+type PageFetcher[T any] func(ctx context.Context, cursor string, limit int) (items []T, next string, err error)
+
+// OffsetPageFetcher 按偏移量拉取一页数据，hasMore表示是否还有下一页
+type OffsetPageFetcher[T any] func(ctx context.Context, offset, limit int) (items []T, hasMore bool, err error)
+
+type PagerOptionFn func(option *pagerOption)
+
+type pagerOption struct {
+	limit  int    // 分页大小
+	cursor string // 起始游标
+}
+
+// PagerWithLimit 设置分页大小
+func PagerWithLimit(limit int) PagerOptionFn {
+	return func(option *pagerOption) {
+		option.limit = limit
+	}
+}
+
+// PagerWithCursor 设置起始游标，可用于从上次中断的位置继续拉取
+func PagerWithCursor(cursor string) PagerOptionFn {
+	return func(option *pagerOption) {
+		option.cursor = cursor
+	}
+}
+
+// Pager 通用分页迭代器
+//
+// 非线程安全，同一个Pager不应在多个goroutine中同时使用。
+type Pager[T any] struct {
+	fetch  PageFetcher[T]
+	limit  int
+	cursor string
+	done   bool
+}
+
+func NewPager[T any](fetch PageFetcher[T], options ...PagerOptionFn) *Pager[T] {
+	option := pagerOption{}
+	for _, opt := range options {
+		opt(&option)
+	}
+
+	return &Pager[T]{fetch: fetch, limit: option.limit, cursor: option.cursor}
+}
+
+// NewOffsetPager 创建基于偏移量分页的迭代器
+//
+// 游标为十进制表示的偏移量，下一页的偏移量为当前偏移量加上本页的数据条数。
+func NewOffsetPager[T any](fetch OffsetPageFetcher[T], options ...PagerOptionFn) *Pager[T] {
+	return NewPager(func(ctx context.Context, cursor string, limit int) ([]T, string, error) {
+		offset := 0
+		if cursor != "" {
+			var err error
+			if offset, err = strconv.Atoi(cursor); err != nil {
+				return nil, "", err
+			}
+		}
+
+		items, hasMore, err := fetch(ctx, offset, limit)
+		if err != nil || !hasMore || len(items) == 0 {
+			return items, "", err
+		}
+		return items, strconv.Itoa(offset + len(items)), nil
+	}, options...)
+}
+
+// Cursor 下一次拉取使用的游标
+func (p *Pager[T]) Cursor() string {
+	return p.cursor
+}
+
+// Done 是否已拉取完所有数据
+func (p *Pager[T]) Done() bool {
+	return p.done
+}
+
+// Next 拉取下一页数据
+//
+// 所有数据拉取完毕后返回ErrPagerDone；拉取出错时游标不会前进，可再次调用重试。
+func (p *Pager[T]) Next(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, ErrPagerDone
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	items, next, err := p.fetch(ctx, p.cursor, p.limit)
+	if err != nil {
+		return nil, err
+	}
+
+	p.cursor = next
+	if next == "" {
+		p.done = true
+	}
+	return items, nil
+}
+
+// ForEach 依次遍历剩余的所有数据，fn返回错误时停止遍历并返回该错误
+func (p *Pager[T]) ForEach(ctx context.Context, fn func(item T) error) error {
+	for {
+		items, err := p.Next(ctx)
+		if errors.Is(err, ErrPagerDone) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// All 拉取剩余的所有数据
+func (p *Pager[T]) All(ctx context.Context) ([]T, error) {
+	var all []T
+	err := p.ForEach(ctx, func(item T) error {
+		all = append(all, item)
+		return nil
+	})
+	return all, err
+}
@@ -0,0 +1,141 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/testdata"
+)
+
+type pagerTestSuite struct {
+	TestSuite
+	corpGroup *CorpGroup
+}
+
+func (s *pagerTestSuite) SetupSuite() {
+	s.TestSuite.SetupSuite()
+
+	conf := testdata.TestConf
+	s.corpGroup = newCorpGroup(NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, ClientWithHTTPClient(s.httpClient)))
+}
+
+func (s *pagerTestSuite) numberFetcher(total int, calls *[]string) PageFetcher[int] {
+	return func(ctx context.Context, cursor string, limit int) ([]int, string, error) {
+		*calls = append(*calls, cursor)
+		start := 0
+		if cursor != "" {
+			start, _ = strconv.Atoi(cursor)
+		}
+
+		var items []int
+		for i := start; i < total && i < start+limit; i++ {
+			items = append(items, i)
+		}
+		if start+limit >= total {
+			return items, "", nil
+		}
+		return items, strconv.Itoa(start + limit), nil
+	}
+}
+
+func (s *pagerTestSuite) TestShouldFetchAllPages() {
+	var calls []string
+	pager := NewPager(s.numberFetcher(5, &calls), PagerWithLimit(2))
+
+	items, err := pager.All(context.Background())
+
+	s.NoError(err)
+	s.Equal([]int{0, 1, 2, 3, 4}, items)
+	s.Equal([]string{"", "2", "4"}, calls)
+	s.True(pager.Done())
+}
+
+func (s *pagerTestSuite) TestShouldReturnDoneErrorAfterLastPage() {
+	var calls []string
+	pager := NewPager(s.numberFetcher(1, &calls), PagerWithLimit(2))
+
+	_, err := pager.Next(context.Background())
+	s.NoError(err)
+
+	_, err = pager.Next(context.Background())
+	s.True(errors.Is(err, ErrPagerDone))
+}
+
+func (s *pagerTestSuite) TestShouldResumeFromCursor() {
+	var calls []string
+	pager := NewPager(s.numberFetcher(5, &calls), PagerWithLimit(2), PagerWithCursor("4"))
+
+	items, err := pager.All(context.Background())
+
+	s.NoError(err)
+	s.Equal([]int{4}, items)
+}
+
+func (s *pagerTestSuite) TestShouldStopForEachOnCallbackError() {
+	var calls []string
+	pager := NewPager(s.numberFetcher(5, &calls), PagerWithLimit(2))
+	stop := errors.New("stop")
+
+	var seen []int
+	err := pager.ForEach(context.Background(), func(item int) error {
+		seen = append(seen, item)
+		if item == 2 {
+			return stop
+		}
+		return nil
+	})
+
+	s.True(errors.Is(err, stop))
+	s.Equal([]int{0, 1, 2}, seen)
+}
+
+func (s *pagerTestSuite) TestShouldStopWhenContextCanceled() {
+	var calls []string
+	pager := NewPager(s.numberFetcher(5, &calls), PagerWithLimit(2))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := pager.All(ctx)
+
+	s.True(errors.Is(err, context.Canceled))
+	s.Empty(calls)
+}
+
+func (s *pagerTestSuite) TestShouldIterateByOffset() {
+	var offsets []int
+	pager := NewOffsetPager(func(ctx context.Context, offset, limit int) ([]int, bool, error) {
+		offsets = append(offsets, offset)
+		if offset >= 4 {
+			return []int{offset}, false, nil
+		}
+		return []int{offset, offset + 1}, true, nil
+	}, PagerWithLimit(2))
+
+	items, err := pager.All(context.Background())
+
+	s.NoError(err)
+	s.Equal([]int{0, 1, 2, 3, 4}, items)
+	s.Equal([]int{0, 2, 4}, offsets)
+}
+
+func (s *pagerTestSuite) TestShouldIterateAppShareInfos() {
+	first := httpmock.NewStringResponder(http.StatusOK, s.readFixture("response_cgi-bin_corpgroup_corp_list_app_share_info.json"))
+	last := httpmock.NewStringResponder(http.StatusOK, `{"errcode":0,"errmsg":"ok","ending":1,"corp_list":[{"corpid":"wwcorpid3","corp_name":"测试企业3","agentid":1113}]}`)
+	httpmock.RegisterResponder(http.MethodPost, _BASE_URL+"/cgi-bin/corpgroup/corp/list_app_share_info", first.Then(last))
+
+	infos, err := s.corpGroup.AppShareInfoPager(1111, nil).All(context.Background())
+
+	s.NoError(err)
+	s.Len(infos, 3)
+	s.Equal("wwcorpid3", infos[2].CorpId)
+}
+
+func TestPagerTestSuite(t *testing.T) {
+	suite.Run(t, new(pagerTestSuite))
+}
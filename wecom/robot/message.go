@@ -0,0 +1,76 @@
+package robot
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+// 消息内容长度限制
+const (
+	MaxMarkdownBytes = 4096            // markdown消息内容的最大字节数
+	MaxImageBytes    = 2 * 1024 * 1024 // 图片（base64编码前）的最大字节数
+)
+
+// MentionAll 提醒群中所有人
+const MentionAll = "@all"
+
+// TextMsg 文本消息，可以通过userid或手机号提醒群成员
+type TextMsg struct {
+	// 文本内容，最长不超过2048个字节，必须是utf8编码
+	Content string `json:"content"`
+
+	// userid的列表，提醒群中的指定成员(@某个成员)，@all表示提醒所有人
+	MentionedList []string `json:"mentioned_list,omitempty"`
+
+	// 手机号列表，提醒手机号对应的群成员(@某个成员)，@all表示提醒所有人
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+func (TextMsg) MsgType() string {
+	return "text"
+}
+
+func (m TextMsg) Validate() error {
+	return msg.TextMsg{Content: m.Content}.Validate()
+}
+
+// ImageMsg 图片消息，图片（base64编码前）最大不能超过2M，支持JPG、PNG格式
+type ImageMsg struct {
+	Base64 string `json:"base64"` // 图片内容的base64编码
+	Md5    string `json:"md5"`    // 图片内容（base64编码前）的md5值
+}
+
+func (ImageMsg) MsgType() string {
+	return "image"
+}
+
+// NewImageMsg 根据图片内容生成图片消息
+func NewImageMsg(data []byte) ImageMsg {
+	sum := md5.Sum(data)
+	return ImageMsg{
+		Base64: base64.StdEncoding.EncodeToString(data),
+		Md5:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// 检查群机器人是否支持该消息类型
+//
+// markdown、图文、文件、语音及模板卡片消息直接使用msg包的消息类型；文本及图片消息的格式与应用消息不同，
+// 需使用本包的TextMsg、ImageMsg
+func checkMsgType(message msg.Message) error {
+	switch message.(type) {
+	case TextMsg, ImageMsg, msg.MarkdownMsg, msg.NewsMsg, msg.FileMsg, msg.VoiceMsg, msg.TemplateCardMsg:
+		return nil
+	case msg.TextMsg:
+		return errors.New("robot: msg.TextMsg is not supported, use robot.TextMsg instead")
+	case msg.ImageMsg:
+		return errors.New("robot: msg.ImageMsg is not supported, use robot.ImageMsg (see NewImageMsg) instead")
+	default:
+		return fmt.Errorf("robot: unsupported message type %T", message)
+	}
+}
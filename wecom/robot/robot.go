@@ -0,0 +1,280 @@
+// Package robot 群机器人
+//
+// 群机器人通过webhook地址中的key发送消息，不需要access token。
+// 每个机器人发送的消息不能超过20条/分钟，Robot默认在客户端按此限制限流。
+package robot
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/wecom/media"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+const (
+	_BASE_URL      = "https://qyapi.weixin.qq.com"
+	urlSend        = "/cgi-bin/webhook/send"
+	urlUploadMedia = "/cgi-bin/webhook/upload_media"
+)
+
+// 群机器人的发送频率限制
+const (
+	RateLimit       = 20          // 每个周期内最多发送的消息数
+	RateLimitPeriod = time.Minute // 限流周期
+)
+
+// 上传文件的类型
+const (
+	MediaTypeFile  = "file"  // 普通文件，不超过20M
+	MediaTypeVoice = "voice" // 语音，不超过2M，播放长度不超过60s，仅支持AMR格式
+)
+
+var ErrInvalidWebhook = errors.New("robot: webhook url must contain key")
+
+type Robot struct {
+	key        string
+	baseUrl    string
+	httpClient *http.Client
+	limiter    wechatgo.RateLimiter
+	log        wechatgo.Logger
+}
+
+type RobotOptionFn func(robot *Robot)
+
+func RobotWithHTTPClient(httpClient *http.Client) RobotOptionFn {
+	return func(robot *Robot) {
+		robot.httpClient = httpClient
+	}
+}
+
+func RobotWithLogger(logger wechatgo.Logger) RobotOptionFn {
+	return func(robot *Robot) {
+		robot.log = logger
+	}
+}
+
+// RobotWithRateLimiter 指定限流器，多个Robot使用同一个key时应共享同一个限流器
+//
+// limiter为nil时不限流
+func RobotWithRateLimiter(limiter wechatgo.RateLimiter) RobotOptionFn {
+	return func(robot *Robot) {
+		robot.limiter = limiter
+	}
+}
+
+// NewRobot 根据webhook地址中的key创建群机器人
+func NewRobot(key string, options ...RobotOptionFn) *Robot {
+	robot := Robot{
+		key:     key,
+		baseUrl: _BASE_URL,
+		limiter: wechatgo.NewRateLimiter(RateLimit, RateLimitPeriod),
+	}
+
+	for _, opt := range options {
+		opt(&robot)
+	}
+
+	if robot.httpClient == nil {
+		robot.httpClient = &http.Client{}
+	}
+	if robot.log == nil {
+		robot.log = wechatgo.DefaultLogger()
+	}
+	return &robot
+}
+
+// NewRobotFromWebhook 根据完整的webhook地址创建群机器人
+func NewRobotFromWebhook(webhook string, options ...RobotOptionFn) (*Robot, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return nil, err
+	}
+	key := u.Query().Get("key")
+	if key == "" {
+		return nil, ErrInvalidWebhook
+	}
+
+	robot := NewRobot(key, options...)
+	if u.Scheme != "" && u.Host != "" {
+		robot.baseUrl = u.Scheme + "://" + u.Host
+	}
+	return robot, nil
+}
+
+// Key 机器人webhook地址中的key
+func (r *Robot) Key() string {
+	return r.key
+}
+
+func (r *Robot) resourceURL(path string, values url.Values) string {
+	if values == nil {
+		values = url.Values{}
+	}
+	values.Set("key", r.key)
+	return r.baseUrl + path + "?" + values.Encode()
+}
+
+func (r *Robot) post(ctx context.Context, path string, values url.Values, contentType string, body io.Reader, out interface{}) error {
+	request, err := http.NewRequest(http.MethodPost, r.resourceURL(path, values), body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	return r.do(request.WithContext(ctx), out)
+}
+
+// 发送请求并解析响应，errcode不为0时返回*wechatgo.WechatMessageError
+func (r *Robot) do(request *http.Request, out interface{}) error {
+	resp, err := r.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http response status code[%d] != 200", resp.StatusCode)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	errmsg := wechatgo.WechatMessageError{}
+	if err = json.Unmarshal(content, &errmsg); err != nil {
+		return err
+	}
+	r.log.Debug(request.Context(), fmt.Sprintf("ResponseHandler response message: %s", errmsg))
+	if errmsg.ErrCode != 0 {
+		return &errmsg
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(content, out)
+}
+
+// Send 发送群机器人消息
+//
+// 支持文本（TextMsg）、markdown（msg.MarkdownMsg）、图片（ImageMsg）、图文（msg.NewsMsg）、
+// 文件（msg.FileMsg）、语音（msg.VoiceMsg）及模板卡片（msg.TemplateCardMsg，仅文本通知型
+// 和图文展示型）消息，msg.TextMsg及msg.ImageMsg的格式与群机器人不同，会返回错误。
+// 发送前会校验消息内容，违反限制时返回*msg.ValidationError。
+//
+// 超过每分钟20条的频率限制时阻塞直到可以发送，ctx被取消时返回ctx.Err()。
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/91770
+func (r *Robot) Send(ctx context.Context, message msg.Message) error {
+	if err := checkMsgType(message); err != nil {
+		return err
+	}
+	if err := validate(message); err != nil {
+		return err
+	}
+
+	msgType := message.MsgType()
+	data, err := json.Marshal(map[string]interface{}{
+		"msgtype": msgType,
+		msgType:   message,
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.limiter != nil {
+		if err = r.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return r.post(ctx, urlSend, nil, "application/json", bytes.NewReader(data), nil)
+}
+
+// SendText 发送文本消息，mentionedList为需要提醒的成员userid
+func (r *Robot) SendText(ctx context.Context, content string, mentionedList ...string) error {
+	return r.Send(ctx, TextMsg{Content: content, MentionedList: mentionedList})
+}
+
+// SendMarkdown 发送markdown消息
+func (r *Robot) SendMarkdown(ctx context.Context, content string) error {
+	return r.Send(ctx, msg.MarkdownMsg{Content: content})
+}
+
+// SendImage 发送图片消息，data为图片内容
+func (r *Robot) SendImage(ctx context.Context, data []byte) error {
+	return r.Send(ctx, NewImageMsg(data))
+}
+
+// UploadMedia 上传文件，获取的media_id仅三天内有效，且只能对同一个机器人使用
+//
+// type_为MediaTypeFile或MediaTypeVoice，文件大小需大于5个字节
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/91770
+func (r *Robot) UploadMedia(ctx context.Context, type_, filename string, reader io.Reader) (*media.MediaInfo, error) {
+	mediaInfo := media.MediaInfo{}
+	body, err := media.NewUploadBody("media", filename, reader)
+	if err != nil {
+		return &mediaInfo, err
+	}
+	content, err := body.Open()
+	if err != nil {
+		return &mediaInfo, err
+	}
+
+	values := url.Values{}
+	values.Add("type", type_)
+
+	request, err := http.NewRequest(http.MethodPost, r.resourceURL(urlUploadMedia, values), content)
+	if err != nil {
+		content.Close()
+		return &mediaInfo, err
+	}
+	request.ContentLength = body.ContentLength()
+	request.Header.Set("Content-Type", body.ContentType())
+
+	err = r.do(request.WithContext(ctx), &mediaInfo)
+	return &mediaInfo, err
+}
+
+// 校验消息内容，群机器人的markdown、图片及模板卡片消息的限制与应用消息不同
+func validate(message msg.Message) error {
+	var violations []msg.Violation
+	switch m := message.(type) {
+	case msg.MarkdownMsg:
+		if m.Content == "" {
+			violations = append(violations, msg.Violation{Field: "content", Message: "is required"})
+		} else if len(m.Content) > MaxMarkdownBytes {
+			violations = append(violations, msg.Violation{Field: "content",
+				Message: fmt.Sprintf("exceeds %d bytes (got %d)", MaxMarkdownBytes, len(m.Content))})
+		}
+	case ImageMsg:
+		if m.Base64 == "" || m.Md5 == "" {
+			violations = append(violations, msg.Violation{Field: "base64", Message: "and md5 are required"})
+		} else if size := base64.StdEncoding.DecodedLen(len(m.Base64)) - strings.Count(m.Base64, "="); size > MaxImageBytes {
+			violations = append(violations, msg.Violation{Field: "base64",
+				Message: fmt.Sprintf("exceeds %d bytes before encoding", MaxImageBytes)})
+		}
+	case msg.TemplateCardMsg:
+		if m.CardType != msg.CardTypeTextNotice && m.CardType != msg.CardTypeNewsNotice {
+			violations = append(violations, msg.Violation{Field: "card_type",
+				Message: "must be " + msg.CardTypeTextNotice + " or " + msg.CardTypeNewsNotice})
+		}
+	default:
+		return msg.Validate(message)
+	}
+
+	if len(violations) > 0 {
+		return &msg.ValidationError{MsgType: message.MsgType(), Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package robot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo"
+	"github.com/huimingz/wechatgo/mock"
+	"github.com/huimingz/wechatgo/wecom/msg"
+)
+
+const mockWebhookPath = "/cgi-bin/webhook/send?key=robotkey0001"
+
+type robotTestSuite struct {
+	suite.Suite
+	transport *mock.Transport
+	robot     *Robot
+}
+
+func (s *robotTestSuite) SetupTest() {
+	s.transport = mock.NewTransport()
+
+	robot, err := NewRobotFromWebhook(mock.BaseURL+mockWebhookPath, RobotWithHTTPClient(s.transport.HTTPClient()))
+	s.Require().NoError(err)
+	s.robot = robot
+}
+
+func (s *robotTestSuite) TestShouldParseWebhook() {
+	s.Equal("robotkey0001", s.robot.Key())
+
+	_, err := NewRobotFromWebhook("https://qyapi.weixin.qq.com/cgi-bin/webhook/send")
+	s.True(errors.Is(err, ErrInvalidWebhook))
+}
+
+func (s *robotTestSuite) TestShouldSendTextWithMentions() {
+	s.transport.RegisterPost(mockWebhookPath, `{"errcode":0,"errmsg":"ok"}`)
+
+	err := s.robot.Send(context.Background(), TextMsg{
+		Content: "广州今日天气：29度", MentionedList: []string{"wangqing", MentionAll}, MentionedMobileList: []string{"13800001111"},
+	})
+
+	s.Require().NoError(err)
+	s.Equal(map[string]any{
+		"msgtype": "text",
+		"text": map[string]any{
+			"content":               "广州今日天气：29度",
+			"mentioned_list":        []any{"wangqing", "@all"},
+			"mentioned_mobile_list": []any{"13800001111"},
+		},
+	}, s.transport.Requests()[0])
+}
+
+func (s *robotTestSuite) TestShouldSendImage() {
+	s.transport.RegisterPost(mockWebhookPath, `{"errcode":0,"errmsg":"ok"}`)
+
+	s.Require().NoError(s.robot.SendImage(context.Background(), []byte("hello")))
+	s.Equal(map[string]any{
+		"msgtype": "image",
+		"image":   map[string]any{"base64": "aGVsbG8=", "md5": "5d41402abc4b2a76b9719d911017c592"},
+	}, s.transport.Requests()[0])
+}
+
+func (s *robotTestSuite) TestShouldValidateRobotLimits() {
+	s.NoError(validate(msg.MarkdownMsg{Content: strings.Repeat("a", MaxMarkdownBytes)}))
+	s.Error(validate(msg.MarkdownMsg{Content: strings.Repeat("a", MaxMarkdownBytes+1)}))
+	s.Error(validate(NewImageMsg(make([]byte, MaxImageBytes+1))))
+	s.NoError(validate(NewImageMsg(make([]byte, MaxImageBytes))))
+	s.Error(validate(msg.TemplateCardMsg{CardType: msg.CardTypeButtonInteraction}))
+	s.Error(validate(msg.NewsMsg{}))
+
+	err := s.robot.Send(context.Background(), msg.TextCardMsg{})
+	s.Error(err)
+	s.Empty(s.transport.Requests())
+}
+
+func (s *robotTestSuite) TestShouldRejectAppMessageTypes() {
+	err := s.robot.Send(context.Background(), msg.ImageMsg{MediaId: "MEDIA1"})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "robot.ImageMsg")
+
+	err = s.robot.Send(context.Background(), msg.TextMsg{Content: "hello"})
+	s.Require().Error(err)
+	s.Contains(err.Error(), "robot.TextMsg")
+	s.Empty(s.transport.Requests())
+}
+
+func (s *robotTestSuite) TestShouldReturnApiError() {
+	s.transport.RegisterPost(mockWebhookPath, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+
+	err := s.robot.SendMarkdown(context.Background(), "**hello**")
+
+	var msgErr *wechatgo.WechatMessageError
+	s.Require().True(errors.As(err, &msgErr))
+	s.Equal(45009, msgErr.ErrCode)
+}
+
+func (s *robotTestSuite) TestShouldWaitForRateLimit() {
+	s.transport.RegisterPost(mockWebhookPath, `{"errcode":0,"errmsg":"ok"}`)
+	robot := NewRobot("robotkey0001",
+		RobotWithHTTPClient(&http.Client{Transport: s.transport}),
+		RobotWithRateLimiter(wechatgo.NewRateLimiter(1, time.Hour)))
+
+	s.Require().NoError(robot.SendText(context.Background(), "first"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	s.True(errors.Is(robot.SendText(ctx, "second"), context.DeadlineExceeded))
+	s.Len(s.transport.Requests(), 1)
+}
+
+func (s *robotTestSuite) TestShouldUploadMedia() {
+	s.transport.RegisterResponder(http.MethodPost, mock.BaseURL+"/cgi-bin/webhook/upload_media?key=robotkey0001&type=file",
+		func(req *http.Request) (*http.Response, error) {
+			s.True(req.ContentLength > int64(len("hello world")))
+			s.NoError(req.ParseMultipartForm(1 << 20))
+			file, header, err := req.FormFile("media")
+			s.Require().NoError(err)
+			content, _ := io.ReadAll(file)
+			s.Equal("report.txt", header.Filename)
+			s.Equal("hello world", string(content))
+			return httpmock.NewStringResponse(http.StatusOK,
+				`{"errcode":0,"errmsg":"ok","type":"file","media_id":"3a8asd892asd8asd","created_at":"1380000000"}`), nil
+		})
+
+	info, err := s.robot.UploadMedia(context.Background(), MediaTypeFile, "report.txt", strings.NewReader("hello world"))
+
+	s.Require().NoError(err)
+	s.Equal("3a8asd892asd8asd", info.MediaId)
+	s.Equal("file", info.Type)
+}
+
+func TestRobotTestSuite(t *testing.T) {
+	suite.Run(t, new(robotTestSuite))
+}
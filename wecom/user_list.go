@@ -0,0 +1,165 @@
+package wecom
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/huimingz/wechatgo"
+)
+
+const urlUserListId = "/cgi-bin/user/list_id"
+
+// MaxUserListIdLimit 获取成员id列表时分页大小的最大值
+const MaxUserListIdLimit = 10000
+
+// DeptUser 成员id及其所属的部门id，成员属于多个部门时每个部门返回一条
+type DeptUser struct {
+	UserId     string `json:"userid"`     // 成员UserID
+	Department int    `json:"department"` // 成员所属的部门id
+}
+
+// ListId 获取成员id列表
+//
+// 按游标分页获取企业成员的userid与对应的部门id列表，首次请求cursor为空字符串，
+// 返回的next为空字符串时表示没有更多数据。limit为分页大小，取值范围1~10000，0表示使用接口默认值。
+// 适用于成员较多的企业，替代在根部门上递归获取成员详情
+//
+// 参考文档：https://developer.work.weixin.qq.com/document/path/96067
+func (w *UserManager) ListId(ctx context.Context, cursor string, limit int) (users []DeptUser, next string, err error) {
+	data := struct {
+		Cursor string `json:"cursor,omitempty"`
+		Limit  int    `json:"limit,omitempty"`
+	}{Cursor: cursor, Limit: limit}
+
+	out := struct {
+		NextCursor string     `json:"next_cursor"`
+		DeptUser   []DeptUser `json:"dept_user"`
+	}{}
+	err = w.Client.Post(ctx, urlUserListId, nil, data, nil, &out)
+	return out.DeptUser, out.NextCursor, err
+}
+
+// ListIdPager 成员id列表的分页迭代器，参见ListId
+func (w *UserManager) ListIdPager(options ...PagerOptionFn) *Pager[DeptUser] {
+	return NewPager(func(ctx context.Context, cursor string, limit int) ([]DeptUser, string, error) {
+		return w.ListId(ctx, cursor, limit)
+	}, options...)
+}
+
+// ListAllIds 获取所有成员的userid，按首次出现的顺序排列，成员属于多个部门时只返回一次
+func (w *UserManager) ListAllIds(ctx context.Context, options ...PagerOptionFn) ([]string, error) {
+	seen := map[string]bool{}
+	var userIds []string
+	err := w.ListIdPager(options...).ForEach(ctx, func(user DeptUser) error {
+		if !seen[user.UserId] {
+			seen[user.UserId] = true
+			userIds = append(userIds, user.UserId)
+		}
+		return nil
+	})
+	return userIds, err
+}
+
+type GetUsersOptionFn func(option *getUsersOption)
+
+type getUsersOption struct {
+	concurrency int
+	limiter     wechatgo.RateLimiter
+}
+
+// GetUsersWithConcurrency 同时读取成员的最大数量，默认为4
+func GetUsersWithConcurrency(concurrency int) GetUsersOptionFn {
+	return func(option *getUsersOption) {
+		option.concurrency = concurrency
+	}
+}
+
+// GetUsersWithRateLimiter 读取成员的限流器，每次读取前调用Wait
+func GetUsersWithRateLimiter(limiter wechatgo.RateLimiter) GetUsersOptionFn {
+	return func(option *getUsersOption) {
+		option.limiter = limiter
+	}
+}
+
+// GetUsersError 部分成员读取失败
+type GetUsersError struct {
+	Errors map[string]error // 读取失败的成员userid及对应的错误
+}
+
+func (e *GetUsersError) Error() string {
+	userIds := make([]string, 0, len(e.Errors))
+	for userId := range e.Errors {
+		userIds = append(userIds, userId)
+	}
+	sort.Strings(userIds)
+
+	if len(userIds) > 3 {
+		userIds = append(userIds[:3], "...")
+	}
+	return fmt.Sprintf("failed to get %d users: %s", len(e.Errors), strings.Join(userIds, ", "))
+}
+
+// GetUsers 并发读取成员详情，返回的成员顺序与userIds一致
+//
+// 部分成员读取失败时返回其余成员及*GetUsersError；ctx被取消时不再读取新的成员，返回已读取的成员及ctx.Err()
+func (w *UserManager) GetUsers(ctx context.Context, userIds []string, options ...GetUsersOptionFn) ([]UserInfo, error) {
+	option := getUsersOption{concurrency: 4}
+	for _, opt := range options {
+		opt(&option)
+	}
+	if option.concurrency <= 0 {
+		option.concurrency = 1
+	}
+
+	users := make([]*UserInfo, len(userIds))
+	errs := make([]error, len(userIds))
+
+	sem := make(chan struct{}, option.concurrency)
+	wg := sync.WaitGroup{}
+	for i, userId := range userIds {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, userId string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if option.limiter != nil {
+				if errs[i] = option.limiter.Wait(ctx); errs[i] != nil {
+					return
+				}
+			}
+			users[i], errs[i] = w.GetUser(ctx, userId)
+		}(i, userId)
+	}
+	wg.Wait()
+
+	result := make([]UserInfo, 0, len(userIds))
+	failed := map[string]error{}
+	for i, user := range users {
+		if errs[i] != nil {
+			failed[userIds[i]] = errs[i]
+		} else if user != nil {
+			result = append(result, *user)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if len(failed) > 0 {
+		return result, &GetUsersError{Errors: failed}
+	}
+	return result, nil
+}
@@ -0,0 +1,113 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/huimingz/wechatgo/testdata"
+)
+
+type userListTestSuite struct {
+	TestSuite
+	user *UserManager
+}
+
+func (s *userListTestSuite) SetupSuite() {
+	s.TestSuite.SetupSuite()
+
+	conf := testdata.TestConf
+	client := NewClient(conf.CorpId, conf.CorpSecret, conf.AgentId, ClientWithHTTPClient(s.httpClient))
+	s.user = newUserManager(client)
+}
+
+func (s *userListTestSuite) TestShouldIterateUserIds() {
+	pages := map[string]string{
+		"":      `{"errcode":0,"errmsg":"ok","next_cursor":"page2","dept_user":[{"userid":"zhangsan","department":1},{"userid":"lisi","department":1}]}`,
+		"page2": `{"errcode":0,"errmsg":"ok","next_cursor":"","dept_user":[{"userid":"zhangsan","department":2},{"userid":"wangwu","department":2}]}`,
+	}
+	var limits []float64
+	httpmock.RegisterResponder(http.MethodPost, _BASE_URL+urlUserListId, func(req *http.Request) (*http.Response, error) {
+		content, err := io.ReadAll(req.Body)
+		s.Require().NoError(err)
+		payload := map[string]any{}
+		s.Require().NoError(json.Unmarshal(content, &payload))
+		cursor, _ := payload["cursor"].(string)
+		limits = append(limits, payload["limit"].(float64))
+		return httpmock.NewStringResponse(http.StatusOK, pages[cursor]), nil
+	})
+
+	users, err := s.user.ListIdPager(PagerWithLimit(2)).All(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]DeptUser{{"zhangsan", 1}, {"lisi", 1}, {"zhangsan", 2}, {"wangwu", 2}}, users)
+	s.Equal([]float64{2, 2}, limits)
+
+	userIds, err := s.user.ListAllIds(context.Background(), PagerWithLimit(2))
+	s.Require().NoError(err)
+	s.Equal([]string{"zhangsan", "lisi", "wangwu"}, userIds)
+}
+
+func (s *userListTestSuite) TestShouldGetUsersConcurrently() {
+	mutex := sync.Mutex{}
+	running, maxRunning := 0, 0
+	httpmock.RegisterResponder(http.MethodGet, _BASE_URL+urlUserGet, func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+
+		time.Sleep(time.Millisecond * 10)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+
+		userId := req.URL.Query().Get("userid")
+		if userId == "missing" {
+			return httpmock.NewStringResponse(http.StatusOK, `{"errcode":60111,"errmsg":"userid not found"}`), nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","userid":"`+userId+`","name":"`+userId+`"}`), nil
+	})
+
+	userIds := []string{"u1", "u2", "missing", "u3", "u4", "u5"}
+	users, err := s.user.GetUsers(context.Background(), userIds, GetUsersWithConcurrency(2))
+
+	var usersErr *GetUsersError
+	s.Require().True(errors.As(err, &usersErr))
+	s.Len(usersErr.Errors, 1)
+	s.Contains(usersErr.Errors, "missing")
+
+	var got []string
+	for _, user := range users {
+		got = append(got, user.UserId)
+	}
+	s.Equal([]string{"u1", "u2", "u3", "u4", "u5"}, got)
+	s.Equal(2, maxRunning)
+}
+
+func (s *userListTestSuite) TestShouldStopGettingUsersWhenContextIsCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	httpmock.RegisterResponder(http.MethodGet, _BASE_URL+urlUserGet, func(req *http.Request) (*http.Response, error) {
+		calls++
+		cancel()
+		return httpmock.NewStringResponse(http.StatusOK, `{"errcode":0,"errmsg":"ok","userid":"u1"}`), nil
+	})
+
+	_, err := s.user.GetUsers(ctx, []string{"u1", "u2", "u3"}, GetUsersWithConcurrency(1))
+	s.True(errors.Is(err, context.Canceled))
+	s.Equal(1, calls)
+}
+
+func TestUserList(t *testing.T) {
+	suite.Run(t, new(userListTestSuite))
+}
@@ -3,17 +3,19 @@ package wecom
 import "context"
 
 type Wecom struct {
-	client *Client
-	App    *applicationManager
-	User   *UserManager
+	client    *Client
+	App       *applicationManager
+	User      *UserManager
+	CorpGroup *CorpGroup
 }
 
 func NewWecom(corpId, corpSecret string, agentId int, optionFns ...ClientOptionFn) *Wecom {
 	client := NewClient(corpId, corpSecret, agentId, optionFns...)
 	return &Wecom{
-		client: client,
-		App:    newWechatAppManage(client),
-		User:   newUserManager(client),
+		client:    client,
+		App:       newWechatAppManage(client),
+		User:      newUserManager(client),
+		CorpGroup: newCorpGroup(client),
 	}
 }
 